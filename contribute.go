@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	deserializer "github.com/worldcoin/ptau-deserializer/deserialize"
+)
+
+var contributeCommand = &cli.Command{
+	Name:  "contribute",
+	Usage: "Contribute to a .ptau with fresh secrets and write the result to `OUTPUT`",
+	Action: func(cCtx *cli.Context) error {
+		if cCtx.String("input") == "-" {
+			return fmt.Errorf("contribute needs a seekable --input, not stdin")
+		}
+
+		file, err := deserializer.InitPtau(cCtx.String("input"))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		contribution, err := file.Contribute(cCtx.Context, []byte(cCtx.String("entropy")), cCtx.String("name"), cCtx.String("output"))
+		if err != nil {
+			return err
+		}
+		return logContribution(contribution)
+	},
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "input",
+			Aliases:  []string{"i"},
+			Usage:    "Load the `FILE`.ptau to contribute to",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "output",
+			Aliases:  []string{"o"},
+			Usage:    "Write the contributed ptau to `FILE`.ptau",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "name",
+			Usage: "`NAME` recorded with the contribution",
+		},
+		&cli.StringFlag{
+			Name:    "entropy",
+			Aliases: []string{"e"},
+			Usage:   "Random `TEXT` mixed with the system's randomness to draw the secrets from",
+		},
+	},
+}
+
+var beaconCommand = &cli.Command{
+	Name:  "beacon",
+	Usage: "Finalize a .ptau with a contribution derived from a public beacon and write the result to `OUTPUT`",
+	Action: func(cCtx *cli.Context) error {
+		if cCtx.String("input") == "-" {
+			return fmt.Errorf("beacon needs a seekable --input, not stdin")
+		}
+
+		beaconHash, err := hex.DecodeString(strings.TrimPrefix(cCtx.String("beacon"), "0x"))
+		if err != nil {
+			return fmt.Errorf("--beacon must be hexadecimal: %w", err)
+		}
+		numIterationsExp := cCtx.Uint("iterations-exp")
+		if numIterationsExp > deserializer.BEACON_MAX_ITERATIONS_EXP {
+			return fmt.Errorf("--iterations-exp must be at most %d", deserializer.BEACON_MAX_ITERATIONS_EXP)
+		}
+
+		file, err := deserializer.InitPtau(cCtx.String("input"))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		contribution, err := file.Beacon(cCtx.Context, beaconHash, uint8(numIterationsExp), cCtx.String("name"), cCtx.String("output"))
+		if err != nil {
+			return err
+		}
+		return logContribution(contribution)
+	},
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "input",
+			Aliases:  []string{"i"},
+			Usage:    "Load the `FILE`.ptau to finalize",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "output",
+			Aliases:  []string{"o"},
+			Usage:    "Write the finalized ptau to `FILE`.ptau",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "beacon",
+			Usage:    "Public beacon, such as a block hash, in `HEX`",
+			Required: true,
+		},
+		&cli.UintFlag{
+			Name:     "iterations-exp",
+			Usage:    "Hash the beacon 2^`EXP` times",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "name",
+			Usage: "`NAME` recorded with the beacon",
+		},
+	},
+}
+
+// logContribution logs the hashes a contributor publishes to attest to their
+// contribution.
+func logContribution(contribution deserializer.PtauContribution) error {
+	responseHash, err := contribution.ResponseHash()
+	if err != nil {
+		return err
+	}
+	slog.Info("contribution written",
+		"name", contribution.Name,
+		"responseHash", hex.EncodeToString(responseHash[:]),
+		"nextChallenge", hex.EncodeToString(contribution.NextChallenge[:]))
+	return nil
+}
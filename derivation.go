@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+	deserializer "github.com/worldcoin/ptau-deserializer/deserialize"
+)
+
+var checkDerivationCommand = &cli.Command{
+	Name:  "check-derivation",
+	Usage: "Check that a .ph1 was converted from a given .ptau",
+	Action: func(cCtx *cli.Context) error {
+		if cCtx.Int("samples") < 0 {
+			return fmt.Errorf("--samples can't be negative")
+		}
+
+		ptauFile, err := deserializer.InitPtau(cCtx.String("ptau"))
+		if err != nil {
+			return err
+		}
+		defer ptauFile.Close()
+
+		phase1File, err := deserializer.InitPhase1(cCtx.String("ph1"))
+		if err != nil {
+			return err
+		}
+		defer phase1File.Close()
+
+		return deserializer.CheckDerivation(cCtx.Context, ptauFile, phase1File, cCtx.Int("samples"))
+	},
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "ptau",
+			Usage:    "Load the `FILE`.ptau the .ph1 should come from",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "ph1",
+			Usage:    "Load the `FILE`.ph1 to check",
+			Required: true,
+		},
+		&cli.IntFlag{
+			Name:  "samples",
+			Usage: "Spot-check a random linear combination of `N` randomly sampled points per section instead of comparing every point, points that aren't sampled aren't checked",
+		},
+	},
+}
@@ -0,0 +1,66 @@
+package deserializer
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// atomicFile is written in place of a final output file. It lives next to the
+// output, under a .partial name, and only replaces the output once Commit has
+// flushed it to disk, so a failed conversion never leaves a truncated file
+// that looks valid.
+type atomicFile struct {
+	*os.File
+	path string
+}
+
+func createAtomicFile(path string) (*atomicFile, error) {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.partial")
+	if err != nil {
+		return nil, err
+	}
+
+	// os.CreateTemp only gives the owner access to the file
+	if err := file.Chmod(0644); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+
+	return &atomicFile{File: file, path: path}, nil
+}
+
+// openAtomicFile reopens the partial file of an interrupted write to path.
+func openAtomicFile(path string, partialName string) (*atomicFile, error) {
+	file, err := os.OpenFile(filepath.Join(filepath.Dir(path), partialName), os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &atomicFile{File: file, path: path}, nil
+}
+
+// partialName is the name of the partial file, relative to the directory of
+// the output.
+func (f *atomicFile) partialName() string {
+	return filepath.Base(f.Name())
+}
+
+// Commit syncs and closes the partial file, then moves it over the output.
+func (f *atomicFile) Commit() error {
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), f.path)
+}
+
+// Abort closes and removes the partial file, leaving the output untouched.
+func (f *atomicFile) Abort() error {
+	f.Close()
+	return os.Remove(f.Name())
+}
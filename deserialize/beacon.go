@@ -0,0 +1,84 @@
+package deserializer
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+)
+
+///////////////////////////////////////////////////////////////////
+///                           BEACON                            ///
+///////////////////////////////////////////////////////////////////
+
+// Taken from the iden3/snarkjs repo, powersoftau_beacon.js and misc.js
+// (rngFromBeaconParams). A ceremony is finalized by a contribution whose
+// secrets anyone can derive from a public beacon:
+/*
+hash = beaconHash
+2^numIterationsExp times
+    hash = sha256(hash)
+rng  = ChaCha(hash)
+*/
+// It is recorded with type 1, along with the beacon and the exponent.
+
+const (
+	BEACON_MIN_ITERATIONS_EXP = 10
+	BEACON_MAX_ITERATIONS_EXP = 63
+)
+
+// InvalidBeacon is returned when a beacon can't be recorded the way snarkjs
+// does.
+type InvalidBeacon struct {
+	Reason string
+}
+
+func (r *InvalidBeacon) Error() string {
+	return fmt.Sprintf("invalid beacon: %s", r.Reason)
+}
+
+// Beacon writes the ptau finalized by the beacon to outputPath: the
+// contribution derived from beaconHash, hashed 2^numIterationsExp times. The
+// same beacon always gives the same file. It returns the record of the
+// contribution.
+func (ptauFile *PtauFile) Beacon(ctx context.Context, beaconHash []byte, numIterationsExp uint8, name string, outputPath string) (PtauContribution, error) {
+	contribution := PtauContribution{
+		Type:             CONTRIBUTION_TYPE_BEACON,
+		Name:             name,
+		NumIterationsExp: numIterationsExp,
+		BeaconHash:       beaconHash,
+	}
+
+	rng, err := beaconRng(ctx, beaconHash, numIterationsExp)
+	if err != nil {
+		return contribution, err
+	}
+	_, err = ptauFile.contribute(ctx, rng, &contribution, outputPath)
+	return contribution, err
+}
+
+// beaconRng seeds a generator with the beacon, hashed 2^numIterationsExp
+// times.
+func beaconRng(ctx context.Context, beaconHash []byte, numIterationsExp uint8) (*snarkjsRng, error) {
+	switch {
+	case len(beaconHash) == 0:
+		return nil, &InvalidBeacon{Reason: "the beacon hash is empty"}
+	case len(beaconHash) > 255:
+		return nil, &InvalidBeacon{Reason: fmt.Sprintf("the beacon hash is %d bytes long, at most 255 can be recorded", len(beaconHash))}
+	case numIterationsExp < BEACON_MIN_ITERATIONS_EXP || numIterationsExp > BEACON_MAX_ITERATIONS_EXP:
+		return nil, &InvalidBeacon{Reason: fmt.Sprintf("the iteration exponent must be between %d and %d, got %d", BEACON_MIN_ITERATIONS_EXP, BEACON_MAX_ITERATIONS_EXP, numIterationsExp)}
+	}
+
+	log().Info("hashing the beacon", "iterations", uint64(1)<<numIterationsExp)
+	hash := sha256.Sum256(beaconHash)
+	for i := uint64(1); i < uint64(1)<<numIterationsExp; i++ {
+		// hashing takes hours for large exponents
+		if i%(1<<20) == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		hash = sha256.Sum256(hash[:])
+	}
+
+	return newSnarkjsRng(hash[:]), nil
+}
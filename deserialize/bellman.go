@@ -0,0 +1,672 @@
+package deserializer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"golang.org/x/crypto/blake2b"
+)
+
+///////////////////////////////////////////////////////////////////
+///                   BELLMAN CHALLENGE/RESPONSE                ///
+///////////////////////////////////////////////////////////////////
+
+// Taken from the kobigurk/phase2-bn254 repo, which the Perpetual Powers of Tau
+// ceremony runs, powersoftau/src/batched_accumulator.rs
+// https://github.com/kobigurk/phase2-bn254/blob/master/powersoftau/src/batched_accumulator.rs
+/*
+64 bytes, Blake2b hash of the previous file of the ceremony
+{2N-1}[
+    tauG1
+]
+{N}[
+    tauG2
+]
+{N}[
+    alphaTauG1
+]
+{N}[
+    betaTauG1
+]
+betaG2
+responses only, the public key of the contribution, uncompressed
+    tau_g1s, tau_g1sx, alpha_g1s, alpha_g1sx, beta_g1s, beta_g1sx (G1)
+    tau_g2spx, alpha_g2spx, beta_g2spx (G2)
+*/
+// Coordinates are big-endian and in canonical form, G1 points are laid out x
+// then y, G2 points x.c1, x.c0, y.c1, y.c0. Challenges are uncompressed and
+// responses usually compressed: only x is kept, with the top bit of the first
+// byte set when y is the lexicographically largest of ±y. Bit 6 of the first
+// byte marks the point at infinity in both encodings.
+//
+// A challenge starts with the hash of the response it was made from, a
+// response with the hash of the challenge it answers. The first challenge of
+// a ceremony starts with the hash of nothing.
+
+const BELLMAN_HASH_SIZE = blake2b.Size
+
+// BELLMAN_PUBLIC_KEY_SIZE is the size of the public key closing a response.
+const BELLMAN_PUBLIC_KEY_SIZE = 6*2*BN254_FIELD_ELEMENT_SIZE + 3*4*BN254_FIELD_ELEMENT_SIZE
+
+const (
+	bellmanInfinityFlag = byte(0x40)
+	bellmanLargestFlag  = byte(0x80)
+)
+
+// InvalidPreviousHash is returned when a bellman file doesn't start with the
+// hash of the file it is supposed to follow.
+type InvalidPreviousHash struct {
+	Expected [BELLMAN_HASH_SIZE]byte
+	Actual   [BELLMAN_HASH_SIZE]byte
+}
+
+func (r *InvalidPreviousHash) Error() string {
+	return fmt.Sprintf("the file starts with hash %x, but the previous file hashes to %x", r.Actual, r.Expected)
+}
+
+type BellmanFile struct {
+	Power uint32
+	// Compressed is true when the points are compressed, as in responses
+	Compressed bool
+	// HasPublicKey is true for responses, which end with the public key of
+	// the contribution
+	HasPublicKey bool
+	// PreviousHash is the hash the file starts with
+	PreviousHash [BELLMAN_HASH_SIZE]byte
+	Reader       *os.File
+}
+
+// InitBellman opens a bellman challenge or response. The files have no
+// header, so the power and the variant are worked out from the file size.
+func InitBellman(path string) (*BellmanFile, error) {
+	reader, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := reader.Stat()
+	if err != nil {
+		reader.Close()
+		return nil, err
+	}
+
+	bellmanFile, ok := bellmanFileFromSize(info.Size())
+	if !ok {
+		reader.Close()
+		return nil, fmt.Errorf("%s is %d bytes long, which is the size of no bellman challenge or response", path, info.Size())
+	}
+	log().Debug("read bellman layout", "power", bellmanFile.Power, "compressed", bellmanFile.Compressed, "publicKey", bellmanFile.HasPublicKey)
+
+	if _, err := io.ReadFull(reader, bellmanFile.PreviousHash[:]); err != nil {
+		reader.Close()
+		return nil, err
+	}
+
+	bellmanFile.Reader = reader
+	return bellmanFile, nil
+}
+
+// bellmanFileFromSize finds the variant and power a file of the given size is
+// laid out with, there is at most one.
+func bellmanFileFromSize(size int64) (*BellmanFile, bool) {
+	for _, compressed := range []bool{false, true} {
+		for _, hasPublicKey := range []bool{false, true} {
+			f := &BellmanFile{Compressed: compressed, HasPublicKey: hasPublicKey}
+			for power := uint32(1); power <= BN254_TWO_ADICITY; power++ {
+				f.Power = power
+				if f.size() == size {
+					return f, true
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+func (bellmanFile *BellmanFile) Close() error {
+	return bellmanFile.Reader.Close()
+}
+
+func (bellmanFile *BellmanFile) DomainSize() int {
+	return 1 << bellmanFile.Power
+}
+
+// IsInitialChallenge is true for the challenge a ceremony starts from.
+func (bellmanFile *BellmanFile) IsInitialChallenge() bool {
+	return !bellmanFile.HasPublicKey && bellmanFile.PreviousHash == blake2b.Sum512(nil)
+}
+
+// Hash computes the Blake2b hash of the whole file, the one the next file of
+// the ceremony starts with.
+func (bellmanFile *BellmanFile) Hash() ([BELLMAN_HASH_SIZE]byte, error) {
+	var sum [BELLMAN_HASH_SIZE]byte
+
+	hasher, err := blake2b.New512(nil)
+	if err != nil {
+		return sum, err
+	}
+	if _, err := bellmanFile.Reader.Seek(0, io.SeekStart); err != nil {
+		return sum, err
+	}
+	if _, err := io.Copy(hasher, bellmanFile.Reader); err != nil {
+		return sum, err
+	}
+
+	copy(sum[:], hasher.Sum(nil))
+	return sum, nil
+}
+
+// CheckPreviousHash checks that the file starts with the hash of previous,
+// the file of the ceremony it follows.
+func (bellmanFile *BellmanFile) CheckPreviousHash(previous *BellmanFile) error {
+	hash, err := previous.Hash()
+	if err != nil {
+		return err
+	}
+	if hash != bellmanFile.PreviousHash {
+		return &InvalidPreviousHash{Expected: hash, Actual: bellmanFile.PreviousHash}
+	}
+	return nil
+}
+
+func (bellmanFile *BellmanFile) g1Size() int64 {
+	if bellmanFile.Compressed {
+		return BN254_FIELD_ELEMENT_SIZE
+	}
+	return 2 * BN254_FIELD_ELEMENT_SIZE
+}
+
+func (bellmanFile *BellmanFile) g2Size() int64 {
+	return 2 * bellmanFile.g1Size()
+}
+
+// size is the size of the whole file.
+func (bellmanFile *BellmanFile) size() int64 {
+	size := bellmanFile.sectionOffset(6) + bellmanFile.g2Size()
+	if bellmanFile.HasPublicKey {
+		size += BELLMAN_PUBLIC_KEY_SIZE
+	}
+	return size
+}
+
+// sectionOffset returns the offset of the section holding the points of the
+// ptau section sectionId, bellman files store them in the same order.
+func (bellmanFile *BellmanFile) sectionOffset(sectionId uint32) int64 {
+	N := int64(bellmanFile.DomainSize())
+	offset := int64(BELLMAN_HASH_SIZE)
+	if sectionId > 2 {
+		offset += (2*N - 1) * bellmanFile.g1Size()
+	}
+	if sectionId > 3 {
+		offset += N * bellmanFile.g2Size()
+	}
+	if sectionId > 4 {
+		offset += N * bellmanFile.g1Size()
+	}
+	if sectionId > 5 {
+		offset += N * bellmanFile.g1Size()
+	}
+	return offset
+}
+
+func (bellmanFile *BellmanFile) seekToSection(sectionId uint32) error {
+	_, err := bellmanFile.Reader.Seek(bellmanFile.sectionOffset(sectionId), io.SeekStart)
+	return err
+}
+
+func (bellmanFile *BellmanFile) readG1s(ctx context.Context, out chan bn254.G1Affine, count int) error {
+	reader := bufio.NewReader(bellmanFile.Reader)
+	buffer := make([]byte, 4*BN254_FIELD_ELEMENT_SIZE)
+	for i := 0; i < count; i++ {
+		g1Affine, err := readBellmanG1(reader, buffer, bellmanFile.Compressed)
+		if err != nil {
+			return fmt.Errorf("readG1s: index %d: %w", i, err)
+		}
+		select {
+		case out <- g1Affine:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (bellmanFile *BellmanFile) readG2s(ctx context.Context, out chan bn254.G2Affine, count int) error {
+	reader := bufio.NewReader(bellmanFile.Reader)
+	buffer := make([]byte, 4*BN254_FIELD_ELEMENT_SIZE)
+	for i := 0; i < count; i++ {
+		g2Affine, err := readBellmanG2(reader, buffer, bellmanFile.Compressed)
+		if err != nil {
+			return fmt.Errorf("readG2s: index %d: %w", i, err)
+		}
+		select {
+		case out <- g2Affine:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// ReadTauG1 sends the points of the tauG1 section to out, and closes it once
+// they have all been sent, an error occurred or ctx is cancelled.
+func (bellmanFile *BellmanFile) ReadTauG1(ctx context.Context, out chan bn254.G1Affine) error {
+	defer close(out)
+	if err := bellmanFile.seekToSection(2); err != nil {
+		return err
+	}
+	numPoints := bellmanFile.DomainSize()*2 - 1
+	log().Debug("reading section", "section", "tauG1", "points", numPoints)
+	return bellmanFile.readG1s(ctx, out, numPoints)
+}
+
+// ReadTauG2 sends the points of the tauG2 section to out, and closes it once
+// they have all been sent, an error occurred or ctx is cancelled.
+func (bellmanFile *BellmanFile) ReadTauG2(ctx context.Context, out chan bn254.G2Affine) error {
+	defer close(out)
+	if err := bellmanFile.seekToSection(3); err != nil {
+		return err
+	}
+	numPoints := bellmanFile.DomainSize()
+	log().Debug("reading section", "section", "tauG2", "points", numPoints)
+	return bellmanFile.readG2s(ctx, out, numPoints)
+}
+
+// ReadAlphaTauG1 sends the points of the alphaTauG1 section to out, and closes
+// it once they have all been sent, an error occurred or ctx is cancelled.
+func (bellmanFile *BellmanFile) ReadAlphaTauG1(ctx context.Context, out chan bn254.G1Affine) error {
+	defer close(out)
+	if err := bellmanFile.seekToSection(4); err != nil {
+		return err
+	}
+	numPoints := bellmanFile.DomainSize()
+	log().Debug("reading section", "section", "alphaTauG1", "points", numPoints)
+	return bellmanFile.readG1s(ctx, out, numPoints)
+}
+
+// ReadBetaTauG1 sends the points of the betaTauG1 section to out, and closes
+// it once they have all been sent, an error occurred or ctx is cancelled.
+func (bellmanFile *BellmanFile) ReadBetaTauG1(ctx context.Context, out chan bn254.G1Affine) error {
+	defer close(out)
+	if err := bellmanFile.seekToSection(5); err != nil {
+		return err
+	}
+	numPoints := bellmanFile.DomainSize()
+	log().Debug("reading section", "section", "betaTauG1", "points", numPoints)
+	return bellmanFile.readG1s(ctx, out, numPoints)
+}
+
+func (bellmanFile *BellmanFile) ReadBetaG2() (bn254.G2Affine, error) {
+	log().Debug("reading section", "section", "betaG2", "points", 1)
+	if err := bellmanFile.seekToSection(6); err != nil {
+		return bn254.G2Affine{}, err
+	}
+	g2Affine, err := readBellmanG2(bellmanFile.Reader, make([]byte, 4*BN254_FIELD_ELEMENT_SIZE), bellmanFile.Compressed)
+	if err != nil {
+		return bn254.G2Affine{}, fmt.Errorf("readG2: %w", err)
+	}
+	return g2Affine, nil
+}
+
+// ReadPublicKey reads the public key closing a response.
+func (bellmanFile *BellmanFile) ReadPublicKey() (PtauPublicKey, error) {
+	var key PtauPublicKey
+	if !bellmanFile.HasPublicKey {
+		return key, fmt.Errorf("a bellman challenge has no public key")
+	}
+	if _, err := bellmanFile.Reader.Seek(bellmanFile.size()-BELLMAN_PUBLIC_KEY_SIZE, io.SeekStart); err != nil {
+		return key, err
+	}
+
+	reader := bufio.NewReader(bellmanFile.Reader)
+	buffer := make([]byte, 4*BN254_FIELD_ELEMENT_SIZE)
+	var err error
+	for _, p := range []*bn254.G1Affine{&key.TauG1S, &key.TauG1SX, &key.AlphaG1S, &key.AlphaG1SX, &key.BetaG1S, &key.BetaG1SX} {
+		if *p, err = readBellmanG1(reader, buffer, false); err != nil {
+			return key, err
+		}
+	}
+	for _, p := range []*bn254.G2Affine{&key.TauG2SPX, &key.AlphaG2SPX, &key.BetaG2SPX} {
+		if *p, err = readBellmanG2(reader, buffer, false); err != nil {
+			return key, err
+		}
+	}
+
+	return key, nil
+}
+
+// readBellmanG1 reads a G1 point in the bellman encoding and checks that it
+// lies on the curve. buffer must hold at least 64 bytes.
+func readBellmanG1(reader io.Reader, buffer []byte, compressed bool) (bn254.G1Affine, error) {
+	var g1Affine bn254.G1Affine
+
+	size := 2 * BN254_FIELD_ELEMENT_SIZE
+	if compressed {
+		size = BN254_FIELD_ELEMENT_SIZE
+	}
+	b := buffer[:size]
+	if _, err := io.ReadFull(reader, b); err != nil {
+		return g1Affine, err
+	}
+
+	isInfinity, err := readBellmanFlags(b, compressed)
+	if isInfinity || err != nil {
+		return g1Affine, err
+	}
+
+	if compressed {
+		err = decodeCompressed(b, &g1Affine)
+		return g1Affine, err
+	}
+
+	if err := g1Affine.X.SetBytesCanonical(b[:BN254_FIELD_ELEMENT_SIZE]); err != nil {
+		return g1Affine, err
+	}
+	if err := g1Affine.Y.SetBytesCanonical(b[BN254_FIELD_ELEMENT_SIZE:]); err != nil {
+		return g1Affine, err
+	}
+
+	if !g1Affine.IsOnCurve() {
+		return g1Affine, fmt.Errorf("g1Affine is not on curve: X: %v Y: %v", g1Affine.X.String(), g1Affine.Y.String())
+	}
+
+	return g1Affine, nil
+}
+
+// readBellmanG2 reads a G2 point in the bellman encoding and checks that it
+// lies on the curve. buffer must hold at least 128 bytes.
+func readBellmanG2(reader io.Reader, buffer []byte, compressed bool) (bn254.G2Affine, error) {
+	var g2Affine bn254.G2Affine
+
+	size := 4 * BN254_FIELD_ELEMENT_SIZE
+	if compressed {
+		size = 2 * BN254_FIELD_ELEMENT_SIZE
+	}
+	b := buffer[:size]
+	if _, err := io.ReadFull(reader, b); err != nil {
+		return g2Affine, err
+	}
+
+	isInfinity, err := readBellmanFlags(b, compressed)
+	if isInfinity || err != nil {
+		return g2Affine, err
+	}
+
+	if compressed {
+		err = decodeCompressed(b, &g2Affine)
+		return g2Affine, err
+	}
+
+	coordinates := []*fp.Element{&g2Affine.X.A1, &g2Affine.X.A0, &g2Affine.Y.A1, &g2Affine.Y.A0}
+	for i, e := range coordinates {
+		if err := e.SetBytesCanonical(b[i*BN254_FIELD_ELEMENT_SIZE : (i+1)*BN254_FIELD_ELEMENT_SIZE]); err != nil {
+			return g2Affine, err
+		}
+	}
+
+	if !g2Affine.IsOnCurve() {
+		return g2Affine, fmt.Errorf("g2Affine is not on curve: X: %v Y: %v", g2Affine.X.String(), g2Affine.Y.String())
+	}
+
+	return g2Affine, nil
+}
+
+// readBellmanFlags reads the flags in the first byte of the encoded point b
+// and rewrites them the way gnark encodes compressed points, or clears them
+// for uncompressed ones.
+func readBellmanFlags(b []byte, compressed bool) (isInfinity bool, err error) {
+	flags := b[0] &^ 0x3f
+	b[0] &= 0x3f
+
+	if flags&bellmanInfinityFlag != 0 {
+		if flags != bellmanInfinityFlag || b[0] != 0 || !bytes.Equal(b[1:], make([]byte, len(b)-1)) {
+			return true, fmt.Errorf("invalid point at infinity")
+		}
+		return true, nil
+	}
+
+	if !compressed {
+		if flags != 0 {
+			return false, fmt.Errorf("invalid flags %#x for an uncompressed point", flags)
+		}
+		return false, nil
+	}
+
+	// gnark flags compressed points with the top bit, and a y larger than -y
+	// with bit 6
+	b[0] |= 0x80
+	if flags&bellmanLargestFlag != 0 {
+		b[0] |= 0x40
+	}
+	return false, nil
+}
+
+// decodeCompressed decodes a point that readBellmanFlags converted to gnark's
+// compressed encoding. Like for ptau files, the points are only checked to be
+// on the curve, which solving for y already ensures.
+func decodeCompressed(b []byte, point interface{}) error {
+	return bn254.NewDecoder(bytes.NewReader(b), bn254.NoSubgroupChecks()).Decode(point)
+}
+
+// WriteBellmanFromPtauFile writes the points of the ptau as the uncompressed
+// response of its last contribution, which bellman and bellperson tools can
+// read: it starts with the hash of the challenge the contribution answered
+// and ends with its public key. A ptau without contributions, such as a
+// reduced one, is written as a challenge instead, starting with the blank
+// hash like snarkjs exports it. It returns the hash of the written file.
+func WriteBellmanFromPtauFile(ctx context.Context, ptauFile *PtauFile, outputPath string) ([BELLMAN_HASH_SIZE]byte, error) {
+	var hash [BELLMAN_HASH_SIZE]byte
+
+	contributions, err := ptauFile.ReadContributions()
+	if err != nil {
+		return hash, err
+	}
+
+	// snarkjs hashes the challenges like bellman does, the one answered by
+	// the first contribution holds the generators
+	previousHash := blake2b.Sum512(nil)
+	var key *PtauPublicKey
+	if n := len(contributions); n > 1 {
+		previousHash = contributions[n-2].NextChallenge
+		key = &contributions[n-1].Key
+	} else if n == 1 {
+		ceremonyPower, err := ptauFile.ceremonyPower()
+		if err != nil {
+			return hash, err
+		}
+		previousHash = initialBellmanChallengeHash(ceremonyPower)
+		key = &contributions[0].Key
+	}
+
+	outputFile, err := createAtomicFile(outputPath)
+	if err != nil {
+		return hash, err
+	}
+
+	hasher, err := blake2b.New512(nil)
+	if err != nil {
+		outputFile.Abort()
+		return hash, err
+	}
+	writer := bufio.NewWriter(io.MultiWriter(outputFile, hasher))
+
+	if err := writeBellman(ctx, ptauFile, writer, previousHash, key); err != nil {
+		outputFile.Abort()
+		return hash, err
+	}
+	if err := writer.Flush(); err != nil {
+		outputFile.Abort()
+		return hash, err
+	}
+
+	copy(hash[:], hasher.Sum(nil))
+	return hash, outputFile.Commit()
+}
+
+// writeBellman writes the points of the ptau after previousHash, followed by
+// key unless it is nil.
+func writeBellman(ctx context.Context, ptauFile *PtauFile, writer io.Writer, previousHash [BELLMAN_HASH_SIZE]byte, key *PtauPublicKey) error {
+	if _, err := writer.Write(previousHash[:]); err != nil {
+		return err
+	}
+
+	if err := writeBellmanG1s(ctx, writer, ptauFile.ReadTauG1); err != nil {
+		return err
+	}
+	if err := writeBellmanG2s(ctx, writer, ptauFile.ReadTauG2); err != nil {
+		return err
+	}
+	for _, read := range []func(context.Context, chan bn254.G1Affine) error{ptauFile.ReadAlphaTauG1, ptauFile.ReadBetaTauG1} {
+		if err := writeBellmanG1s(ctx, writer, read); err != nil {
+			return err
+		}
+	}
+	betaG2, err := ptauFile.ReadBetaG2()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(bellmanG2Bytes(&betaG2)); err != nil {
+		return err
+	}
+
+	if key == nil {
+		return nil
+	}
+	_, err = writer.Write(bellmanPublicKeyBytes(*key))
+	return err
+}
+
+// writeBellmanG1s writes the points read sends, uncompressed.
+func writeBellmanG1s(ctx context.Context, writer io.Writer, read func(context.Context, chan bn254.G1Affine) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	points := make(chan bn254.G1Affine, 1024)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- read(ctx, points)
+	}()
+
+	for p := range points {
+		if _, err := writer.Write(bellmanG1Bytes(&p)); err != nil {
+			cancel()
+			for range points {
+			}
+			<-errs
+			return err
+		}
+	}
+	return <-errs
+}
+
+// writeBellmanG2s writes the points read sends, uncompressed.
+func writeBellmanG2s(ctx context.Context, writer io.Writer, read func(context.Context, chan bn254.G2Affine) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	points := make(chan bn254.G2Affine, 1024)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- read(ctx, points)
+	}()
+
+	for p := range points {
+		if _, err := writer.Write(bellmanG2Bytes(&p)); err != nil {
+			cancel()
+			for range points {
+			}
+			<-errs
+			return err
+		}
+	}
+	return <-errs
+}
+
+// bellmanG1Bytes encodes p uncompressed, which gnark lays out like bellman.
+func bellmanG1Bytes(p *bn254.G1Affine) []byte {
+	if p.IsInfinity() {
+		b := make([]byte, 2*BN254_FIELD_ELEMENT_SIZE)
+		b[0] = bellmanInfinityFlag
+		return b
+	}
+	raw := p.RawBytes()
+	return raw[:]
+}
+
+// bellmanG2Bytes encodes p uncompressed, which gnark lays out like bellman.
+func bellmanG2Bytes(p *bn254.G2Affine) []byte {
+	if p.IsInfinity() {
+		b := make([]byte, 4*BN254_FIELD_ELEMENT_SIZE)
+		b[0] = bellmanInfinityFlag
+		return b
+	}
+	raw := p.RawBytes()
+	return raw[:]
+}
+
+// bellmanG1CompressedBytes encodes p compressed, with bellman's flags.
+func bellmanG1CompressedBytes(p *bn254.G1Affine) []byte {
+	b := p.Bytes()
+	b[0] = b[0]&^0xC0 | bellmanCompressedFlags(b[0])
+	return b[:]
+}
+
+// bellmanG2CompressedBytes encodes p compressed, with bellman's flags.
+func bellmanG2CompressedBytes(p *bn254.G2Affine) []byte {
+	b := p.Bytes()
+	b[0] = b[0]&^0xC0 | bellmanCompressedFlags(b[0])
+	return b[:]
+}
+
+// bellmanCompressedFlags converts the flags of a point gnark compressed.
+func bellmanCompressedFlags(b byte) byte {
+	switch b & 0xC0 {
+	case 0xC0:
+		return bellmanLargestFlag
+	case 0x40:
+		return bellmanInfinityFlag
+	}
+	return 0
+}
+
+func bellmanPublicKeyBytes(key PtauPublicKey) []byte {
+	b := make([]byte, 0, BELLMAN_PUBLIC_KEY_SIZE)
+	for _, p := range []*bn254.G1Affine{&key.TauG1S, &key.TauG1SX, &key.AlphaG1S, &key.AlphaG1SX, &key.BetaG1S, &key.BetaG1SX} {
+		b = append(b, bellmanG1Bytes(p)...)
+	}
+	for _, p := range []*bn254.G2Affine{&key.TauG2SPX, &key.AlphaG2SPX, &key.BetaG2SPX} {
+		b = append(b, bellmanG2Bytes(p)...)
+	}
+	return b
+}
+
+// initialBellmanChallengeHash is the hash of the challenge a ceremony of the
+// given power starts from, where every point is a generator.
+func initialBellmanChallengeHash(power uint32) [BELLMAN_HASH_SIZE]byte {
+	var hash [BELLMAN_HASH_SIZE]byte
+	hasher, _ := blake2b.New512(nil)
+
+	_, _, g1, g2 := bn254.Generators()
+	g1Bytes, g2Bytes := bellmanG1Bytes(&g1), bellmanG2Bytes(&g2)
+	N := 1 << power
+
+	blank := blake2b.Sum512(nil)
+	hasher.Write(blank[:])
+	for _, section := range []struct {
+		point []byte
+		count int
+	}{{g1Bytes, 2*N - 1}, {g2Bytes, N}, {g1Bytes, N}, {g1Bytes, N}, {g2Bytes, 1}} {
+		for i := 0; i < section.count; i++ {
+			hasher.Write(section.point)
+		}
+	}
+
+	copy(hash[:], hasher.Sum(nil))
+	return hash
+}
@@ -0,0 +1,106 @@
+package deserializer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A conversion of a large ptau takes hours, so WritePhase1FromPtauFile
+// regularly records how far it got in a sidecar file next to the .ph1 output.
+// ResumePhase1FromPtauFile picks the conversion up from there.
+
+// phase1CheckpointInterval is the number of points written between two
+// checkpoints of the same section.
+var phase1CheckpointInterval = 1 << 20
+
+// phase1CheckpointHook, when set, is called every time a checkpoint has been
+// recorded. Tests use it to interrupt a conversion.
+var phase1CheckpointHook func(phase1Checkpoint) error
+
+type phase1Checkpoint struct {
+	// Power of the ptau being converted
+	Power uint32 `json:"power"`
+	// Section is the index in phase1Sections of the section being written
+	Section int `json:"section"`
+	// Points is the number of points of Section already written
+	Points int `json:"points"`
+	// Offset is the size of the .ph1 prefix written so far
+	Offset int64 `json:"offset"`
+	// Hash is the hex encoded sha256 of the .ph1 prefix written so far
+	Hash string `json:"sha256"`
+	// Partial is the name of the file holding the .ph1 prefix, it lives in
+	// the same directory as the output
+	Partial string `json:"partial"`
+}
+
+func phase1CheckpointPath(outputPath string) string {
+	return outputPath + ".checkpoint"
+}
+
+func readPhase1Checkpoint(path string) (phase1Checkpoint, error) {
+	var checkpoint phase1Checkpoint
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return checkpoint, fmt.Errorf("no checkpoint found at %s, there is nothing to resume", path)
+	}
+	if err != nil {
+		return checkpoint, err
+	}
+
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return checkpoint, fmt.Errorf("invalid checkpoint %s: %w", path, err)
+	}
+	if checkpoint.Power > BN254_TWO_ADICITY || checkpoint.Section < 0 || checkpoint.Section >= len(phase1Sections) || checkpoint.Partial == "" {
+		return checkpoint, fmt.Errorf("invalid checkpoint %s", path)
+	}
+	// checkpoints are recorded before the last point of a section, at the
+	// offset of the points already written
+	section := phase1Sections[checkpoint.Section]
+	if numPoints := section.numPoints(1 << checkpoint.Power); checkpoint.Points < 0 || checkpoint.Points >= numPoints {
+		return checkpoint, fmt.Errorf("invalid checkpoint %s: %s has %d points, not %d", path, section.name, numPoints, checkpoint.Points)
+	}
+	offset := newPhase1Layout(byte(checkpoint.Power)).offsets()[checkpoint.Section] + int64(checkpoint.Points)*section.pointSize()
+	if checkpoint.Offset != offset {
+		return checkpoint, fmt.Errorf("invalid checkpoint %s: point %d of %s is at offset %d, not %d", path, checkpoint.Points, section.name, offset, checkpoint.Offset)
+	}
+	// the partial file is resumed in place, it must not point outside of the
+	// directory of the output
+	if filepath.Base(checkpoint.Partial) != checkpoint.Partial || checkpoint.Partial == "." || strings.Contains(checkpoint.Partial, "..") {
+		return checkpoint, fmt.Errorf("invalid checkpoint %s: %q is not the name of a partial file", path, checkpoint.Partial)
+	}
+
+	return checkpoint, nil
+}
+
+// writePhase1Checkpoint replaces the checkpoint at path, going through a
+// rename so that a crash never leaves a half-written checkpoint behind.
+func writePhase1Checkpoint(path string, checkpoint phase1Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	tmpFile, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(path+".tmp", path)
+}
@@ -0,0 +1,370 @@
+package deserializer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"golang.org/x/crypto/blake2b"
+)
+
+///////////////////////////////////////////////////////////////////
+///                        CONTRIBUTIONS                        ///
+///////////////////////////////////////////////////////////////////
+
+// Taken from the iden3/snarkjs repo, powersoftau_contribute.js. A
+// contribution multiplies the points of the challenge by powers of its
+// secrets:
+/*
+tauG1[i]      *= tau^i
+tauG2[i]      *= tau^i
+alphaTauG1[i] *= alpha*tau^i
+betaTauG1[i]  *= beta*tau^i
+betaG2        *= beta
+*/
+// and appends its record to section 7, along with the hashes chaining it to
+// the challenge it answered:
+/*
+challengeHash = nextChallenge of the last contribution, or the hash of the
+                initial challenge
+responseHash  = blake2b(challengeHash || compressed points || public key)
+nextChallenge = blake2b(responseHash || uncompressed points)
+*/
+// where the points of sections 2 to 6 are laid out like in a bellman file.
+
+// CONTRIBUTION_ENTROPY_SIZE is the number of random bytes the secrets of a
+// contribution are drawn from, along with the entropy given by the user.
+const CONTRIBUTION_ENTROPY_SIZE = 64
+
+// contributionSection is a section of points a contribution multiplies by
+// factor*tau^i.
+type contributionSection struct {
+	id     uint32
+	g2     bool
+	factor *fr.Element
+	count  int
+}
+
+// ReducedPtau is returned when contributing to a ptau truncated below the
+// power of its ceremony: snarkjs refuses to, as the contribution could only
+// cover the points left.
+type ReducedPtau struct {
+	Power         uint32
+	CeremonyPower uint32
+}
+
+func (r *ReducedPtau) Error() string {
+	return fmt.Sprintf("can't contribute to a ptau reduced to power %d from the ceremony's %d", r.Power, r.CeremonyPower)
+}
+
+// contributionChunkSize is the number of points multiplied at once.
+var contributionChunkSize = 1 << 16
+
+// Contribute writes the ptau with one more contribution to outputPath. Its
+// secrets are drawn from the system's randomness and entropy, then
+// forgotten. It returns the record of the contribution.
+func (ptauFile *PtauFile) Contribute(ctx context.Context, entropy []byte, name string, outputPath string) (PtauContribution, error) {
+	random := make([]byte, CONTRIBUTION_ENTROPY_SIZE)
+	if _, err := rand.Read(random); err != nil {
+		return PtauContribution{}, err
+	}
+	seedHash := blake2b.Sum512(append(random, entropy...))
+
+	contribution := PtauContribution{Type: CONTRIBUTION_TYPE_CONTRIBUTION, Name: name}
+	_, err := ptauFile.contribute(ctx, newSnarkjsRng(seedHash[:]), &contribution, outputPath)
+	return contribution, err
+}
+
+// contribute writes the ptau with contribution appended, drawing its secrets
+// from rng.
+func (ptauFile *PtauFile) contribute(ctx context.Context, rng *snarkjsRng, contribution *PtauContribution, outputPath string) (PtauSecrets, error) {
+	var secrets PtauSecrets
+
+	contributions, err := ptauFile.ReadContributions()
+	if err != nil {
+		return secrets, err
+	}
+	ceremonyPower, err := ptauFile.ceremonyPower()
+	if err != nil {
+		return secrets, err
+	}
+	if ceremonyPower != ptauFile.Header.Power {
+		return secrets, &ReducedPtau{Power: ptauFile.Header.Power, CeremonyPower: ceremonyPower}
+	}
+
+	var challengeHash [BELLMAN_HASH_SIZE]byte
+	if n := len(contributions); n > 0 {
+		challengeHash = contributions[n-1].NextChallenge
+	} else {
+		challengeHash = initialBellmanChallengeHash(ceremonyPower)
+	}
+	secrets, contribution.Key = newPtauKey(rng, challengeHash)
+
+	outputFile, err := createAtomicFile(outputPath)
+	if err != nil {
+		return secrets, err
+	}
+	err = writeContributedPtau(ctx, outputFile, ptauFile.Header.Power, ceremonyPower, contributions, &secrets, challengeHash, contribution, ptauFile.writeContributionSection)
+	if err != nil {
+		outputFile.Abort()
+		return secrets, err
+	}
+
+	log().Info("contributed to ptau", "output", outputPath, "contributions", len(contributions)+1)
+	return secrets, outputFile.Commit()
+}
+
+// writeContributedPtau writes a ptau of the given power, whose sections
+// writeSection computes, followed by the previous contributions and the one
+// the points come from.
+func writeContributedPtau(
+	ctx context.Context,
+	outputFile *atomicFile,
+	power, ceremonyPower uint32,
+	previous []PtauContribution,
+	secrets *PtauSecrets,
+	challengeHash [BELLMAN_HASH_SIZE]byte,
+	contribution *PtauContribution,
+	writeSection func(ctx context.Context, section contributionSection, tau *fr.Element, writer io.Writer, responseHasher io.Writer) error,
+) error {
+	writer := newPtauWriter(outputFile, 7, power, ceremonyPower)
+	N := 1 << power
+
+	responseHasher, err := blake2b.New512(nil)
+	if err != nil {
+		return err
+	}
+	responseHasher.Write(challengeHash[:])
+
+	var one fr.Element
+	one.SetOne()
+	for _, section := range []contributionSection{
+		{2, false, &one, 2*N - 1},
+		{3, true, &one, N},
+		{4, false, &secrets.Alpha, N},
+		{5, false, &secrets.Beta, N},
+		{6, true, &secrets.Beta, 1},
+	} {
+		writer.beginSection(section.id, ptauPointsSize(section.id, power))
+		log().Debug("computing section", "section", section.id, "points", section.count)
+		if err := writeSection(ctx, section, &secrets.Tau, writer, responseHasher); err != nil {
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	if contribution.PartialHash, err = partialHash(responseHasher.(encoding.BinaryMarshaler)); err != nil {
+		return err
+	}
+	responseHasher.Write(bellmanPublicKeyBytes(contribution.Key))
+	responseHash := responseHasher.Sum(nil)
+
+	// read the new points back from the file, to hash them uncompressed and
+	// record the first ones
+	ptauFile := &PtauFile{
+		Header:   PtauHeader{N8: BN254_FIELD_ELEMENT_SIZE, Prime: *fp.Modulus(), Power: power},
+		Sections: writer.Sections,
+		Reader:   outputFile.File,
+	}
+	challengeHasher, err := blake2b.New512(nil)
+	if err != nil {
+		return err
+	}
+	if err := writeBellman(ctx, ptauFile, challengeHasher, [BELLMAN_HASH_SIZE]byte(responseHash), nil); err != nil {
+		return err
+	}
+	copy(contribution.NextChallenge[:], challengeHasher.Sum(nil))
+	if err := ptauFile.readContributionPoints(contribution); err != nil {
+		return err
+	}
+	if _, err := outputFile.Seek(0, io.SeekEnd); err != nil {
+		return err
+	}
+
+	// Contributions (7)
+	contributions := binary.LittleEndian.AppendUint32(nil, uint32(len(previous)+1))
+	for i := range previous {
+		contributions = appendContribution(contributions, &previous[i])
+	}
+	contributions = appendContribution(contributions, contribution)
+	writer.beginSection(7, uint64(len(contributions)))
+	writer.Write(contributions)
+	return writer.Flush()
+}
+
+// readContributionPoints reads the points a contribution records: the second
+// points of the tau sections, and the first ones of the others.
+func (ptauFile *PtauFile) readContributionPoints(contribution *PtauContribution) error {
+	buffer := make([]byte, BN254_FIELD_ELEMENT_SIZE)
+	g1Size, g2Size := int64(2*BN254_FIELD_ELEMENT_SIZE), int64(4*BN254_FIELD_ELEMENT_SIZE)
+
+	for _, point := range []struct {
+		sectionId uint32
+		offset    int64
+		g1        *bn254.G1Affine
+		g2        *bn254.G2Affine
+	}{
+		{2, g1Size, &contribution.TauG1, nil},
+		{3, g2Size, nil, &contribution.TauG2},
+		{4, 0, &contribution.AlphaG1, nil},
+		{5, 0, &contribution.BetaG1, nil},
+		{6, 0, nil, &contribution.BetaG2},
+	} {
+		seekToUniqueSection(ptauFile.Reader, ptauFile.Sections, point.sectionId)
+		if _, err := ptauFile.Reader.Seek(point.offset, io.SeekCurrent); err != nil {
+			return err
+		}
+
+		var err error
+		if point.g1 != nil {
+			*point.g1, err = readG1Affine(ptauFile.Reader, buffer)
+		} else {
+			*point.g2, err = readG2Affine(ptauFile.Reader, buffer)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeContributionSection multiplies the points of a section of the ptau.
+func (ptauFile *PtauFile) writeContributionSection(ctx context.Context, section contributionSection, tau *fr.Element, writer io.Writer, responseHasher io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if section.g2 {
+		points := make(chan bn254.G2Affine, contributionChunkSize)
+		errs := make(chan error, 1)
+		go func() {
+			if section.id == 3 {
+				errs <- ptauFile.ReadTauG2(ctx, points)
+				return
+			}
+			defer close(points)
+			betaG2, err := ptauFile.ReadBetaG2()
+			if err == nil {
+				points <- betaG2
+			}
+			errs <- err
+		}()
+
+		if err := scaleG2s(ctx, points, section.factor, tau, writer, responseHasher); err != nil {
+			cancel()
+			for range points {
+			}
+			<-errs
+			return err
+		}
+		return <-errs
+	}
+
+	read := map[uint32]func(context.Context, chan bn254.G1Affine) error{
+		2: ptauFile.ReadTauG1,
+		4: ptauFile.ReadAlphaTauG1,
+		5: ptauFile.ReadBetaTauG1,
+	}[section.id]
+	points := make(chan bn254.G1Affine, contributionChunkSize)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- read(ctx, points)
+	}()
+
+	if err := scaleG1s(ctx, points, section.factor, tau, writer, responseHasher); err != nil {
+		cancel()
+		for range points {
+		}
+		<-errs
+		return err
+	}
+	return <-errs
+}
+
+// scaleG1s multiplies the i-th point received by factor*tau^i, a chunk at a
+// time, and writes the results like writeContributionSection.
+func scaleG1s(ctx context.Context, points chan bn254.G1Affine, factor, tau *fr.Element, writer io.Writer, responseHasher io.Writer) error {
+	scalar := *factor
+	chunk := make([]bn254.G1Affine, 0, contributionChunkSize)
+	scalars := make([]fr.Element, 0, contributionChunkSize)
+
+	for {
+		p, ok := <-points
+		if ok {
+			chunk = append(chunk, p)
+			scalars = append(scalars, scalar)
+			scalar.Mul(&scalar, tau)
+			if len(chunk) < contributionChunkSize {
+				continue
+			}
+		}
+		if len(chunk) == 0 {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		parallelize(len(chunk), func(start, end int) {
+			var s big.Int
+			for i := start; i < end; i++ {
+				chunk[i].ScalarMultiplication(&chunk[i], scalars[i].BigInt(&s))
+			}
+		})
+		for i := range chunk {
+			if _, err := writer.Write(montgomeryLE(chunk[i].X, chunk[i].Y)); err != nil {
+				return err
+			}
+			responseHasher.Write(bellmanG1CompressedBytes(&chunk[i]))
+		}
+		chunk, scalars = chunk[:0], scalars[:0]
+	}
+}
+
+// scaleG2s multiplies the i-th point received by factor*tau^i, a chunk at a
+// time, and writes the results like writeContributionSection.
+func scaleG2s(ctx context.Context, points chan bn254.G2Affine, factor, tau *fr.Element, writer io.Writer, responseHasher io.Writer) error {
+	scalar := *factor
+	chunk := make([]bn254.G2Affine, 0, contributionChunkSize)
+	scalars := make([]fr.Element, 0, contributionChunkSize)
+
+	for {
+		p, ok := <-points
+		if ok {
+			chunk = append(chunk, p)
+			scalars = append(scalars, scalar)
+			scalar.Mul(&scalar, tau)
+			if len(chunk) < contributionChunkSize {
+				continue
+			}
+		}
+		if len(chunk) == 0 {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		parallelize(len(chunk), func(start, end int) {
+			var s big.Int
+			for i := start; i < end; i++ {
+				chunk[i].ScalarMultiplication(&chunk[i], scalars[i].BigInt(&s))
+			}
+		})
+		for i := range chunk {
+			if _, err := writer.Write(montgomeryLE(chunk[i].X.A0, chunk[i].X.A1, chunk[i].Y.A0, chunk[i].Y.A1)); err != nil {
+				return err
+			}
+			responseHasher.Write(bellmanG2CompressedBytes(&chunk[i]))
+		}
+		chunk, scalars = chunk[:0], scalars[:0]
+	}
+}
@@ -0,0 +1,277 @@
+package deserializer
+
+import (
+	"bufio"
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"golang.org/x/crypto/blake2b"
+)
+
+///////////////////////////////////////////////////////////////////
+///                    PTAU CONTRIBUTIONS (7)                   ///
+///////////////////////////////////////////////////////////////////
+
+// Taken from the iden3/snarkjs repo, powersoftau_utils.js (readContribution)
+// https://github.com/iden3/snarkjs/blob/master/src/powersoftau_utils.js
+/*
+contributions(7)
+    4 bytes, NContributions
+    {NContributions}[
+        tauG1 (G1), tauG2 (G2), alphaG1 (G1), betaG1 (G1), betaG2 (G2)
+        pubKey
+            tau_g1s, tau_g1sx, alpha_g1s, alpha_g1sx, beta_g1s, beta_g1sx (G1)
+            tau_g2spx, alpha_g2spx, beta_g2spx (G2)
+        216 bytes, partialHash
+        64 bytes, hashNewChallenge
+        4 bytes, type (0 contribution, 1 beacon)
+        4 bytes, paramLength
+        {paramLength} bytes, params, sorted by key
+            1: name (1 byte length, string)
+            2: numIterationsExp (1 byte)
+            3: beaconHash (1 byte length, bytes)
+    ]
+*/
+
+const (
+	CONTRIBUTION_TYPE_CONTRIBUTION = uint32(0)
+	CONTRIBUTION_TYPE_BEACON       = uint32(1)
+)
+
+// CONTRIBUTION_NAME_MAX_LENGTH is the number of characters of a name snarkjs
+// keeps.
+const CONTRIBUTION_NAME_MAX_LENGTH = 64
+
+// PtauPublicKey is the proof of knowledge of a contribution's secrets.
+type PtauPublicKey struct {
+	TauG1S     bn254.G1Affine
+	TauG1SX    bn254.G1Affine
+	AlphaG1S   bn254.G1Affine
+	AlphaG1SX  bn254.G1Affine
+	BetaG1S    bn254.G1Affine
+	BetaG1SX   bn254.G1Affine
+	TauG2SPX   bn254.G2Affine
+	AlphaG2SPX bn254.G2Affine
+	BetaG2SPX  bn254.G2Affine
+}
+
+type PtauContribution struct {
+	TauG1         bn254.G1Affine
+	TauG2         bn254.G2Affine
+	AlphaG1       bn254.G1Affine
+	BetaG1        bn254.G1Affine
+	BetaG2        bn254.G2Affine
+	Key           PtauPublicKey
+	PartialHash   [216]byte
+	NextChallenge [64]byte
+	Type          uint32
+	// Optional parameters
+	Name             string
+	NumIterationsExp uint8
+	BeaconHash       []byte
+}
+
+// ReadContributions reads the contribution history stored in section 7.
+func (ptauFile *PtauFile) ReadContributions() ([]PtauContribution, error) {
+	if len(ptauFile.Sections) <= 7 || len(ptauFile.Sections[7]) == 0 {
+		return nil, fmt.Errorf("ptau has no contributions section")
+	}
+	seekToUniqueSection(ptauFile.Reader, ptauFile.Sections, 7)
+
+	return readContributions(bufio.NewReader(io.LimitReader(ptauFile.Reader, int64(ptauFile.Sections[7][0].size))))
+}
+
+func readContributions(reader io.Reader) ([]PtauContribution, error) {
+	numContributions, err := readULE32(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	contributions := make([]PtauContribution, numContributions)
+	for i := range contributions {
+		if contributions[i], err = readContribution(reader); err != nil {
+			return nil, fmt.Errorf("contribution %d: %w", i+1, err)
+		}
+	}
+
+	return contributions, nil
+}
+
+func readContribution(reader io.Reader) (PtauContribution, error) {
+	var c PtauContribution
+	var err error
+	buffer := make([]byte, BN254_FIELD_ELEMENT_SIZE)
+
+	if c.TauG1, err = readG1Affine(reader, buffer); err != nil {
+		return c, err
+	}
+	if c.TauG2, err = readG2Affine(reader, buffer); err != nil {
+		return c, err
+	}
+	for _, p := range []*bn254.G1Affine{&c.AlphaG1, &c.BetaG1} {
+		if *p, err = readG1Affine(reader, buffer); err != nil {
+			return c, err
+		}
+	}
+	if c.BetaG2, err = readG2Affine(reader, buffer); err != nil {
+		return c, err
+	}
+
+	for _, p := range []*bn254.G1Affine{&c.Key.TauG1S, &c.Key.TauG1SX, &c.Key.AlphaG1S, &c.Key.AlphaG1SX, &c.Key.BetaG1S, &c.Key.BetaG1SX} {
+		if *p, err = readG1Affine(reader, buffer); err != nil {
+			return c, err
+		}
+	}
+	for _, p := range []*bn254.G2Affine{&c.Key.TauG2SPX, &c.Key.AlphaG2SPX, &c.Key.BetaG2SPX} {
+		if *p, err = readG2Affine(reader, buffer); err != nil {
+			return c, err
+		}
+	}
+
+	if _, err := io.ReadFull(reader, c.PartialHash[:]); err != nil {
+		return c, err
+	}
+	if _, err := io.ReadFull(reader, c.NextChallenge[:]); err != nil {
+		return c, err
+	}
+	if c.Type, err = readULE32(reader); err != nil {
+		return c, err
+	}
+
+	paramLength, err := readULE32(reader)
+	if err != nil {
+		return c, err
+	}
+	params := make([]byte, paramLength)
+	if _, err := io.ReadFull(reader, params); err != nil {
+		return c, err
+	}
+
+	lastKey := byte(0)
+	for len(params) > 0 {
+		key := params[0]
+		if key <= lastKey {
+			return c, fmt.Errorf("parameters in the contribution must be sorted")
+		}
+		lastKey = key
+
+		switch {
+		case key == 1 && len(params) >= 2 && len(params) >= 2+int(params[1]):
+			c.Name = string(params[2 : 2+params[1]])
+			params = params[2+params[1]:]
+		case key == 2 && len(params) >= 2:
+			c.NumIterationsExp = params[1]
+			params = params[2:]
+		case key == 3 && len(params) >= 2 && len(params) >= 2+int(params[1]):
+			c.BeaconHash = append([]byte{}, params[2:2+params[1]]...)
+			params = params[2+params[1]:]
+		default:
+			return c, fmt.Errorf("invalid contribution parameter %d", key)
+		}
+	}
+
+	return c, nil
+}
+
+// appendContribution lays out c the way snarkjs writes it in section 7.
+func appendContribution(b []byte, c *PtauContribution) []byte {
+	b = append(b, montgomeryLE(c.TauG1.X, c.TauG1.Y)...)
+	b = append(b, montgomeryLE(c.TauG2.X.A0, c.TauG2.X.A1, c.TauG2.Y.A0, c.TauG2.Y.A1)...)
+	for _, p := range []*bn254.G1Affine{&c.AlphaG1, &c.BetaG1} {
+		b = append(b, montgomeryLE(p.X, p.Y)...)
+	}
+	b = append(b, montgomeryLE(c.BetaG2.X.A0, c.BetaG2.X.A1, c.BetaG2.Y.A0, c.BetaG2.Y.A1)...)
+
+	for _, p := range []*bn254.G1Affine{&c.Key.TauG1S, &c.Key.TauG1SX, &c.Key.AlphaG1S, &c.Key.AlphaG1SX, &c.Key.BetaG1S, &c.Key.BetaG1SX} {
+		b = append(b, montgomeryLE(p.X, p.Y)...)
+	}
+	for _, p := range []*bn254.G2Affine{&c.Key.TauG2SPX, &c.Key.AlphaG2SPX, &c.Key.BetaG2SPX} {
+		b = append(b, montgomeryLE(p.X.A0, p.X.A1, p.Y.A0, p.Y.A1)...)
+	}
+
+	b = append(b, c.PartialHash[:]...)
+	b = append(b, c.NextChallenge[:]...)
+	b = binary.LittleEndian.AppendUint32(b, c.Type)
+
+	var params []byte
+	if name := []rune(c.Name); len(name) > 0 {
+		if len(name) > CONTRIBUTION_NAME_MAX_LENGTH {
+			name = name[:CONTRIBUTION_NAME_MAX_LENGTH]
+		}
+		params = append(params, 1, byte(len(string(name))))
+		params = append(params, string(name)...)
+	}
+	if c.Type == CONTRIBUTION_TYPE_BEACON {
+		params = append(params, 2, c.NumIterationsExp, 3, byte(len(c.BeaconHash)))
+		params = append(params, c.BeaconHash...)
+	}
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(params)))
+	return append(b, params...)
+}
+
+// The partial hash is the state of the blake2b-wasm hasher snarkjs hashes the
+// response with, saved before the public key:
+/*
+   128 bytes, b, the block being filled
+   8 x 8 bytes, h, the chaining value
+   8 bytes, t0, and 8 bytes, t1, the number of bytes compressed
+   8 bytes, c, the number of bytes in b
+*/
+// in little-endian. Like Go's, the hasher only compresses a full block once
+// more data comes, so both states hold the same values.
+const (
+	partialHashHOffset = 128
+	partialHashTOffset = partialHashHOffset + 8*8
+	partialHashCOffset = partialHashTOffset + 2*8
+)
+
+// goStateLength is the length of the state of Go's blake2b before the block:
+// "b2b", h, c and the size of the digest.
+const goStateLength = 3 + 8*8 + 2*8 + 1
+
+// partialHash saves the state of hasher, a blake2b-512 hasher, the way
+// snarkjs does.
+func partialHash(hasher encoding.BinaryMarshaler) ([216]byte, error) {
+	var partial [216]byte
+	state, err := hasher.MarshalBinary()
+	if err != nil {
+		return partial, err
+	}
+
+	for i := 0; i < 10; i++ {
+		binary.LittleEndian.PutUint64(partial[partialHashHOffset+8*i:], binary.BigEndian.Uint64(state[3+8*i:]))
+	}
+	offset := state[goStateLength+blake2b.BlockSize]
+	copy(partial[:offset], state[goStateLength:])
+	binary.LittleEndian.PutUint64(partial[partialHashCOffset:], uint64(offset))
+	return partial, nil
+}
+
+// ResponseHash is the hash of the response the contribution wrote, that the
+// hash of the next challenge starts with.
+func (c *PtauContribution) ResponseHash() ([BELLMAN_HASH_SIZE]byte, error) {
+	var hash [BELLMAN_HASH_SIZE]byte
+
+	offset := binary.LittleEndian.Uint64(c.PartialHash[partialHashCOffset:])
+	if offset > blake2b.BlockSize {
+		return hash, fmt.Errorf("invalid partial hash, %d bytes in the block", offset)
+	}
+	state := append([]byte("b2b"), make([]byte, goStateLength-3+blake2b.BlockSize+1)...)
+	for i := 0; i < 10; i++ {
+		binary.BigEndian.PutUint64(state[3+8*i:], binary.LittleEndian.Uint64(c.PartialHash[partialHashHOffset+8*i:]))
+	}
+	state[goStateLength-1] = blake2b.Size
+	copy(state[goStateLength:], c.PartialHash[:offset])
+	state[goStateLength+blake2b.BlockSize] = byte(offset)
+
+	hasher, _ := blake2b.New512(nil)
+	if err := hasher.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err != nil {
+		return hash, err
+	}
+	hasher.Write(bellmanPublicKeyBytes(c.Key))
+	copy(hash[:], hasher.Sum(nil))
+	return hash, nil
+}
@@ -0,0 +1,62 @@
+package deserializer
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+)
+
+// The headers of ptau and zkey files only carry the size and the modulus of
+// the fields they are defined over, this is enough to tell the curves apart.
+
+// UnsupportedCurve is returned when a file is defined over another curve than
+// bn254, or over no known curve at all (which usually means it is corrupted).
+type UnsupportedCurve struct {
+	Curve ecc.ID
+	N8    uint32
+	Prime big.Int
+}
+
+func (r *UnsupportedCurve) Error() string {
+	if r.Curve == ecc.UNKNOWN {
+		return fmt.Sprintf("unknown curve with a %d byte prime %s, only bn254 is supported", r.N8, r.Prime.String())
+	}
+	return fmt.Sprintf("%s is not supported, only bn254 is", r.Curve)
+}
+
+// curveFromBaseField identifies the curve whose base field has an n8 byte
+// modulus q.
+func curveFromBaseField(n8 uint32, q *big.Int) ecc.ID {
+	for _, id := range ecc.Implemented() {
+		if id.BaseField().Cmp(q) == 0 && fieldElementSize(q) == n8 {
+			return id
+		}
+	}
+	return ecc.UNKNOWN
+}
+
+// curveFromFields identifies the curve whose base field has an n8q byte
+// modulus q and whose scalar field has an n8r byte modulus r.
+func curveFromFields(n8q uint32, q *big.Int, n8r uint32, r *big.Int) ecc.ID {
+	curve := curveFromBaseField(n8q, q)
+	if curve == ecc.UNKNOWN || curve.ScalarField().Cmp(r) != 0 || fieldElementSize(r) != n8r {
+		return ecc.UNKNOWN
+	}
+	return curve
+}
+
+// fieldElementSize is the size snarkjs gives to the elements of the field of
+// modulus q: the modulus rounded up to a whole number of 64 bit limbs.
+func fieldElementSize(q *big.Int) uint32 {
+	return uint32((q.BitLen()+63)/64) * 8
+}
+
+// checkBN254 fails with UnsupportedCurve when curve, detected from the n8
+// byte prime q, is not bn254.
+func checkBN254(curve ecc.ID, n8 uint32, q *big.Int) error {
+	if curve != ecc.BN254 {
+		return &UnsupportedCurve{Curve: curve, N8: n8, Prime: *q}
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package deserializer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+///////////////////////////////////////////////////////////////////
+///                      DERIVATION CHECK                       ///
+///////////////////////////////////////////////////////////////////
+
+// A .ph1 is derived from a ptau when every one of its sections is a prefix of
+// the matching ptau section, and its header matches the ptau: its power is
+// at most the one of the ptau, and it records as many contributions as
+// section 7 holds. The points are either all compared, the ptau points being
+// compressed like gnark writes them so that the .ph1 doesn't need to be
+// decompressed, or spot-checked: for random indices i and random scalars r_i,
+/*
+sum(r_i * ptau[i]) == sum(r_i * ph1[i])
+*/
+// which only holds for differing sampled points with negligible probability.
+// Spot-checking is probabilistic sampling, points that aren't sampled aren't
+// checked at all: a .ph1 where k of the n points of a section differ passes
+// s samples with probability about (1 - k/n)^s. It only reads, decompresses
+// and combines s points per section rather than all of them, which is what
+// makes it fast on large files.
+
+// DerivationMismatch is returned when a .ph1 was not derived from a ptau.
+type DerivationMismatch struct {
+	// Section is the .ph1 section that differs, or header
+	Section string
+	// Index is the first differing point, or -1 when it is unknown
+	Index  int
+	Reason string
+}
+
+func (r *DerivationMismatch) Error() string {
+	return fmt.Sprintf("the .ph1 was not derived from the ptau, %s: %s", r.Section, r.Reason)
+}
+
+// derivationChunkSize is the number of points read from each file and
+// compared at once.
+var derivationChunkSize = 1 << 16
+
+// CheckDerivation checks that phase1File was converted from ptauFile. With
+// samples set to 0, every point is compared, otherwise each section is
+// spot-checked on a random linear combination of samples random points.
+func CheckDerivation(ctx context.Context, ptauFile *PtauFile, phase1File *Phase1File, samples int) error {
+	if uint32(phase1File.Header.Power) > ptauFile.Header.Power {
+		return &DerivationMismatch{Section: "header", Index: -1, Reason: fmt.Sprintf("power %d is larger than the one of the ptau, %d", phase1File.Header.Power, ptauFile.Header.Power)}
+	}
+
+	contributions, err := ptauFile.ReadContributions()
+	if err != nil {
+		return err
+	}
+	if int(phase1File.Header.Contributions) != len(contributions) {
+		return &DerivationMismatch{Section: "header", Index: -1, Reason: fmt.Sprintf("%d contributions are recorded, but section 7 of the ptau holds %d", phase1File.Header.Contributions, len(contributions))}
+	}
+
+	for _, section := range phase1Sections {
+		log().Debug("checking section", "section", section.name, "samples", samples)
+		if samples == 0 {
+			err = checkDerivedSection(ctx, ptauFile, phase1File, section)
+		} else {
+			err = checkDerivedSamples(ptauFile, phase1File, section, samples)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	log().Info("the .ph1 was derived from the ptau", "power", phase1File.Header.Power, "contributions", len(contributions))
+	return nil
+}
+
+// checkDerivedSection compares every point of section, a chunk at a time.
+// The chunks are read from each file in turn, then compared in parallel.
+func checkDerivedSection(ctx context.Context, ptauFile *PtauFile, phase1File *Phase1File, section phase1Section) error {
+	count := section.numPoints(1 << phase1File.Header.Power)
+
+	ptauReader, err := ptauFile.phase1SectionReader(section, 0)
+	if err != nil {
+		return err
+	}
+	phase1Reader, err := phase1File.phase1SectionReader(section, 0)
+	if err != nil {
+		return err
+	}
+
+	ptauSize, phase1Size := int(section.ptauPointSize()), int(section.pointSize())
+	ptauChunk := make([]byte, derivationChunkSize*ptauSize)
+	phase1Chunk := make([]byte, derivationChunkSize*phase1Size)
+	mismatches := make([]bool, derivationChunkSize)
+
+	for start := 0; start < count; start += derivationChunkSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n := min(derivationChunkSize, count-start)
+		if _, err := io.ReadFull(ptauReader, ptauChunk[:n*ptauSize]); err != nil {
+			return fmt.Errorf("%s point %d: %w", section.name, start, err)
+		}
+		if _, err := io.ReadFull(phase1Reader, phase1Chunk[:n*phase1Size]); err != nil {
+			return fmt.Errorf("%s point %d: %w", section.name, start, err)
+		}
+
+		parallelize(n, func(first, end int) {
+			buffer := make([]byte, 4*BN254_FIELD_ELEMENT_SIZE)
+			for i := first; i < end; i++ {
+				reader := bytes.NewReader(ptauChunk[i*ptauSize:])
+				phase1Point := phase1Chunk[i*phase1Size : (i+1)*phase1Size]
+				if section.isG2 {
+					p, err := ptauFile.readPhase1G2(reader, buffer)
+					compressed := p.Bytes()
+					mismatches[i] = err != nil || !bytes.Equal(compressed[:], phase1Point)
+					continue
+				}
+				p, err := ptauFile.readPhase1G1(reader, buffer)
+				compressed := p.Bytes()
+				mismatches[i] = err != nil || !bytes.Equal(compressed[:], phase1Point)
+			}
+		})
+
+		for i, mismatch := range mismatches[:n] {
+			if mismatch {
+				return &DerivationMismatch{Section: section.name, Index: start + i, Reason: fmt.Sprintf("point %d differs", start+i)}
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkDerivedSamples spot-checks section on a random linear combination of
+// samples distinct random points, or of all of them when there are fewer.
+func checkDerivedSamples(ptauFile *PtauFile, phase1File *Phase1File, section phase1Section, samples int) error {
+	count := section.numPoints(1 << phase1File.Header.Power)
+	samples = min(samples, count)
+
+	// the indices are distinct, and read in the order of the files
+	indices := make([]int, 0, samples)
+	drawn := make(map[int]bool, samples)
+	for i := 0; len(indices) < samples; i++ {
+		index := i
+		if samples < count {
+			random, err := rand.Int(rand.Reader, big.NewInt(int64(count)))
+			if err != nil {
+				return err
+			}
+			index = int(random.Int64())
+		}
+		if !drawn[index] {
+			drawn[index] = true
+			indices = append(indices, index)
+		}
+	}
+	scalars := make([]fr.Element, samples)
+	for i := range scalars {
+		if _, err := scalars[i].SetRandom(); err != nil {
+			return err
+		}
+	}
+	sort.Ints(indices)
+
+	buffer := make([]byte, 4*BN254_FIELD_ELEMENT_SIZE)
+	reason := fmt.Sprintf("a random linear combination of %d points differs", samples)
+
+	if section.isG2 {
+		ptauPoints := make([]bn254.G2Affine, samples)
+		phase1Points := make([]bn254.G2Affine, samples)
+		for i, index := range indices {
+			var err error
+			if ptauPoints[i], err = readPhase1G2At(ptauFile, section, index, buffer); err != nil {
+				return err
+			}
+			if phase1Points[i], err = readPhase1G2At(phase1File, section, index, buffer); err != nil {
+				return &DerivationMismatch{Section: section.name, Index: index, Reason: err.Error()}
+			}
+		}
+
+		var ptauSum, phase1Sum bn254.G2Affine
+		if _, err := ptauSum.MultiExp(ptauPoints, scalars, ecc.MultiExpConfig{}); err != nil {
+			return err
+		}
+		if _, err := phase1Sum.MultiExp(phase1Points, scalars, ecc.MultiExpConfig{}); err != nil {
+			return err
+		}
+		if !ptauSum.Equal(&phase1Sum) {
+			return &DerivationMismatch{Section: section.name, Index: -1, Reason: reason}
+		}
+		return nil
+	}
+
+	ptauPoints := make([]bn254.G1Affine, samples)
+	phase1Points := make([]bn254.G1Affine, samples)
+	for i, index := range indices {
+		var err error
+		if ptauPoints[i], err = readPhase1G1At(ptauFile, section, index, buffer); err != nil {
+			return err
+		}
+		if phase1Points[i], err = readPhase1G1At(phase1File, section, index, buffer); err != nil {
+			return &DerivationMismatch{Section: section.name, Index: index, Reason: err.Error()}
+		}
+	}
+
+	var ptauSum, phase1Sum bn254.G1Affine
+	if _, err := ptauSum.MultiExp(ptauPoints, scalars, ecc.MultiExpConfig{}); err != nil {
+		return err
+	}
+	if _, err := phase1Sum.MultiExp(phase1Points, scalars, ecc.MultiExpConfig{}); err != nil {
+		return err
+	}
+	if !ptauSum.Equal(&phase1Sum) {
+		return &DerivationMismatch{Section: section.name, Index: -1, Reason: reason}
+	}
+	return nil
+}
+
+// readPhase1G1At reads point i of section from source.
+func readPhase1G1At(source phase1Source, section phase1Section, i int, buffer []byte) (bn254.G1Affine, error) {
+	reader, err := source.phase1SectionReader(section, i)
+	if err != nil {
+		return bn254.G1Affine{}, err
+	}
+	return source.readPhase1G1(reader, buffer)
+}
+
+// readPhase1G2At reads point i of section from source.
+func readPhase1G2At(source phase1Source, section phase1Section, i int, buffer []byte) (bn254.G2Affine, error) {
+	reader, err := source.phase1SectionReader(section, i)
+	if err != nil {
+		return bn254.G2Affine{}, err
+	}
+	return source.readPhase1G2(reader, buffer)
+}
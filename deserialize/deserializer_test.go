@@ -1,15 +1,35 @@
 package deserializer
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/consensys/gnark-crypto/ecc"
+	curve "github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/constraint"
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
 
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/blake2b"
 )
 
 type TestCircuit struct {
@@ -80,6 +100,28 @@ contributions(7) - Ignore contributions, users can verify using snarkjs
 
 const r1csFilePath = "test.r1cs"
 
+// testPtauPath is a ptau of power 8 generated for the tests, with a single
+// contribution of secrets testPtauSecrets.
+var testPtauPath string
+var testPtauSecrets PtauSecrets
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "ptau-deserializer")
+	if err != nil {
+		panic(err)
+	}
+
+	testPtauPath = dir + "/08.ptau"
+	testPtauSecrets, err = WriteNewPtau(context.Background(), 8, []byte("ptau-deserializer"), "test", testPtauPath)
+	if err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 func TestSerializeR1CS(t *testing.T) {
 	assert := require.New(t)
 	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &TestCircuit{})
@@ -94,7 +136,7 @@ func TestSerializeR1CS(t *testing.T) {
 func TestDeserializePtauConvertPhase1(t *testing.T) {
 	assert := require.New(t)
 
-	input_path := "08.ptau"
+	input_path := testPtauPath
 
 	ptau, err := ReadPtau(input_path)
 
@@ -122,6 +164,355 @@ func TestDeserializePtauConvertPhase1(t *testing.T) {
 	}
 }
 
+func TestWritePhase1FromPtauReader(t *testing.T) {
+	assert := require.New(t)
+
+	input_path := testPtauPath
+	dir := t.TempDir()
+
+	ptauFile, err := InitPtau(input_path)
+	assert.NoError(err)
+	defer ptauFile.Close()
+
+	err = WritePhase1FromPtauFile(context.Background(), ptauFile, dir+"/file.ph1")
+	assert.NoError(err)
+
+	// the stream conversion must not rely on the input being seekable
+	input, err := os.Open(input_path)
+	assert.NoError(err)
+	defer input.Close()
+
+	err = WritePhase1FromPtauReader(context.Background(), struct{ io.Reader }{input}, dir+"/stream.ph1")
+	assert.NoError(err)
+
+	fromFile, err := os.ReadFile(dir + "/file.ph1")
+	assert.NoError(err)
+	fromStream, err := os.ReadFile(dir + "/stream.ph1")
+	assert.NoError(err)
+	assert.Equal(fromFile, fromStream)
+}
+
+func TestResumePhase1FromPtauFile(t *testing.T) {
+	assert := require.New(t)
+
+	input_path := testPtauPath
+	dir := t.TempDir()
+
+	ptauFile, err := InitPtau(input_path)
+	assert.NoError(err)
+	defer ptauFile.Close()
+
+	err = WritePhase1FromPtauFile(context.Background(), ptauFile, dir+"/uninterrupted.ph1")
+	assert.NoError(err)
+	expected, err := os.ReadFile(dir + "/uninterrupted.ph1")
+	assert.NoError(err)
+
+	defer func(interval int) {
+		phase1CheckpointInterval = interval
+		phase1CheckpointHook = nil
+	}(phase1CheckpointInterval)
+	phase1CheckpointInterval = 100
+
+	errInterrupted := errors.New("interrupted")
+
+	// interrupt the conversion at every checkpoint in turn, then resume it
+	for stop := 1; ; stop++ {
+		outputPath := fmt.Sprintf("%s/interrupted_%d.ph1", dir, stop)
+
+		checkpoints := 0
+		phase1CheckpointHook = func(phase1Checkpoint) error {
+			checkpoints++
+			if checkpoints == stop {
+				return errInterrupted
+			}
+			return nil
+		}
+
+		err = WritePhase1FromPtauFile(context.Background(), ptauFile, outputPath)
+		phase1CheckpointHook = nil
+		if err == nil {
+			break
+		}
+		assert.ErrorIs(err, errInterrupted)
+
+		// a failed conversion must not leave anything at the output path
+		_, err = os.Stat(outputPath)
+		assert.ErrorIs(err, os.ErrNotExist)
+
+		checkpoint, err := readPhase1Checkpoint(phase1CheckpointPath(outputPath))
+		assert.NoError(err)
+
+		// points written after the checkpoint must be discarded on resume
+		output, err := os.OpenFile(dir+"/"+checkpoint.Partial, os.O_APPEND|os.O_WRONLY, 0)
+		assert.NoError(err)
+		_, err = output.Write([]byte("garbage"))
+		assert.NoError(err)
+		assert.NoError(output.Close())
+
+		err = ResumePhase1FromPtauFile(context.Background(), ptauFile, outputPath)
+		assert.NoError(err)
+
+		resumed, err := os.ReadFile(outputPath)
+		assert.NoError(err)
+		assert.Equal(expected, resumed, "interrupted at checkpoint %d", stop)
+
+		_, err = os.Stat(dir + "/" + checkpoint.Partial)
+		assert.ErrorIs(err, os.ErrNotExist)
+
+		_, err = os.Stat(phase1CheckpointPath(outputPath))
+		assert.ErrorIs(err, os.ErrNotExist)
+	}
+
+	// a corrupted prefix must not be resumed
+	outputPath := dir + "/corrupted.ph1"
+	phase1CheckpointHook = func(checkpoint phase1Checkpoint) error {
+		if checkpoint.Section == 2 {
+			return errInterrupted
+		}
+		return nil
+	}
+	err = WritePhase1FromPtauFile(context.Background(), ptauFile, outputPath)
+	phase1CheckpointHook = nil
+	assert.ErrorIs(err, errInterrupted)
+
+	checkpoint, err := readPhase1Checkpoint(phase1CheckpointPath(outputPath))
+	assert.NoError(err)
+
+	output, err := os.OpenFile(dir+"/"+checkpoint.Partial, os.O_WRONLY, 0)
+	assert.NoError(err)
+	_, err = output.WriteAt([]byte{0xff}, 100)
+	assert.NoError(err)
+	assert.NoError(output.Close())
+
+	err = ResumePhase1FromPtauFile(context.Background(), ptauFile, outputPath)
+	assert.Error(err)
+	_, err = os.Stat(outputPath)
+	assert.ErrorIs(err, os.ErrNotExist)
+	_, err = os.Stat(dir + "/" + checkpoint.Partial)
+	assert.ErrorIs(err, os.ErrNotExist)
+	_, err = os.Stat(phase1CheckpointPath(outputPath))
+	assert.ErrorIs(err, os.ErrNotExist)
+
+	// a checkpoint can only name a partial file next to the output
+	for _, partial := range []string{"../" + checkpoint.Partial, dir + "/" + checkpoint.Partial, "..", "."} {
+		checkpoint.Partial = partial
+		assert.NoError(writePhase1Checkpoint(phase1CheckpointPath(outputPath), checkpoint))
+		_, err = readPhase1Checkpoint(phase1CheckpointPath(outputPath))
+		assert.Error(err, partial)
+		assert.Error(ResumePhase1FromPtauFile(context.Background(), ptauFile, outputPath), partial)
+	}
+
+	// a new conversion doesn't start over an interrupted one
+	outputPath = dir + "/rerun.ph1"
+	phase1CheckpointHook = func(checkpoint phase1Checkpoint) error {
+		if checkpoint.Section == 1 {
+			return errInterrupted
+		}
+		return nil
+	}
+	err = WritePhase1FromPtauFile(context.Background(), ptauFile, outputPath)
+	phase1CheckpointHook = nil
+	assert.ErrorIs(err, errInterrupted)
+	checkpoint, err = readPhase1Checkpoint(phase1CheckpointPath(outputPath))
+	assert.NoError(err)
+	recorded, err := os.ReadFile(phase1CheckpointPath(outputPath))
+	assert.NoError(err)
+
+	err = WritePhase1FromPtauFile(context.Background(), ptauFile, outputPath)
+	assert.ErrorContains(err, phase1CheckpointPath(outputPath))
+	assert.ErrorContains(err, dir+"/"+checkpoint.Partial)
+	partials, err := filepath.Glob(dir + "/rerun.ph1.*.partial")
+	assert.NoError(err)
+	assert.Equal([]string{dir + "/" + checkpoint.Partial}, partials)
+	unchanged, err := os.ReadFile(phase1CheckpointPath(outputPath))
+	assert.NoError(err)
+	assert.Equal(recorded, unchanged)
+
+	// a checkpoint must point at a point of the layout of its power
+	for name, invalid := range map[string]phase1Checkpoint{
+		"past the last section": {Power: checkpoint.Power, Section: len(phase1Sections), Offset: checkpoint.Offset, Hash: checkpoint.Hash, Partial: checkpoint.Partial},
+		"past the last point":   {Power: checkpoint.Power, Section: 4, Points: 1, Offset: checkpoint.Offset, Hash: checkpoint.Hash, Partial: checkpoint.Partial},
+		"negative points":       {Power: checkpoint.Power, Section: 1, Points: -1, Offset: checkpoint.Offset, Hash: checkpoint.Hash, Partial: checkpoint.Partial},
+		"other offset":          {Power: checkpoint.Power, Section: 1, Offset: checkpoint.Offset + 1, Hash: checkpoint.Hash, Partial: checkpoint.Partial},
+		"power out of range":    {Power: BN254_TWO_ADICITY + 1, Section: 1, Offset: checkpoint.Offset, Hash: checkpoint.Hash, Partial: checkpoint.Partial},
+	} {
+		assert.NoError(writePhase1Checkpoint(phase1CheckpointPath(outputPath), invalid))
+		_, err = readPhase1Checkpoint(phase1CheckpointPath(outputPath))
+		assert.Error(err, name)
+	}
+	assert.NoError(os.WriteFile(phase1CheckpointPath(outputPath), recorded, 0644))
+	assert.NoError(ResumePhase1FromPtauFile(context.Background(), ptauFile, outputPath))
+	resumed, err := os.ReadFile(outputPath)
+	assert.NoError(err)
+	assert.Equal(expected, resumed)
+}
+
+func TestWritePhase1Atomic(t *testing.T) {
+	assert := require.New(t)
+
+	dir := t.TempDir()
+	outputPath := dir + "/truncated.ph1"
+
+	// a truncated ptau stream must not produce a .ph1
+	ptau, err := os.ReadFile(testPtauPath)
+	assert.NoError(err)
+	err = WritePhase1FromPtauReader(context.Background(), bytes.NewReader(ptau[:len(ptau)/2]), outputPath)
+	assert.Error(err)
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(err)
+	assert.Empty(entries)
+
+	// an invalid point can't be resumed, neither the partial file nor its
+	// checkpoint are kept
+	defer func(interval int) {
+		phase1CheckpointInterval = interval
+	}(phase1CheckpointInterval)
+	phase1CheckpointInterval = 100
+
+	ptauFile, err := InitPtau(testPtauPath)
+	assert.NoError(err)
+	pos := ptauFile.Sections[2][0].pos
+	assert.NoError(ptauFile.Close())
+	ptau[pos+300*64] ^= 0xff
+	inputDir := t.TempDir()
+	assert.NoError(os.WriteFile(inputDir+"/invalid.ptau", ptau, 0644))
+	invalidFile, err := InitPtau(inputDir + "/invalid.ptau")
+	assert.NoError(err)
+	defer invalidFile.Close()
+
+	err = WritePhase1FromPtauFile(context.Background(), invalidFile, outputPath)
+	assert.ErrorContains(err, "TauG1 point 300")
+	entries, err = os.ReadDir(dir)
+	assert.NoError(err)
+	assert.Empty(entries)
+}
+
+func TestWritePhase1Cancel(t *testing.T) {
+	assert := require.New(t)
+
+	dir := t.TempDir()
+	outputPath := dir + "/cancelled.ph1"
+
+	ptauFile, err := InitPtau(testPtauPath)
+	assert.NoError(err)
+	defer ptauFile.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// cancel as soon as the second section starts
+	var updates []ProgressUpdate
+	progress := ProgressFunc(func(update ProgressUpdate) {
+		updates = append(updates, update)
+		if update.Section == "AlphaTauG1" {
+			cancel()
+		}
+	})
+
+	err = WritePhase1FromPtauFile(ctx, ptauFile, outputPath, WithProgress(progress))
+	assert.ErrorIs(err, context.Canceled)
+	assert.Equal("TauG1", updates[0].Section)
+	assert.Equal(ptauFile.DomainSize()*2-1, updates[0].Total)
+
+	// the cancelled conversion can be resumed
+	err = ResumePhase1FromPtauFile(context.Background(), ptauFile, outputPath)
+	assert.NoError(err)
+
+	expectedPath := dir + "/expected.ph1"
+	err = WritePhase1FromPtauFile(context.Background(), ptauFile, expectedPath)
+	assert.NoError(err)
+
+	expected, err := os.ReadFile(expectedPath)
+	assert.NoError(err)
+	resumed, err := os.ReadFile(outputPath)
+	assert.NoError(err)
+	assert.Equal(expected, resumed)
+
+	// the reader goroutine must return when its consumer stops early
+	ctx, cancel = context.WithCancel(context.Background())
+	tauG1 := make(chan curve.G1Affine)
+	errc := make(chan error)
+	go func() {
+		errc <- ptauFile.ReadTauG1(ctx, tauG1)
+	}()
+	<-tauG1
+	cancel()
+	assert.ErrorIs(<-errc, context.Canceled)
+}
+
+func TestSetLogger(t *testing.T) {
+	assert := require.New(t)
+
+	var logs bytes.Buffer
+	SetLogger(slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	defer SetLogger(nil)
+
+	ptauFile, err := InitPtau(testPtauPath)
+	assert.NoError(err)
+	defer ptauFile.Close()
+
+	assert.Contains(logs.String(), `"msg":"read magic","magic":"ptau"`)
+
+	// the package is silent again once the logger is removed
+	SetLogger(nil)
+	logs.Reset()
+	err = WritePhase1FromPtauFile(context.Background(), ptauFile, t.TempDir()+"/silent.ph1")
+	assert.NoError(err)
+	assert.Empty(logs.String())
+}
+
+func TestInspect(t *testing.T) {
+	assert := require.New(t)
+
+	ptauFile, err := InitPtau(testPtauPath)
+	assert.NoError(err)
+	defer ptauFile.Close()
+
+	info, err := Inspect(testPtauPath)
+	assert.NoError(err)
+	assert.Equal("ptau", info.Type)
+	assert.Equal(ptauFile.Header.Power, info.Ptau.Power)
+	assert.Equal(ptauFile.Header.Prime.String(), info.Ptau.Prime)
+	assert.Len(info.Sections, 7)
+	for _, section := range info.Sections {
+		assert.Equal(ptauFile.Sections[section.ID][0].pos, section.Offset)
+		assert.Equal(ptauFile.Sections[section.ID][0].size, section.Size)
+	}
+
+	outputPath := t.TempDir() + "/inspect.ph1"
+	err = WritePhase1FromPtauFile(context.Background(), ptauFile, outputPath)
+	assert.NoError(err)
+
+	info, err = Inspect(outputPath)
+	assert.NoError(err)
+	assert.Equal("ph1", info.Type)
+	assert.Equal(byte(ptauFile.Header.Power), info.Phase1.Power)
+	assert.Equal(int64(0), info.Phase1.TrailingBytes)
+
+	// a truncated .ph1 is not recognised
+	err = os.Truncate(outputPath, info.Size-1)
+	assert.NoError(err)
+	_, err = Inspect(outputPath)
+	assert.Error(err)
+
+	// testdata/groth16.zkey only holds the header sections of a Groth16 zkey
+	zkey, err := ReadZkey("testdata/groth16.zkey")
+	assert.NoError(err)
+	info, err = Inspect("testdata/groth16.zkey")
+	assert.NoError(err)
+	assert.Equal("zkey", info.Type)
+	assert.Equal(GROTH_16_PROTOCOL_ID, info.Zkey.ProtocolID)
+	assert.Equal("groth16", info.Zkey.Protocol)
+	assert.Equal("bn254", info.Zkey.Curve)
+	assert.Equal(zkey.protocolHeader.R.String(), info.Zkey.R)
+	assert.Equal(zkey.protocolHeader.NVars, info.Zkey.NVars)
+	assert.Equal(zkey.protocolHeader.NPublic, info.Zkey.NPublic)
+	assert.Equal(zkey.protocolHeader.DomainSize, info.Zkey.DomainSize)
+	assert.Equal(zkey.protocolHeader.Power, info.Zkey.Power)
+	assert.Len(info.Sections, 2)
+}
+
 ///////////////////////////////////////////////////////////////////
 ///                             ZKEY                            ///
 ///////////////////////////////////////////////////////////////////
@@ -153,7 +544,9 @@ func TestDeserializePtauConvertPhase1(t *testing.T) {
 //      delta2
 
 func TestDeserializerZkey(t *testing.T) {
-	input_path := "semaphore_16.zkey"
+	// only the header sections of a Groth16 zkey, the downloaded
+	// semaphore_16.zkey reads the same way
+	input_path := "testdata/groth16.zkey"
 
 	assert := require.New(t)
 
@@ -167,20 +560,1889 @@ func TestDeserializerZkey(t *testing.T) {
 
 	// protocolID should be 1 (Groth16)
 	assert.Equal(GROTH_16_PROTOCOL_ID, zkey.ZkeyHeader.ProtocolID)
+	assert.Equal(ecc.BN254.ScalarField().String(), zkey.protocolHeader.R.String())
+	assert.Equal(uint32(100), zkey.protocolHeader.NVars)
+	assert.Equal(uint32(2), zkey.protocolHeader.NPublic)
+	assert.Equal(uint32(128), zkey.protocolHeader.DomainSize)
+	assert.Equal(uint32(7), zkey.protocolHeader.Power)
 
-	fmt.Printf("n8q is: %v \n", zkey.protocolHeader.n8q)
+	fmt.Printf("n8q is: %v \n", zkey.protocolHeader.N8q)
 
-	fmt.Printf("q is: %v \n", zkey.protocolHeader.q.String())
+	fmt.Printf("q is: %v \n", zkey.protocolHeader.Q.String())
 
-	fmt.Printf("n8r is: %v \n", zkey.protocolHeader.n8r)
+	fmt.Printf("n8r is: %v \n", zkey.protocolHeader.N8r)
 
-	fmt.Printf("r is: %v \n", zkey.protocolHeader.r.String())
+	fmt.Printf("r is: %v \n", zkey.protocolHeader.R.String())
 
-	fmt.Printf("nVars is: %v \n", zkey.protocolHeader.nVars)
+	fmt.Printf("nVars is: %v \n", zkey.protocolHeader.NVars)
 
-	fmt.Printf("nPublic is: %v \n", zkey.protocolHeader.nPublic)
+	fmt.Printf("nPublic is: %v \n", zkey.protocolHeader.NPublic)
+
+	fmt.Printf("domainSize is: %v \n", zkey.protocolHeader.DomainSize)
+
+	fmt.Printf("power is: %v \n", zkey.protocolHeader.Power)
+}
+
+// groth16HeaderBytes serializes a Groth16 zkey header (section 2) over bn254.
+func groth16HeaderBytes(nVars, nPublic, domainSize uint32) []byte {
+	var buffer bytes.Buffer
+	for _, modulus := range []*big.Int{ecc.BN254.BaseField(), ecc.BN254.ScalarField()} {
+		binary.Write(&buffer, binary.LittleEndian, uint32(32))
+		element := make([]byte, 32)
+		modulus.FillBytes(element)
+		buffer.Write(reverseSlice(element))
+	}
+	binary.Write(&buffer, binary.LittleEndian, []uint32{nVars, nPublic, domainSize})
+	return buffer.Bytes()
+}
+
+func TestReadHeaderGroth16DomainSize(t *testing.T) {
+	assert := require.New(t)
+
+	for power := uint32(0); power <= BN254_TWO_ADICITY; power++ {
+		header, err := readHeaderGroth16(bytes.NewReader(groth16HeaderBytes(10, 1, 1<<power)))
+		assert.NoError(err)
+		assert.Equal(power, header.Power)
+		assert.Equal(uint32(1)<<power, header.DomainSize)
+	}
+
+	for _, domainSize := range []uint32{0, 3, 6, 100, 1<<16 + 1, 1<<31 - 1, 1 << 29, 1 << 31} {
+		_, err := readHeaderGroth16(bytes.NewReader(groth16HeaderBytes(10, 1, domainSize)))
+		var invalid *InvalidZkeyHeader
+		assert.ErrorAs(err, &invalid, "domainSize %d", domainSize)
+	}
+}
+
+func FuzzReadHeaderGroth16(f *testing.F) {
+	f.Add(groth16HeaderBytes(100, 2, 128))
+	f.Add(groth16HeaderBytes(100, 2, 100))
+	f.Add(groth16HeaderBytes(1, 0, 1<<28))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		header, err := readHeaderGroth16(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		if header.Power > BN254_TWO_ADICITY || header.DomainSize != uint32(1)<<header.Power {
+			t.Fatalf("accepted domainSize %d with power %d", header.DomainSize, header.Power)
+		}
+	})
+}
+
+func TestCurveDetection(t *testing.T) {
+	assert := require.New(t)
+
+	field := func(modulus *big.Int) []byte {
+		n8 := fieldElementSize(modulus)
+		element := make([]byte, n8)
+		modulus.FillBytes(element)
+		return append(binary.LittleEndian.AppendUint32(nil, n8), reverseSlice(element)...)
+	}
+
+	header, err := readHeaderGroth16(bytes.NewReader(groth16HeaderBytes(10, 1, 8)))
+	assert.NoError(err)
+	assert.Equal(ecc.BN254, header.Curve())
+
+	blsHeader := append(field(ecc.BLS12_381.BaseField()), field(ecc.BLS12_381.ScalarField())...)
+	blsHeader = binary.LittleEndian.AppendUint32(blsHeader, 10)
+	blsHeader = binary.LittleEndian.AppendUint32(blsHeader, 1)
+	blsHeader = binary.LittleEndian.AppendUint32(blsHeader, 8)
+	header, err = readHeaderGroth16(bytes.NewReader(blsHeader))
+	assert.NoError(err)
+	assert.Equal(ecc.BLS12_381, header.Curve())
+
+	// a bn254 base field with a bls12-381 scalar field is no curve at all
+	header.Q = *ecc.BN254.BaseField()
+	header.N8q = 32
+	assert.Equal(ecc.UNKNOWN, header.Curve())
+
+	// a bls12-381 ptau is rejected as soon as its header is read
+	ptauHeader := append(field(ecc.BLS12_381.BaseField()), binary.LittleEndian.AppendUint32(nil, 1)...)
+	var ptau bytes.Buffer
+	ptau.WriteString(ptauMagic)
+	binary.Write(&ptau, binary.LittleEndian, []uint32{1, 1, 1})
+	binary.Write(&ptau, binary.LittleEndian, uint64(len(ptauHeader)))
+	ptau.Write(ptauHeader)
+
+	err = WritePhase1FromPtauReader(context.Background(), &ptau, t.TempDir()+"/bls.ph1")
+	var unsupported *UnsupportedCurve
+	assert.ErrorAs(err, &unsupported)
+	assert.Equal(ecc.BLS12_381, unsupported.Curve)
+	assert.EqualError(err, "bls12_381 is not supported, only bn254 is")
+
+	assert.Equal(ecc.UNKNOWN, curveFromBaseField(32, big.NewInt(7)))
+}
+
+func TestInitPtauInvalid(t *testing.T) {
+	assert := require.New(t)
+	dir := t.TempDir()
+
+	ptau, err := os.ReadFile(testPtauPath)
+	assert.NoError(err)
+	header := ptau[12+12 : 12+12+44]
+
+	openFiles := func() int {
+		entries, err := os.ReadDir("/proc/self/fd")
+		if err != nil {
+			t.Skip("open files can't be listed")
+		}
+		return len(entries)
+	}
+	before := openFiles()
+
+	// the sections of the test ptau, to be put together again
+	ptauFile, err := InitPtau(testPtauPath)
+	assert.NoError(err)
+	sectionsOf := func() map[uint32][]byte {
+		sections := make(map[uint32][]byte)
+		for id := uint32(1); id <= 7; id++ {
+			segment := ptauFile.Sections[id][0]
+			sections[id] = ptau[segment.pos : segment.pos+segment.size]
+		}
+		return sections
+	}
+	ptauFile.Close()
+	assert.Equal(ptau, binFileBytes("ptau", sectionsOf()))
+
+	noTauG2 := sectionsOf()
+	delete(noTauG2, 3)
+	shortTauG1 := sectionsOf()
+	shortTauG1[2] = shortTauG1[2][64:]
+	largePower := sectionsOf()
+	largePower[1] = binary.LittleEndian.AppendUint32(append([]byte{}, header[:36]...), PTAU_MAX_POWER+1)
+	split := binFileBytes("ptau", sectionsOf())
+	binary.LittleEndian.PutUint32(split[8:], 8)
+	split = binary.LittleEndian.AppendUint32(split, 2)
+	split = binary.LittleEndian.AppendUint64(split, 0)
+	duplicate := binFileBytes("ptau", sectionsOf())
+	binary.LittleEndian.PutUint32(duplicate[8:], 8)
+	duplicate = binary.LittleEndian.AppendUint32(duplicate, 2)
+	duplicate = binary.LittleEndian.AppendUint64(duplicate, uint64(len(sectionsOf()[2])))
+	duplicate = append(duplicate, sectionsOf()[2]...)
+	longTauG1 := sectionsOf()
+	longTauG1[2] = append(append([]byte{}, longTauG1[2]...), longTauG1[2][:64]...)
+
+	for name, data := range map[string][]byte{
+		"empty":              nil,
+		"magic":              ptau[:3],
+		"other magic":        append([]byte("zkey"), ptau[4:]...),
+		"sections":           ptau[:10],
+		"section table":      ptau[:12+6],
+		"truncated":          ptau[:len(ptau)-1],
+		"no header":          binFileBytes("ptau", map[uint32][]byte{2: ptau[:64]}),
+		"short header":       binFileBytes("ptau", map[uint32][]byte{1: header[:10]}),
+		"unknown section":    binFileBytes("ptau", map[uint32][]byte{1: header, 16: nil}),
+		"missing section":    binFileBytes("ptau", noTauG2),
+		"short section":      binFileBytes("ptau", shortTauG1),
+		"split section":      split,
+		"duplicate section":  duplicate,
+		"long section":       binFileBytes("ptau", longTauG1),
+		"power out of range": binFileBytes("ptau", largePower),
+	} {
+		path := dir + "/" + strings.ReplaceAll(name, " ", "_") + ".ptau"
+		assert.NoError(os.WriteFile(path, data, 0644))
+		_, err := InitPtau(path)
+		assert.Error(err, name)
+
+		// the stream conversion refuses them too
+		err = WritePhase1FromPtauReader(context.Background(), bytes.NewReader(data), dir+"/stream.ph1")
+		assert.Error(err, name)
+		assert.NoFileExists(dir+"/stream.ph1", name)
+	}
+
+	// the file is closed on every error
+	assert.Equal(before, openFiles())
+}
+
+type PlonkTestCircuit struct {
+	X frontend.Variable `gnark:",public"`
+	Y frontend.Variable
+}
+
+func (circuit *PlonkTestCircuit) Define(api frontend.API) error {
+	api.AssertIsEqual(api.Mul(circuit.Y, circuit.Y), circuit.X)
+	api.AssertIsEqual(api.Add(circuit.Y, 3), api.Mul(circuit.Y, 2))
+	return nil
+}
+
+// montgomeryBytes lays out field elements the way snarkjs stores them: the
+// limbs of their Montgomery form in little-endian.
+func montgomeryBytes(limbs ...[4]uint64) []byte {
+	var b []byte
+	for _, l := range limbs {
+		for _, limb := range l {
+			b = binary.LittleEndian.AppendUint64(b, limb)
+		}
+	}
+	return b
+}
+
+func g1Bytes(p curve.G1Affine) []byte {
+	return montgomeryBytes(p.X, p.Y)
+}
+
+// binFileBytes serializes sections in the iden3 binary file layout.
+func binFileBytes(magic string, sections map[uint32][]byte) []byte {
+	var buffer bytes.Buffer
+	buffer.WriteString(magic)
+	binary.Write(&buffer, binary.LittleEndian, []uint32{1, uint32(len(sections))})
+	for id := uint32(1); len(sections) > 0; id++ {
+		data, ok := sections[id]
+		if !ok {
+			continue
+		}
+		binary.Write(&buffer, binary.LittleEndian, id)
+		binary.Write(&buffer, binary.LittleEndian, uint64(len(data)))
+		buffer.Write(data)
+		delete(sections, id)
+	}
+	return buffer.Bytes()
+}
+
+// plonkZkeyBytes lays out the keys gnark derives from ccs the way snarkjs
+// writes a PLONK zkey, labelling the wires with k1 = 2 and k2 = 3 like
+// snarkjs does. It also returns the serialized gnark keys.
+func plonkZkeyBytes(t *testing.T, ccs constraint.ConstraintSystem, srs *kzg.SRS) ([]byte, []byte, []byte) {
+	assert := require.New(t)
+
+	pk, vk, err := plonk.Setup(ccs, srs)
+	assert.NoError(err)
+	var pkBytes, vkBytes bytes.Buffer
+	_, err = pk.WriteTo(&pkBytes)
+	assert.NoError(err)
+	_, err = vk.WriteTo(&vkBytes)
+	assert.NoError(err)
+
+	keys := bytes.NewReader(pkBytes.Bytes())
+	dec := curve.NewDecoder(keys)
+	var n, nPublic uint64
+	var element fr.Element
+	var digest curve.G1Affine
+	for _, v := range []interface{}{&n, &element, &element, &nPublic, &element} {
+		assert.NoError(dec.Decode(v))
+	}
+	for i := 0; i < 8; i++ {
+		assert.NoError(dec.Decode(&digest))
+	}
+	var domain, bigDomain fft.Domain
+	_, err = domain.ReadFrom(keys)
+	assert.NoError(err)
+	_, err = bigDomain.ReadFrom(keys)
+	assert.NoError(err)
+	dec = curve.NewDecoder(keys)
+	polynomials := make([][]fr.Element, 9)
+	for i := range polynomials {
+		assert.NoError(dec.Decode(&polynomials[i]))
+	}
+	permutation := make([]int64, 3*n)
+	assert.NoError(dec.Decode(&permutation))
+
+	toLagrange := func(p []fr.Element) []fr.Element {
+		p = append([]fr.Element{}, p...)
+		domain.FFT(p, fft.DIF)
+		fft.BitReverse(p)
+		return p
+	}
+	extendedDomain := fft.NewDomain(4 * n)
+	// writes the coefficients and the 4n extended evaluations of the
+	// polynomial of evaluations p, and returns its commitment
+	var sectionBuffer bytes.Buffer
+	writePolynomial := func(p []fr.Element) curve.G1Affine {
+		coefficients := append([]fr.Element{}, p...)
+		domain.FFTInverse(coefficients, fft.DIF)
+		fft.BitReverse(coefficients)
+		extended := make([]fr.Element, 4*n)
+		copy(extended, coefficients)
+		extendedDomain.FFT(extended, fft.DIF)
+		fft.BitReverse(extended)
+		for _, e := range append(coefficients, extended...) {
+			sectionBuffer.Write(montgomeryBytes(e))
+		}
+		commitment, err := kzg.Commit(coefficients, srs)
+		assert.NoError(err)
+		return commitment
+	}
+	section := func() []byte {
+		defer sectionBuffer.Reset()
+		return append([]byte{}, sectionBuffer.Bytes()...)
+	}
+
+	ql := toLagrange(polynomials[0])
+	for i := uint64(0); i < nPublic; i++ {
+		ql[i].Neg(&ql[i])
+	}
+	selectors := [][]fr.Element{toLagrange(polynomials[2]), ql, toLagrange(polynomials[1]), toLagrange(polynomials[3]), polynomials[5]}
+
+	sections := map[uint32][]byte{1: binary.LittleEndian.AppendUint32(nil, PLONK_PROTOCOL_ID), 3: {}}
+	var commitments []curve.G1Affine
+	for i, p := range selectors {
+		commitments = append(commitments, writePolynomial(p))
+		sections[uint32(7+i)] = section()
+	}
+
+	var shifts [3]fr.Element
+	shifts[0].SetOne()
+	shifts[1].SetUint64(2)
+	shifts[2].SetUint64(3)
+	for k := 0; k < 3; k++ {
+		sigma := make([]fr.Element, n)
+		for i := range sigma {
+			wire := permutation[uint64(k)*n+uint64(i)]
+			var w fr.Element
+			w.Exp(domain.Generator, big.NewInt(wire%int64(n)))
+			sigma[i].Mul(&shifts[wire/int64(n)], &w)
+		}
+		commitments = append(commitments, writePolynomial(sigma))
+	}
+	sections[12] = section()
+
+	for i := uint64(0); i < nPublic; i++ {
+		lagrange := make([]fr.Element, n)
+		lagrange[i].SetOne()
+		writePolynomial(lagrange)
+	}
+	sections[13] = section()
+
+	for _, p := range srs.G1[:n+6] {
+		sectionBuffer.Write(g1Bytes(p))
+	}
+	sections[14] = section()
+
+	nConstraints := uint32(ccs.GetNbConstraints() + int(nPublic))
+	for id := uint32(4); id <= 6; id++ {
+		sections[id] = make([]byte, 4*nConstraints)
+	}
+
+	header := groth16HeaderBytes(uint32(ccs.GetNbInternalVariables()), uint32(nPublic), uint32(n))
+	header = binary.LittleEndian.AppendUint32(header, 0)
+	header = binary.LittleEndian.AppendUint32(header, nConstraints)
+	header = append(header, montgomeryBytes(shifts[1], shifts[2])...)
+	for _, c := range commitments {
+		header = append(header, g1Bytes(c)...)
+	}
+	header = append(header, montgomeryBytes(srs.G2[1].X.A0, srs.G2[1].X.A1, srs.G2[1].Y.A0, srs.G2[1].Y.A1)...)
+	sections[2] = header
+
+	return binFileBytes("zkey", sections), pkBytes.Bytes(), vkBytes.Bytes()
+}
+
+// checkPlonkCommitments checks the polynomials read from a PLONK zkey against
+// the commitments of its header, which snarkjs computes with the PTau section.
+func checkPlonkCommitments(assert *require.Assertions, zkey *PlonkZkey) {
+	srs := &kzg.SRS{G1: zkey.PTau}
+	for name, polynomial := range map[string]struct {
+		polynomial *PlonkPolynomial
+		commitment curve.G1Affine
+	}{
+		"Qm": {&zkey.Qm, zkey.Header.Qm},
+		"Ql": {&zkey.Ql, zkey.Header.Ql},
+		"Qr": {&zkey.Qr, zkey.Header.Qr},
+		"Qo": {&zkey.Qo, zkey.Header.Qo},
+		"Qc": {&zkey.Qc, zkey.Header.Qc},
+		"S1": {&zkey.Sigma[0], zkey.Header.S1},
+		"S2": {&zkey.Sigma[1], zkey.Header.S2},
+		"S3": {&zkey.Sigma[2], zkey.Header.S3},
+	} {
+		commitment, err := kzg.Commit(polynomial.polynomial.Coefficients, srs)
+		assert.NoError(err, name)
+		assert.True(commitment.Equal(&polynomial.commitment), name)
+	}
+}
+
+// TestPlonkZkeySnarkjs reads testdata/plonk.zkey, written by snarkjs rather
+// than by plonkZkeyBytes, so that a misreading of the layout can't show up on
+// both sides. It is set up with snarkjs from any circuit and ptau:
+//
+//	circom circuit.circom --r1cs
+//	snarkjs plonk setup circuit.r1cs pot.ptau plonk.zkey
+func TestPlonkZkeySnarkjs(t *testing.T) {
+	assert := require.New(t)
+
+	const zkeyPath = "testdata/plonk.zkey"
+	if _, err := os.Stat(zkeyPath); errors.Is(err, os.ErrNotExist) {
+		t.Skip(zkeyPath + " is missing, it has to be written by snarkjs plonk setup")
+	}
+
+	zkey, err := ReadPlonkZkey(zkeyPath)
+	assert.NoError(err)
+	checkPlonkCommitments(assert, zkey)
+
+	_, _, srs, err := zkey.GnarkKeys()
+	assert.NoError(err)
+	assert.Equal(zkey.Header.X2, srs.G2[1])
+}
+
+func TestPlonkZkeyGnarkKeys(t *testing.T) {
+	assert := require.New(t)
+
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), scs.NewBuilder, &PlonkTestCircuit{})
+	assert.NoError(err)
+	srs, err := kzg.NewSRS(64, big.NewInt(42))
+	assert.NoError(err)
+
+	zkeyBytes, pkBytes, vkBytes := plonkZkeyBytes(t, ccs, srs)
+	zkeyPath := t.TempDir() + "/plonk.zkey"
+	assert.NoError(os.WriteFile(zkeyPath, zkeyBytes, 0644))
+
+	zkey, err := ReadZkey(zkeyPath)
+	assert.NoError(err)
+	header, ok := zkey.PlonkHeader()
+	assert.True(ok)
+	assert.Equal(ecc.BN254, zkey.Curve())
+	assert.Equal(uint32(1), header.NPublic)
+	_, ok = zkey.Groth16Header()
+	assert.False(ok)
+
+	info, err := Inspect(zkeyPath)
+	assert.NoError(err)
+	assert.Equal("plonk", info.Zkey.Protocol)
+	assert.Equal("bn254", info.Zkey.Curve)
+	assert.Equal(header.NConstraints, info.Zkey.NConstraints)
+
+	plonkZkey, err := ReadPlonkZkey(zkeyPath)
+	assert.NoError(err)
+	checkPlonkCommitments(assert, plonkZkey)
+	pk, vk, convertedSRS, err := plonkZkey.GnarkKeys()
+	assert.NoError(err)
+	assert.Equal(srs.G2, convertedSRS.G2)
+
+	// the keys come out exactly as gnark set them up
+	var convertedPk, convertedVk bytes.Buffer
+	_, err = pk.WriteTo(&convertedPk)
+	assert.NoError(err)
+	_, err = vk.WriteTo(&convertedVk)
+	assert.NoError(err)
+	assert.Equal(pkBytes, convertedPk.Bytes())
+	assert.Equal(vkBytes, convertedVk.Bytes())
+
+	witness, err := frontend.NewWitness(&PlonkTestCircuit{X: 9, Y: 3}, ecc.BN254.ScalarField())
+	assert.NoError(err)
+	proof, err := plonk.Prove(ccs, pk, witness)
+	assert.NoError(err)
+	publicWitness, err := witness.Public()
+	assert.NoError(err)
+	assert.NoError(plonk.Verify(proof, vk, publicWitness))
+
+	// a corrupted sigma no longer labels wires
+	plonkZkey.Sigma[1].Evaluations[0].SetUint64(7)
+	_, _, _, err = plonkZkey.GnarkKeys()
+	assert.ErrorContains(err, "sigma 2")
+
+	unknown := binFileBytes("zkey", map[uint32][]byte{1: binary.LittleEndian.AppendUint32(nil, 99)})
+	assert.NoError(os.WriteFile(zkeyPath, unknown, 0644))
+	_, err = ReadZkey(zkeyPath)
+	var unsupported *UnsupportedProtocol
+	assert.ErrorAs(err, &unsupported)
+	assert.Equal(uint32(99), unsupported.ProtocolID)
+}
+
+func TestPtauToKZGSRS(t *testing.T) {
+	assert := require.New(t)
 
-	fmt.Printf("domainSize is: %v \n", zkey.protocolHeader.domainSize)
+	ptauFile, err := InitPtau(testPtauPath)
+	assert.NoError(err)
+	defer ptauFile.Close()
+
+	srs, err := PtauToKZGSRS(ptauFile, 2*ptauFile.DomainSize()-1)
+	assert.NoError(err)
+	assert.Len(srs.G1, 511)
+
+	// e([τ]₁, [1]₂) = e([1]₁, [τ]₂)
+	var negTau curve.G1Affine
+	negTau.Neg(&srs.G1[1])
+	ok, err := curve.PairingCheck([]curve.G1Affine{negTau, srs.G1[0]}, []curve.G2Affine{srs.G2[0], srs.G2[1]})
+	assert.NoError(err)
+	assert.True(ok)
+
+	outputPath := t.TempDir() + "/08.srs"
+	assert.NoError(WriteKZGSRS(srs, outputPath))
+	file, err := os.Open(outputPath)
+	assert.NoError(err)
+	defer file.Close()
+	var read kzg.SRS
+	_, err = read.ReadFrom(file)
+	assert.NoError(err)
+	assert.Equal(srs.G1, read.G1)
+	assert.Equal(srs.G2, read.G2)
+
+	lagrangeSRS, err := PtauToLagrangeKZGSRS(ptauFile, 64)
+	assert.NoError(err)
+	assert.Equal(srs.G2, lagrangeSRS.G2)
+
+	// committing to the evaluations of a polynomial with the Lagrange SRS
+	// commits to its coefficients with the canonical one
+	domain := fft.NewDomain(64)
+	coefficients := make([]fr.Element, 64)
+	for i := range coefficients {
+		coefficients[i].SetRandom()
+	}
+	evaluations := append([]fr.Element{}, coefficients...)
+	domain.FFT(evaluations, fft.DIF)
+	fft.BitReverse(evaluations)
+	expected, err := kzg.Commit(coefficients, srs)
+	assert.NoError(err)
+	actual, err := lagrangeSRS.Commit(evaluations)
+	assert.NoError(err)
+	assert.True(expected.Equal(&actual))
+
+	for _, size := range []int{0, 1, 512} {
+		_, err = PtauToKZGSRS(ptauFile, size)
+		assert.Error(err, "size %d", size)
+	}
+	for _, size := range []int{1, 48, 512} {
+		_, err = PtauToLagrangeKZGSRS(ptauFile, size)
+		assert.Error(err, "size %d", size)
+	}
+}
+
+func TestWriteHalo2Params(t *testing.T) {
+	assert := require.New(t)
+
+	ptauFile, err := InitPtau(testPtauPath)
+	assert.NoError(err)
+	defer ptauFile.Close()
+
+	const k = 4
+	srs, err := PtauToKZGSRS(ptauFile, 1<<k)
+	assert.NoError(err)
+
+	rawPath := t.TempDir() + "/raw.params"
+	assert.NoError(WriteHalo2Params(ptauFile, k, HALO2_SERDE_RAW_BYTES, rawPath))
+	raw, err := os.ReadFile(rawPath)
+	assert.NoError(err)
+	assert.Len(raw, 4+2*(1<<k)*64+2*128)
+	assert.Equal(uint32(k), binary.LittleEndian.Uint32(raw))
+
+	reader := bytes.NewReader(raw[4:])
+	buffer := make([]byte, BN254_FIELD_ELEMENT_SIZE)
+	for i := 0; i < 1<<k; i++ {
+		g, err := readG1Affine(reader, buffer)
+		assert.NoError(err)
+		assert.True(g.Equal(&srs.G1[i]))
+	}
+	lagrange := make([]curve.G1Affine, 1<<k)
+	for i := range lagrange {
+		lagrange[i], err = readG1Affine(reader, buffer)
+		assert.NoError(err)
+	}
+	for i := range srs.G2 {
+		g2, err := readG2Affine(reader, buffer)
+		assert.NoError(err)
+		assert.True(g2.Equal(&srs.G2[i]))
+	}
+
+	// ∑ Lⱼ = 1 and ∑ ωʲ Lⱼ = X, with ω the 2^k-th root of unity of halo2: 7^((r-1)/2^k)
+	r := ecc.BN254.ScalarField()
+	omega := new(big.Int).Exp(big.NewInt(7), new(big.Int).Rsh(new(big.Int).Sub(r, big.NewInt(1)), k), r)
+	var one, x curve.G1Jac
+	power := big.NewInt(1)
+	for j := range lagrange {
+		var term curve.G1Jac
+		term.FromAffine(&lagrange[j])
+		one.AddAssign(&term)
+		term.ScalarMultiplication(&term, power)
+		x.AddAssign(&term)
+		power.Mul(power, omega).Mod(power, r)
+	}
+	var oneAffine, xAffine curve.G1Affine
+	oneAffine.FromJacobian(&one)
+	xAffine.FromJacobian(&x)
+	assert.True(oneAffine.Equal(&srs.G1[0]))
+	assert.True(xAffine.Equal(&srs.G1[1]))
+
+	processedPath := t.TempDir() + "/processed.params"
+	assert.NoError(WriteHalo2Params(ptauFile, k, HALO2_SERDE_PROCESSED, processedPath))
+	processed, err := os.ReadFile(processedPath)
+	assert.NoError(err)
+	assert.Len(processed, 4+2*(1<<k)*32+2*64)
+
+	// the x coordinate of tau*G1, with the parity of y on top
+	compressed := processed[4+32 : 4+64]
+	x1 := srs.G1[1].X.Bytes()
+	y1 := srs.G1[1].Y.Bytes()
+	assert.Equal(y1[31]&1, compressed[31]>>7)
+	compressed[31] &= 0x7f
+	assert.Equal(x1[:], reverseSlice(compressed))
+
+	assert.Error(WriteHalo2Params(ptauFile, 9, HALO2_SERDE_PROCESSED, processedPath))
+
+	// the sign of a G2 point is the parity of y.c0, or of y.c1 when y.c0 is
+	// zero, so that y and -y are always told apart. Such points are hard to
+	// find on the curve, the encoding doesn't need them to be on it
+	for _, y1 := range []uint64{1, 2} {
+		var p, neg curve.G2Affine
+		p.X = srs.G2[1].X
+		p.Y.A1.SetUint64(y1)
+		neg.Neg(&p)
+		b, negB := halo2G2Bytes(&p, HALO2_SERDE_PROCESSED), halo2G2Bytes(&neg, HALO2_SERDE_PROCESSED)
+		assert.Equal(byte(parity(p.Y.A1)), b[63]>>7, "y.c1 = %d", y1)
+		assert.Equal(byte(parity(neg.Y.A1)), negB[63]>>7, "y.c1 = -%d", y1)
+		assert.NotEqual(b, negB)
+	}
+	g2 := halo2G2Bytes(&srs.G2[1], HALO2_SERDE_PROCESSED)
+	assert.Equal(byte(parity(srs.G2[1].Y.A0)), g2[63]>>7)
+}
+
+// bellmanBytes lays out phase1 as a bellman challenge (uncompressed) or
+// response (compressed and followed by a public key) starting with hash.
+func bellmanBytes(phase1 Phase1, hash []byte, compressed bool) []byte {
+	b := append([]byte{}, hash...)
+	// gnark flags a largest y with 0b11 in the top bits, bellman with the top bit alone
+	appendCompressed := func(c []byte) {
+		largest := c[0]&0xc0 == 0xc0
+		c[0] &= 0x3f
+		if largest {
+			c[0] |= 0x80
+		}
+		b = append(b, c...)
+	}
+	g1 := func(p *curve.G1Affine, compressed bool) {
+		if compressed {
+			c := p.Bytes()
+			appendCompressed(c[:])
+		} else {
+			raw := p.RawBytes()
+			b = append(b, raw[:]...)
+		}
+	}
+	g2 := func(p *curve.G2Affine, compressed bool) {
+		if compressed {
+			c := p.Bytes()
+			appendCompressed(c[:])
+		} else {
+			raw := p.RawBytes()
+			b = append(b, raw[:]...)
+		}
+	}
+
+	for i := range phase1.tauG1 {
+		g1(&phase1.tauG1[i], compressed)
+	}
+	for i := range phase1.tauG2 {
+		g2(&phase1.tauG2[i], compressed)
+	}
+	for i := range phase1.alphaTauG1 {
+		g1(&phase1.alphaTauG1[i], compressed)
+	}
+	for i := range phase1.betaTauG1 {
+		g1(&phase1.betaTauG1[i], compressed)
+	}
+	g2(&phase1.betaG2, compressed)
+
+	if compressed {
+		// any points will do for the public key
+		for i := 0; i < 6; i++ {
+			g1(&phase1.tauG1[i], false)
+		}
+		for i := 0; i < 3; i++ {
+			g2(&phase1.tauG2[i], false)
+		}
+	}
+
+	return b
+}
+
+func TestWritePhase1FromBellmanFile(t *testing.T) {
+	assert := require.New(t)
+	dir := t.TempDir()
+
+	ptau, err := ReadPtau(testPtauPath)
+	assert.NoError(err)
+	phase1, err := ConvertPtauToPhase1(ptau)
+	assert.NoError(err)
+
+	ptauFile, err := InitPtau(testPtauPath)
+	assert.NoError(err)
+	defer ptauFile.Close()
+	assert.NoError(WritePhase1FromPtauFile(context.Background(), ptauFile, dir+"/ptau.ph1"))
+	expected, err := os.ReadFile(dir + "/ptau.ph1")
+	assert.NoError(err)
+
+	initialHash := blake2b.Sum512(nil)
+	challenge := bellmanBytes(phase1, initialHash[:], false)
+	challengeHash := blake2b.Sum512(challenge)
+	response := bellmanBytes(phase1, challengeHash[:], true)
+	assert.NoError(os.WriteFile(dir+"/challenge", challenge, 0644))
+	assert.NoError(os.WriteFile(dir+"/response", response, 0644))
+
+	challengeFile, err := InitBellman(dir + "/challenge")
+	assert.NoError(err)
+	defer challengeFile.Close()
+	assert.Equal(uint32(8), challengeFile.Power)
+	assert.False(challengeFile.Compressed)
+	assert.False(challengeFile.HasPublicKey)
+	assert.True(challengeFile.IsInitialChallenge())
+
+	responseFile, err := InitBellman(dir + "/response")
+	assert.NoError(err)
+	defer responseFile.Close()
+	assert.Equal(uint32(8), responseFile.Power)
+	assert.True(responseFile.Compressed)
+	assert.True(responseFile.HasPublicKey)
+	assert.False(responseFile.IsInitialChallenge())
+
+	assert.NoError(responseFile.CheckPreviousHash(challengeFile))
+	var invalidHash *InvalidPreviousHash
+	assert.ErrorAs(challengeFile.CheckPreviousHash(responseFile), &invalidHash)
+
+	key, err := responseFile.ReadPublicKey()
+	assert.NoError(err)
+	assert.True(key.BetaG1SX.Equal(&phase1.tauG1[5]))
+	assert.True(key.BetaG2SPX.Equal(&phase1.tauG2[2]))
+
+	betaG2, err := responseFile.ReadBetaG2()
+	assert.NoError(err)
+	assert.True(betaG2.Equal(&phase1.betaG2))
+
+	// bellman files don't record their contributions
+	binary.BigEndian.PutUint16(expected[1:], 0)
+	for name, bellmanFile := range map[string]*BellmanFile{"challenge": challengeFile, "response": responseFile} {
+		output := dir + "/" + name + ".ph1"
+		assert.NoError(WritePhase1FromBellmanFile(context.Background(), bellmanFile, output), name)
+		converted, err := os.ReadFile(output)
+		assert.NoError(err)
+		assert.Equal(expected, converted, name)
+	}
+
+	_, err = InitBellman(dir + "/ptau.ph1")
+	assert.Error(err)
+}
+
+// writeIgnitionTranscripts splits the powers of tau of phase1, generators
+// aside, into numTranscripts Ignition transcripts in dir.
+func writeIgnitionTranscripts(t *testing.T, dir string, phase1 Phase1, numTranscripts int) {
+	element := func(b []byte, e fp.Element) []byte {
+		for _, limb := range e {
+			b = binary.BigEndian.AppendUint64(b, limb)
+		}
+		return b
+	}
+
+	powers := phase1.tauG1[1:]
+	for i := 0; i < numTranscripts; i++ {
+		start := i * len(powers) / numTranscripts
+		end := (i + 1) * len(powers) / numTranscripts
+		manifest := IgnitionManifest{
+			TranscriptNumber: uint32(i),
+			TotalTranscripts: uint32(numTranscripts),
+			TotalG1Points:    uint32(len(powers)),
+			TotalG2Points:    2,
+			NumG1Points:      uint32(end - start),
+			StartFrom:        uint32(start),
+		}
+		if i == 0 {
+			manifest.NumG2Points = 2
+		}
+
+		var b bytes.Buffer
+		require.NoError(t, binary.Write(&b, binary.BigEndian, manifest))
+		transcript := b.Bytes()
+		for _, p := range powers[start:end] {
+			transcript = element(element(transcript, p.X), p.Y)
+		}
+		if i == 0 {
+			for _, p := range phase1.tauG2[1:3] {
+				transcript = element(element(element(element(transcript, p.X.A0), p.X.A1), p.Y.A0), p.Y.A1)
+			}
+		}
+		checksum := blake2b.Sum512(transcript)
+		transcript = append(transcript, checksum[:]...)
+		require.NoError(t, os.WriteFile(fmt.Sprintf("%s/transcript%02d.dat", dir, i), transcript, 0644))
+	}
+}
+
+func TestIgnitionToKZGSRS(t *testing.T) {
+	assert := require.New(t)
+	dir := t.TempDir()
+
+	ptau, err := ReadPtau(testPtauPath)
+	assert.NoError(err)
+	phase1, err := ConvertPtauToPhase1(ptau)
+	assert.NoError(err)
+	writeIgnitionTranscripts(t, dir, phase1, 3)
+
+	ptauFile, err := InitPtau(testPtauPath)
+	assert.NoError(err)
+	defer ptauFile.Close()
+
+	transcripts, err := InitIgnition(dir)
+	assert.NoError(err)
+	assert.NoError(transcripts.VerifyChecksums())
+	assert.Equal(len(phase1.tauG1), transcripts.NumG1Powers())
+
+	for _, size := range []int{2, 100, transcripts.NumG1Powers()} {
+		expected, err := PtauToKZGSRS(ptauFile, size)
+		assert.NoError(err)
+		srs, err := IgnitionToKZGSRS(transcripts, size)
+		assert.NoError(err)
+		assert.Equal(expected, srs, "size %d", size)
+	}
+
+	expected, err := PtauToLagrangeKZGSRS(ptauFile, 64)
+	assert.NoError(err)
+	srs, err := IgnitionToLagrangeKZGSRS(transcripts, 64)
+	assert.NoError(err)
+	assert.Equal(expected, srs)
+
+	_, err = IgnitionToKZGSRS(transcripts, transcripts.NumG1Powers()+1)
+	assert.Error(err)
+
+	// a flipped bit is caught by the checksum
+	transcript, err := os.ReadFile(dir + "/transcript01.dat")
+	assert.NoError(err)
+	transcript[IGNITION_MANIFEST_SIZE+10] ^= 1
+	assert.NoError(os.WriteFile(dir+"/transcript01.dat", transcript, 0644))
+	assert.Error(transcripts.VerifyChecksums())
+
+	// and a missing transcript by the manifests
+	assert.NoError(os.Remove(dir + "/transcript02.dat"))
+	_, err = InitIgnition(dir)
+	var invalidManifest *InvalidIgnitionManifest
+	assert.ErrorAs(err, &invalidManifest)
+}
+
+// contributionBytes lays out c the way snarkjs writes it in section 7.
+func contributionBytes(c PtauContribution) []byte {
+	g2Bytes := func(p curve.G2Affine) []byte {
+		return montgomeryBytes(p.X.A0, p.X.A1, p.Y.A0, p.Y.A1)
+	}
+
+	var b []byte
+	b = append(b, g1Bytes(c.TauG1)...)
+	b = append(b, g2Bytes(c.TauG2)...)
+	b = append(b, g1Bytes(c.AlphaG1)...)
+	b = append(b, g1Bytes(c.BetaG1)...)
+	b = append(b, g2Bytes(c.BetaG2)...)
+	for _, p := range []curve.G1Affine{c.Key.TauG1S, c.Key.TauG1SX, c.Key.AlphaG1S, c.Key.AlphaG1SX, c.Key.BetaG1S, c.Key.BetaG1SX} {
+		b = append(b, g1Bytes(p)...)
+	}
+	for _, p := range []curve.G2Affine{c.Key.TauG2SPX, c.Key.AlphaG2SPX, c.Key.BetaG2SPX} {
+		b = append(b, g2Bytes(p)...)
+	}
+	b = append(b, c.PartialHash[:]...)
+	b = append(b, c.NextChallenge[:]...)
+	b = binary.LittleEndian.AppendUint32(b, c.Type)
+	return binary.LittleEndian.AppendUint32(b, 0)
+}
+
+// assertBellmanMatchesPtau checks that every section of bellmanFile reads back
+// as the one of ptauFile.
+func assertBellmanMatchesPtau(t *testing.T, ptauFile *PtauFile, bellmanFile *BellmanFile) {
+	assert := require.New(t)
+	assert.Equal(ptauFile.Header.Power, bellmanFile.Power)
+
+	g1Sections := []struct {
+		name     string
+		expected func(context.Context, chan curve.G1Affine) error
+		actual   func(context.Context, chan curve.G1Affine) error
+	}{
+		{"tauG1", ptauFile.ReadTauG1, bellmanFile.ReadTauG1},
+		{"alphaTauG1", ptauFile.ReadAlphaTauG1, bellmanFile.ReadAlphaTauG1},
+		{"betaTauG1", ptauFile.ReadBetaTauG1, bellmanFile.ReadBetaTauG1},
+	}
+	for _, section := range g1Sections {
+		expected, actual := make(chan curve.G1Affine, 1024), make(chan curve.G1Affine, 1024)
+		assert.NoError(section.expected(context.Background(), expected))
+		assert.NoError(section.actual(context.Background(), actual))
+		assert.Equal(len(expected), len(actual), section.name)
+		for p := range expected {
+			q := <-actual
+			assert.True(p.Equal(&q), section.name)
+		}
+	}
+
+	expected, actual := make(chan curve.G2Affine, 1024), make(chan curve.G2Affine, 1024)
+	assert.NoError(ptauFile.ReadTauG2(context.Background(), expected))
+	assert.NoError(bellmanFile.ReadTauG2(context.Background(), actual))
+	assert.Equal(len(expected), len(actual), "tauG2")
+	for p := range expected {
+		q := <-actual
+		assert.True(p.Equal(&q), "tauG2")
+	}
+
+	expectedBetaG2, err := ptauFile.ReadBetaG2()
+	assert.NoError(err)
+	actualBetaG2, err := bellmanFile.ReadBetaG2()
+	assert.NoError(err)
+	assert.True(expectedBetaG2.Equal(&actualBetaG2), "betaG2")
+}
+
+func TestWriteBellmanFromPtauFile(t *testing.T) {
+	assert := require.New(t)
+	dir := t.TempDir()
+
+	ptauFile, err := InitPtau(testPtauPath)
+	assert.NoError(err)
+	defer ptauFile.Close()
+
+	ptau, err := os.ReadFile(testPtauPath)
+	assert.NoError(err)
+	ptauWithContributions := func(contributions []PtauContribution) []byte {
+		sections := make(map[uint32][]byte)
+		for id := uint32(1); id <= 6; id++ {
+			segment := ptauFile.Sections[id][0]
+			sections[id] = ptau[segment.pos : segment.pos+segment.size]
+		}
+		sections[7] = binary.LittleEndian.AppendUint32(nil, uint32(len(contributions)))
+		for _, c := range contributions {
+			sections[7] = append(sections[7], contributionBytes(c)...)
+		}
+		return binFileBytes("ptau", sections)
+	}
+
+	// without contributions, the ptau is written as a challenge
+	assert.NoError(os.WriteFile(dir+"/new.ptau", ptauWithContributions(nil), 0644))
+	newFile, err := InitPtau(dir + "/new.ptau")
+	assert.NoError(err)
+	defer newFile.Close()
+
+	hash, err := WriteBellmanFromPtauFile(context.Background(), newFile, dir+"/challenge")
+	assert.NoError(err)
+
+	challengeFile, err := InitBellman(dir + "/challenge")
+	assert.NoError(err)
+	defer challengeFile.Close()
+	assert.False(challengeFile.Compressed)
+	assert.False(challengeFile.HasPublicKey)
+	assert.Equal(blake2b.Sum512(nil), challengeFile.PreviousHash)
+	actualHash, err := challengeFile.Hash()
+	assert.NoError(err)
+	assert.Equal(hash, actualHash)
+	assertBellmanMatchesPtau(t, newFile, challengeFile)
+
+	// with one contribution, as its response to the initial challenge
+	hash, err = WriteBellmanFromPtauFile(context.Background(), ptauFile, dir+"/first_response")
+	assert.NoError(err)
+
+	firstResponseFile, err := InitBellman(dir + "/first_response")
+	assert.NoError(err)
+	defer firstResponseFile.Close()
+	assert.True(firstResponseFile.HasPublicKey)
+	assert.Equal(initialBellmanChallengeHash(ptauFile.Header.Power), firstResponseFile.PreviousHash)
+	actualHash, err = firstResponseFile.Hash()
+	assert.NoError(err)
+	assert.Equal(hash, actualHash)
+	assertBellmanMatchesPtau(t, ptauFile, firstResponseFile)
+
+	// with more, as the response of the last one
+	_, _, g1, g2 := curve.Generators()
+	contributions := []PtauContribution{
+		{TauG1: g1, TauG2: g2, AlphaG1: g1, BetaG1: g1, BetaG2: g2, Key: PtauPublicKey{TauG1S: g1, TauG1SX: g1, AlphaG1S: g1, AlphaG1SX: g1, BetaG1S: g1, BetaG1SX: g1, TauG2SPX: g2, AlphaG2SPX: g2, BetaG2SPX: g2}},
+	}
+	contributions = append(contributions, contributions[0])
+	contributions[0].NextChallenge[0] = 1
+	contributions[1].NextChallenge[0] = 2
+	contributions[1].Key.BetaG1SX.Add(&g1, &g1)
+	assert.NoError(os.WriteFile(dir+"/contributed.ptau", ptauWithContributions(contributions), 0644))
+
+	contributedFile, err := InitPtau(dir + "/contributed.ptau")
+	assert.NoError(err)
+	defer contributedFile.Close()
+
+	hash, err = WriteBellmanFromPtauFile(context.Background(), contributedFile, dir+"/response")
+	assert.NoError(err)
+
+	responseFile, err := InitBellman(dir + "/response")
+	assert.NoError(err)
+	defer responseFile.Close()
+	assert.False(responseFile.Compressed)
+	assert.True(responseFile.HasPublicKey)
+	assert.Equal(contributions[0].NextChallenge, responseFile.PreviousHash)
+	actualHash, err = responseFile.Hash()
+	assert.NoError(err)
+	assert.Equal(hash, actualHash)
+	key, err := responseFile.ReadPublicKey()
+	assert.NoError(err)
+	assert.Equal(contributions[1].Key, key)
+	assertBellmanMatchesPtau(t, contributedFile, responseFile)
+}
+
+func TestInitialBellmanChallengeHash(t *testing.T) {
+	_, _, g1, g2 := curve.Generators()
+	const power = 3
+	phase1 := Phase1{betaG2: g2}
+	for i := 0; i < 2<<power-1; i++ {
+		phase1.tauG1 = append(phase1.tauG1, g1)
+	}
+	for i := 0; i < 1<<power; i++ {
+		phase1.tauG2 = append(phase1.tauG2, g2)
+		phase1.alphaTauG1 = append(phase1.alphaTauG1, g1)
+		phase1.betaTauG1 = append(phase1.betaTauG1, g1)
+	}
+
+	blank := blake2b.Sum512(nil)
+	require.Equal(t, blake2b.Sum512(bellmanBytes(phase1, blank[:], false)), initialBellmanChallengeHash(power))
+}
+
+// assertPtauContribution checks that the points of the ptau at path are the
+// generators times the powers of secrets, and that its last contribution
+// proves knowledge of secrets for challengeHash and hashes like snarkjs does.
+func assertPtauContribution(t *testing.T, path string, secrets PtauSecrets, challengeHash [BELLMAN_HASH_SIZE]byte) PtauContribution {
+	assert := require.New(t)
+
+	ptau, err := ReadPtau(path)
+	assert.NoError(err)
+	phase1, err := ConvertPtauToPhase1(ptau)
+	assert.NoError(err)
+
+	_, _, g1, g2 := curve.Generators()
+	var tau, alpha, beta, scalar big.Int
+	secrets.Tau.BigInt(&tau)
+	secrets.Alpha.BigInt(&alpha)
+	secrets.Beta.BigInt(&beta)
+	r := fr.Modulus()
+	for i, p := range phase1.tauG1 {
+		var expected curve.G1Affine
+		expected.ScalarMultiplication(&g1, scalar.Exp(&tau, big.NewInt(int64(i)), r))
+		assert.True(expected.Equal(&p), "tauG1[%d]", i)
+	}
+	for i := range phase1.tauG2 {
+		var expected curve.G2Affine
+		var expectedAlpha, expectedBeta curve.G1Affine
+		scalar.Exp(&tau, big.NewInt(int64(i)), r)
+		expected.ScalarMultiplication(&g2, &scalar)
+		assert.True(expected.Equal(&phase1.tauG2[i]), "tauG2[%d]", i)
+		expectedAlpha.ScalarMultiplication(&g1, new(big.Int).Mul(&scalar, &alpha))
+		assert.True(expectedAlpha.Equal(&phase1.alphaTauG1[i]), "alphaTauG1[%d]", i)
+		expectedBeta.ScalarMultiplication(&g1, new(big.Int).Mul(&scalar, &beta))
+		assert.True(expectedBeta.Equal(&phase1.betaTauG1[i]), "betaTauG1[%d]", i)
+	}
+	var expectedBetaG2 curve.G2Affine
+	expectedBetaG2.ScalarMultiplication(&g2, &beta)
+	assert.True(expectedBetaG2.Equal(&phase1.betaG2))
+
+	ptauFile, err := InitPtau(path)
+	assert.NoError(err)
+	defer ptauFile.Close()
+	contributions, err := ptauFile.ReadContributions()
+	assert.NoError(err)
+	c := contributions[len(contributions)-1]
+	assert.True(c.TauG1.Equal(&phase1.tauG1[1]))
+	assert.True(c.TauG2.Equal(&phase1.tauG2[1]))
+	assert.True(c.AlphaG1.Equal(&phase1.alphaTauG1[0]))
+	assert.True(c.BetaG1.Equal(&phase1.betaTauG1[0]))
+	assert.True(c.BetaG2.Equal(&phase1.betaG2))
+
+	proofs := []struct {
+		g1S, g1SX curve.G1Affine
+		g2SPX     curve.G2Affine
+	}{
+		{c.Key.TauG1S, c.Key.TauG1SX, c.Key.TauG2SPX},
+		{c.Key.AlphaG1S, c.Key.AlphaG1SX, c.Key.AlphaG2SPX},
+		{c.Key.BetaG1S, c.Key.BetaG1SX, c.Key.BetaG2SPX},
+	}
+	for i, proof := range proofs {
+		g2SP := g2SP(byte(i), challengeHash, &proof.g1S, &proof.g1SX)
+		assert.True(g2SP.IsInSubGroup())
+		var negG1SX curve.G1Affine
+		negG1SX.Neg(&proof.g1SX)
+		ok, err := curve.PairingCheck([]curve.G1Affine{proof.g1S, negG1SX}, []curve.G2Affine{proof.g2SPX, g2SP})
+		assert.NoError(err)
+		assert.True(ok, "proof of knowledge %d", i)
+	}
+
+	response := bellmanBytes(phase1, challengeHash[:], true)
+	response = append(response[:len(response)-BELLMAN_PUBLIC_KEY_SIZE], bellmanPublicKeyBytes(c.Key)...)
+	responseHash, err := c.ResponseHash()
+	assert.NoError(err)
+	assert.Equal(blake2b.Sum512(response), responseHash)
+	assert.Equal(blake2b.Sum512(bellmanBytes(phase1, responseHash[:], false)), c.NextChallenge)
+
+	return c
+}
+
+func TestWriteNewPtau(t *testing.T) {
+	assert := require.New(t)
+
+	ptauFile, err := InitPtau(testPtauPath)
+	assert.NoError(err)
+	defer ptauFile.Close()
+	assert.Equal(uint32(8), ptauFile.Header.Power)
+	ceremonyPower, err := ptauFile.ceremonyPower()
+	assert.NoError(err)
+	assert.Equal(uint32(8), ceremonyPower)
+
+	contributions, err := ptauFile.ReadContributions()
+	assert.NoError(err)
+	assert.Len(contributions, 1)
+	assert.Equal(CONTRIBUTION_TYPE_CONTRIBUTION, contributions[0].Type)
+	assert.Equal("test", contributions[0].Name)
+	assertPtauContribution(t, testPtauPath, testPtauSecrets, initialBellmanChallengeHash(8))
+
+	// the same seed gives the same file, however the points are chunked
+	defer func(size int) {
+		newPtauChunkSize = size
+	}(newPtauChunkSize)
+	newPtauChunkSize = 100
+	outputPath := t.TempDir() + "/08.ptau"
+	secrets, err := WriteNewPtau(context.Background(), 8, []byte("ptau-deserializer"), "test", outputPath)
+	assert.NoError(err)
+	assert.Equal(testPtauSecrets, secrets)
+	expected, err := os.ReadFile(testPtauPath)
+	assert.NoError(err)
+	actual, err := os.ReadFile(outputPath)
+	assert.NoError(err)
+	assert.Equal(expected, actual)
+
+	_, err = WriteNewPtau(context.Background(), PTAU_MAX_POWER+1, nil, "", outputPath)
+	assert.Error(err)
+}
+
+func TestContribute(t *testing.T) {
+	assert := require.New(t)
+	dir := t.TempDir()
+
+	ptauFile, err := InitPtau(testPtauPath)
+	assert.NoError(err)
+	defer ptauFile.Close()
+	previous, err := ptauFile.ReadContributions()
+	assert.NoError(err)
+
+	defer func(size int) {
+		contributionChunkSize = size
+	}(contributionChunkSize)
+	contributionChunkSize = 100
+
+	// the secrets of the contributions multiply
+	contribution := PtauContribution{Type: CONTRIBUTION_TYPE_CONTRIBUTION, Name: "second"}
+	secrets, err := ptauFile.contribute(context.Background(), newSnarkjsRng(make([]byte, 32)), &contribution, dir+"/second.ptau")
+	assert.NoError(err)
+	secrets.Tau.Mul(&secrets.Tau, &testPtauSecrets.Tau)
+	secrets.Alpha.Mul(&secrets.Alpha, &testPtauSecrets.Alpha)
+	secrets.Beta.Mul(&secrets.Beta, &testPtauSecrets.Beta)
+	c := assertPtauContribution(t, dir+"/second.ptau", secrets, previous[0].NextChallenge)
+	assert.Equal(contribution, c)
+
+	secondFile, err := InitPtau(dir + "/second.ptau")
+	assert.NoError(err)
+	defer secondFile.Close()
+	contributions, err := secondFile.ReadContributions()
+	assert.NoError(err)
+	assert.Equal(append(previous, contribution), contributions)
+
+	// fresh entropy gives a different contribution every time
+	first, err := secondFile.Contribute(context.Background(), []byte("entropy"), "third", dir+"/third.ptau")
+	assert.NoError(err)
+	second, err := secondFile.Contribute(context.Background(), []byte("entropy"), "third", dir+"/third.ptau")
+	assert.NoError(err)
+	assert.NotEqual(first.Key, second.Key)
+	assert.NotEqual(first.NextChallenge, second.NextChallenge)
+
+	thirdFile, err := InitPtau(dir + "/third.ptau")
+	assert.NoError(err)
+	defer thirdFile.Close()
+	contributions, err = thirdFile.ReadContributions()
+	assert.NoError(err)
+	assert.Len(contributions, 3)
+	assert.Equal(second, contributions[2])
+	assert.Equal(CONTRIBUTION_TYPE_CONTRIBUTION, contributions[2].Type)
+}
+
+func TestBeacon(t *testing.T) {
+	assert := require.New(t)
+	dir := t.TempDir()
+
+	ptauFile, err := InitPtau(testPtauPath)
+	assert.NoError(err)
+	defer ptauFile.Close()
+	previous, err := ptauFile.ReadContributions()
+	assert.NoError(err)
+
+	beaconHash := []byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+	contribution, err := ptauFile.Beacon(context.Background(), beaconHash, 10, "final", dir+"/final.ptau")
+	assert.NoError(err)
+
+	// the secrets are the ones the hashed beacon seeds
+	hash := beaconHash
+	for i := 0; i < 1<<10; i++ {
+		sum := sha256.Sum256(hash)
+		hash = sum[:]
+	}
+	secrets, _ := newPtauKey(newSnarkjsRng(hash), previous[0].NextChallenge)
+	secrets.Tau.Mul(&secrets.Tau, &testPtauSecrets.Tau)
+	secrets.Alpha.Mul(&secrets.Alpha, &testPtauSecrets.Alpha)
+	secrets.Beta.Mul(&secrets.Beta, &testPtauSecrets.Beta)
+	c := assertPtauContribution(t, dir+"/final.ptau", secrets, previous[0].NextChallenge)
+	assert.Equal(contribution, c)
+	assert.Equal(CONTRIBUTION_TYPE_BEACON, c.Type)
+	assert.Equal("final", c.Name)
+	assert.Equal(uint8(10), c.NumIterationsExp)
+	assert.Equal(beaconHash, c.BeaconHash)
+
+	// anyone can reproduce it
+	_, err = ptauFile.Beacon(context.Background(), beaconHash, 10, "final", dir+"/reproduced.ptau")
+	assert.NoError(err)
+	expected, err := os.ReadFile(dir + "/final.ptau")
+	assert.NoError(err)
+	actual, err := os.ReadFile(dir + "/reproduced.ptau")
+	assert.NoError(err)
+	assert.Equal(expected, actual)
+
+	var invalidBeacon *InvalidBeacon
+	_, err = ptauFile.Beacon(context.Background(), beaconHash, 9, "", dir+"/invalid.ptau")
+	assert.ErrorAs(err, &invalidBeacon)
+	_, err = ptauFile.Beacon(context.Background(), nil, 10, "", dir+"/invalid.ptau")
+	assert.ErrorAs(err, &invalidBeacon)
+	_, err = os.Stat(dir + "/invalid.ptau")
+	assert.ErrorIs(err, os.ErrNotExist)
+}
+
+func TestPtauJSON(t *testing.T) {
+	assert := require.New(t)
+	dir := t.TempDir()
+
+	// a beacon records every optional parameter of a contribution
+	ptauFile, err := InitPtau(testPtauPath)
+	assert.NoError(err)
+	defer ptauFile.Close()
+	beaconHash := sha256.Sum256([]byte("beacon"))
+	_, err = ptauFile.Beacon(context.Background(), beaconHash[:], 10, "final", dir+"/final.ptau")
+	assert.NoError(err)
+	finalFile, err := InitPtau(dir + "/final.ptau")
+	assert.NoError(err)
+	defer finalFile.Close()
+	contributions, err := finalFile.ReadContributions()
+	assert.NoError(err)
+
+	assert.NoError(WriteJSONFromPtauFile(context.Background(), finalFile, dir+"/final.json"))
+
+	dump, err := os.ReadFile(dir + "/final.json")
+	assert.NoError(err)
+	var parsed struct {
+		Q             string            `json:"q"`
+		Power         uint32            `json:"power"`
+		Contributions []json.RawMessage `json:"contributions"`
+		TauG1         [][3]string       `json:"tauG1"`
+		TauG2         [][3][2]string    `json:"tauG2"`
+		BetaG2        [][3][2]string    `json:"betaG2"`
+	}
+	assert.NoError(json.Unmarshal(dump, &parsed))
+	assert.Equal(fp.Modulus().String(), parsed.Q)
+	assert.Equal(uint32(8), parsed.Power)
+	assert.Len(parsed.Contributions, 2)
+	assert.Len(parsed.TauG1, 511)
+	assert.Equal([3]string{"1", "2", "1"}, parsed.TauG1[0])
+	assert.Len(parsed.TauG2, 256)
+	assert.Equal([2]string{"1", "0"}, parsed.TauG2[0][2])
+	assert.Len(parsed.BetaG2, 1)
+	assert.True(strings.HasPrefix(string(dump), "{\n \"q\": \""))
+	assert.NotContains(string(dump), "ceremonyPower")
+	assert.NotContains(string(dump), "beaconHashSize")
+	assert.NotContains(string(dump), "lTauG1")
+
+	// hashes are the decimals of their little-endian bytes, like snarkjs
+	var beacon struct {
+		NextChallenge string `json:"nextChallenge"`
+		ResponseHash  string `json:"responseHash"`
+		BeaconHash    string `json:"beaconHash"`
+	}
+	assert.NoError(json.Unmarshal(parsed.Contributions[1], &beacon))
+	littleEndian := func(b []byte) string {
+		return new(big.Int).SetBytes(reverseSlice(append([]byte{}, b...))).String()
+	}
+	assert.Equal(littleEndian(contributions[1].NextChallenge[:]), beacon.NextChallenge)
+	assert.Equal(littleEndian(beaconHash[:]), beacon.BeaconHash)
+	responseHash, err := contributions[1].ResponseHash()
+	assert.NoError(err)
+	assert.Equal(littleEndian(responseHash[:]), beacon.ResponseHash)
+	assert.Contains(string(dump), "\n \"tauG1\": [\n  [\n   \"1\",\n   \"2\",\n   \"1\"\n  ],\n  [\n")
+
+	// the dump round-trips exactly
+	input, err := os.Open(dir + "/final.json")
+	assert.NoError(err)
+	defer input.Close()
+	assert.NoError(WritePtauFromJSON(context.Background(), input, dir+"/imported.ptau"))
+	expected, err := os.ReadFile(dir + "/final.ptau")
+	assert.NoError(err)
+	actual, err := os.ReadFile(dir + "/imported.ptau")
+	assert.NoError(err)
+	assert.Equal(expected, actual)
+
+	// points off the curve are rejected
+	invalid := strings.Replace(string(dump), "[\n   \"1\",\n   \"2\",\n   \"1\"\n  ]", "[\n   \"1\",\n   \"3\",\n   \"1\"\n  ]", 1)
+	err = WritePtauFromJSON(context.Background(), strings.NewReader(invalid), dir+"/invalid.ptau")
+	var invalidJSON *InvalidPtauJSON
+	assert.ErrorAs(err, &invalidJSON)
+	assert.Equal("tauG1", invalidJSON.Key)
+	_, err = os.Stat(dir + "/invalid.ptau")
+	assert.ErrorIs(err, os.ErrNotExist)
+
+	// so do the Lagrange sections of a prepared ptau
+	assert.NoError(finalFile.PreparePhase2(context.Background(), dir+"/prepared.ptau", PREPARE_MEMORY_LIMIT))
+	preparedFile, err := InitPtau(dir + "/prepared.ptau")
+	assert.NoError(err)
+	defer preparedFile.Close()
+	assert.NoError(WriteJSONFromPtauFile(context.Background(), preparedFile, dir+"/prepared.json"))
+	dump, err = os.ReadFile(dir + "/prepared.json")
+	assert.NoError(err)
+	var prepared struct {
+		LTauG1      [][][3]string    `json:"lTauG1"`
+		LTauG2      [][][3][2]string `json:"lTauG2"`
+		LAlphaTauG1 [][][3]string    `json:"lAlphaTauG1"`
+		LBetaTauG1  [][][3]string    `json:"lBetaTauG1"`
+	}
+	assert.NoError(json.Unmarshal(dump, &prepared))
+	for _, domains := range [][]int{
+		{len(prepared.LTauG1), len(prepared.LTauG1[8])},
+		{len(prepared.LTauG2), len(prepared.LTauG2[8])},
+		{len(prepared.LAlphaTauG1), len(prepared.LAlphaTauG1[8])},
+		{len(prepared.LBetaTauG1), len(prepared.LBetaTauG1[8])},
+	} {
+		assert.Equal([]int{9, 256}, domains)
+	}
+	// the Lagrange form of 1 over a domain of size 1 is the generator
+	assert.Equal([][3]string{{"1", "2", "1"}}, prepared.LTauG1[0])
+
+	input, err = os.Open(dir + "/prepared.json")
+	assert.NoError(err)
+	defer input.Close()
+	assert.NoError(WritePtauFromJSON(context.Background(), input, dir+"/imported.ptau"))
+	expected, err = os.ReadFile(dir + "/prepared.ptau")
+	assert.NoError(err)
+	actual, err = os.ReadFile(dir + "/imported.ptau")
+	assert.NoError(err)
+	assert.Equal(expected, actual)
+
+	// and come all together
+	partial := strings.Replace(string(dump), "\"lTauG2\"", "\"unknown\"", 1)
+	err = WritePtauFromJSON(context.Background(), strings.NewReader(partial), dir+"/partial.ptau")
+	assert.ErrorAs(err, &invalidJSON)
+
+	// a reduced ptau keeps its ceremony power, and a short beacon hash its size
+	shortHash := []byte{0xbe, 0xac, 0x00, 0x00}
+	_, err = ptauFile.Beacon(context.Background(), shortHash, 10, "short", dir+"/short.ptau")
+	assert.NoError(err)
+	shortFile, err := InitPtau(dir + "/short.ptau")
+	assert.NoError(err)
+	defer shortFile.Close()
+	assert.NoError(shortFile.Truncate(context.Background(), 5, dir+"/reduced.ptau"))
+	reducedFile, err := InitPtau(dir + "/reduced.ptau")
+	assert.NoError(err)
+	defer reducedFile.Close()
+	assert.NoError(WriteJSONFromPtauFile(context.Background(), reducedFile, dir+"/reduced.json"))
+	dump, err = os.ReadFile(dir + "/reduced.json")
+	assert.NoError(err)
+	assert.Contains(string(dump), "\n \"power\": 5,\n \"ceremonyPower\": 8,\n \"contributions\": [")
+	assert.Contains(string(dump), "\"beaconHashSize\": 4\n")
+
+	input, err = os.Open(dir + "/reduced.json")
+	assert.NoError(err)
+	defer input.Close()
+	assert.NoError(WritePtauFromJSON(context.Background(), input, dir+"/imported.ptau"))
+	expected, err = os.ReadFile(dir + "/reduced.ptau")
+	assert.NoError(err)
+	actual, err = os.ReadFile(dir + "/imported.ptau")
+	assert.NoError(err)
+	assert.Equal(expected, actual)
+}
+
+func TestDiff(t *testing.T) {
+	assert := require.New(t)
+	dir := t.TempDir()
+
+	defer func(size int) {
+		diffChunkSize = size
+	}(diffChunkSize)
+	diffChunkSize = 100
+
+	ptauFile, err := InitPtau(testPtauPath)
+	assert.NoError(err)
+	defer ptauFile.Close()
+	assert.NoError(WritePhase1FromPtauFile(context.Background(), ptauFile, dir+"/converted.ph1"))
+
+	// a ptau and its conversion hold the same points
+	diff, err := Diff(context.Background(), testPtauPath, dir+"/converted.ph1", DIFF_MAX_INDICES)
+	assert.NoError(err)
+	assert.Equal([2]string{"ptau", "ph1"}, diff.Types)
+	assert.Equal([2]uint32{8, 8}, diff.Powers)
+	assert.Len(diff.Sections, len(phase1Sections))
+	for _, section := range diff.Sections {
+		assert.Zero(section.Mismatches, section.Name)
+	}
+	assert.Equal(SectionDiff{Name: "TauG1", Counts: [2]int{511, 511}, Compared: 511}, diff.Sections[0])
+	assert.Equal([2]int{1, 1}, diff.Contributions)
+	assert.True(diff.Equal())
+
+	diff, err = Diff(context.Background(), testPtauPath, testPtauPath, DIFF_MAX_INDICES)
+	assert.NoError(err)
+	assert.True(diff.Equal())
+
+	// swapped points are found in the middle of a chunk
+	ph1, err := os.ReadFile(dir + "/converted.ph1")
+	assert.NoError(err)
+	layout := newPhase1Layout(8)
+	point := func(i int64) []byte {
+		offset := layout.tauG1 + i*curve.SizeOfG1AffineCompressed
+		return ph1[offset : offset+curve.SizeOfG1AffineCompressed]
+	}
+	swapped := append([]byte(nil), point(150)...)
+	copy(point(150), point(420))
+	copy(point(420), swapped)
+	assert.NoError(os.WriteFile(dir+"/swapped.ph1", ph1, 0o644))
+
+	diff, err = Diff(context.Background(), dir+"/converted.ph1", dir+"/swapped.ph1", 1)
+	assert.NoError(err)
+	assert.False(diff.Equal())
+	assert.Equal(2, diff.Sections[0].Mismatches)
+	assert.Equal([]int{150}, diff.Sections[0].FirstMismatches)
+	assert.Zero(diff.Sections[1].Mismatches)
+
+	// a contribution changes every point but the generators
+	contribution := PtauContribution{Type: CONTRIBUTION_TYPE_CONTRIBUTION, Name: "second"}
+	_, err = ptauFile.contribute(context.Background(), newSnarkjsRng(make([]byte, 32)), &contribution, dir+"/second.ptau")
+	assert.NoError(err)
+
+	diff, err = Diff(context.Background(), testPtauPath, dir+"/second.ptau", 3)
+	assert.NoError(err)
+	assert.Equal([2]int{1, 2}, diff.Contributions)
+	assert.Equal([]int{1}, diff.ContributionMismatches)
+	assert.Equal(510, diff.Sections[0].Mismatches)
+	assert.Equal([]int{1, 2, 3}, diff.Sections[0].FirstMismatches)
+	assert.Equal(256, diff.Sections[1].Mismatches)
+	assert.Equal(255, diff.Sections[3].Mismatches)
+}
+
+func TestCheckDerivation(t *testing.T) {
+	assert := require.New(t)
+	dir := t.TempDir()
+
+	defer func(size int) {
+		derivationChunkSize = size
+	}(derivationChunkSize)
+	derivationChunkSize = 100
+
+	ptauFile, err := InitPtau(testPtauPath)
+	assert.NoError(err)
+	defer ptauFile.Close()
+	assert.NoError(WritePhase1FromPtauFile(context.Background(), ptauFile, dir+"/converted.ph1"))
+
+	check := func(ph1 []byte, samples int) error {
+		assert.NoError(os.WriteFile(dir+"/checked.ph1", ph1, 0o644))
+		phase1File, err := InitPhase1(dir + "/checked.ph1")
+		assert.NoError(err)
+		defer phase1File.Close()
+		return CheckDerivation(context.Background(), ptauFile, phase1File, samples)
+	}
+
+	// the conversion records the contributions of the ptau
+	ph1, err := os.ReadFile(dir + "/converted.ph1")
+	assert.NoError(err)
+	assert.NoError(check(ph1, 0))
+	assert.NoError(check(ph1, 10))
+
+	// and so does one written from memory
+	ptau, err := ReadPtau(testPtauPath)
+	assert.NoError(err)
+	assert.Equal(uint32(1), ptau.NumContributions)
+	phase1, err := ConvertPtauToPhase1(ptau)
+	assert.NoError(err)
+	assert.NoError(WritePhase1(phase1, 8, dir+"/memory.ph1"))
+	memory, err := os.ReadFile(dir + "/memory.ph1")
+	assert.NoError(err)
+	assert.NoError(check(memory, 0))
+
+	var mismatch *DerivationMismatch
+	binary.BigEndian.PutUint16(ph1[1:], 54)
+	assert.ErrorAs(check(ph1, 0), &mismatch)
+	assert.Equal("header", mismatch.Section)
+	binary.BigEndian.PutUint16(ph1[1:], 1)
+
+	// swapped points are found by both checks
+	layout := newPhase1Layout(8)
+	point := func(i int64) []byte {
+		offset := layout.betaTauG1 + i*curve.SizeOfG1AffineCompressed
+		return ph1[offset : offset+curve.SizeOfG1AffineCompressed]
+	}
+	swapped := append([]byte(nil), point(130)...)
+	copy(point(130), point(200))
+	copy(point(200), swapped)
+
+	assert.ErrorAs(check(ph1, 0), &mismatch)
+	assert.Equal(DerivationMismatch{Section: "BetaTauG1", Index: 130, Reason: "point 130 differs"}, *mismatch)
+	assert.ErrorAs(check(ph1, 1<<10), &mismatch)
+	assert.Equal(DerivationMismatch{Section: "BetaTauG1", Index: -1, Reason: "a random linear combination of 256 points differs"}, *mismatch)
+	copy(point(200), point(130))
+	copy(point(130), swapped)
+
+	// so is a changed G2 point, when it is sampled
+	g2 := layout.tauG2 + 255*curve.SizeOfG2AffineCompressed
+	changed := append([]byte(nil), ph1[g2:g2+curve.SizeOfG2AffineCompressed]...)
+	copy(ph1[g2:], ph1[layout.tauG2+curve.SizeOfG2AffineCompressed:layout.tauG2+2*curve.SizeOfG2AffineCompressed])
+	assert.ErrorAs(check(ph1, 1<<10), &mismatch)
+	assert.Equal("TauG2", mismatch.Section)
+	copy(ph1[g2:], changed)
+	assert.NoError(check(ph1, 1<<10))
+
+	// a .ph1 of another ptau
+	ph1[0] = 9
+	ph1 = append(ph1, make([]byte, newPhase1Layout(9).end-int64(len(ph1)))...)
+	assert.ErrorAs(check(ph1, 0), &mismatch)
+	assert.Equal("header", mismatch.Section)
+}
+
+func BenchmarkCheckDerivation(b *testing.B) {
+	assert := require.New(b)
+	dir := b.TempDir()
+
+	// large enough for reading the points to outweigh the header checks
+	_, err := WriteNewPtau(context.Background(), 14, []byte("benchmark"), "benchmark", dir+"/14.ptau")
+	assert.NoError(err)
+	ptauFile, err := InitPtau(dir + "/14.ptau")
+	assert.NoError(err)
+	defer ptauFile.Close()
+	assert.NoError(WritePhase1FromPtauFile(context.Background(), ptauFile, dir+"/converted.ph1"))
+	phase1File, err := InitPhase1(dir + "/converted.ph1")
+	assert.NoError(err)
+	defer phase1File.Close()
+
+	for _, mode := range []struct {
+		name    string
+		samples int
+	}{{"every point", 0}, {"16 samples", 16}} {
+		b.Run(mode.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := CheckDerivation(context.Background(), ptauFile, phase1File, mode.samples); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyPhase1(t *testing.T) {
+	assert := require.New(t)
+	dir := t.TempDir()
+
+	defer func(size int) {
+		verifyChunkSize = size
+	}(verifyChunkSize)
+	verifyChunkSize = 100
+
+	ptauFile, err := InitPtau(testPtauPath)
+	assert.NoError(err)
+	defer ptauFile.Close()
+	assert.NoError(WritePhase1FromPtauFile(context.Background(), ptauFile, dir+"/valid.ph1"))
+
+	verify := func(ph1 []byte) error {
+		assert.NoError(os.WriteFile(dir+"/verified.ph1", ph1, 0o644))
+		phase1File, err := InitPhase1(dir + "/verified.ph1")
+		assert.NoError(err)
+		defer phase1File.Close()
+		return phase1File.Verify(context.Background())
+	}
+
+	ph1, err := os.ReadFile(dir + "/valid.ph1")
+	assert.NoError(err)
+	assert.NoError(verify(ph1))
+
+	// the same check runs in memory
+	ptau, err := ReadPtau(testPtauPath)
+	assert.NoError(err)
+	phase1, err := ConvertPtauToPhase1(ptau)
+	assert.NoError(err)
+	assert.NoError(phase1.Verify())
+	phase1.alphaTauG1[3], phase1.alphaTauG1[4] = phase1.alphaTauG1[4], phase1.alphaTauG1[3]
+	var invalid *InvalidPhase1
+	assert.ErrorAs(phase1.Verify(), &invalid)
+	assert.Equal("AlphaTauG1", invalid.Section)
+
+	// swapping two points of a section breaks it, in any chunk
+	layout := newPhase1Layout(8)
+	for _, section := range []struct {
+		name   string
+		offset int64
+		size   int64
+		i, j   int64
+	}{
+		{"TauG1", layout.tauG1, curve.SizeOfG1AffineCompressed, 99, 100},
+		{"BetaTauG1", layout.betaTauG1, curve.SizeOfG1AffineCompressed, 7, 250},
+		{"TauG2", layout.tauG2, curve.SizeOfG2AffineCompressed, 120, 121},
+	} {
+		swapped := append([]byte(nil), ph1...)
+		i, j := section.offset+section.i*section.size, section.offset+section.j*section.size
+		copy(swapped[i:i+section.size], ph1[j:j+section.size])
+		copy(swapped[j:j+section.size], ph1[i:i+section.size])
+		assert.ErrorAs(verify(swapped), &invalid)
+		assert.Equal(section.name, invalid.Section)
+	}
+
+	// BetaG2 has to hold the beta of BetaTauG1
+	other := append([]byte(nil), ph1...)
+	copy(other[layout.betaG2:], ph1[layout.tauG2+curve.SizeOfG2AffineCompressed:layout.tauG2+2*curve.SizeOfG2AffineCompressed])
+	assert.ErrorAs(verify(other), &invalid)
+	assert.Equal("BetaG2", invalid.Section)
+
+	// a .ph1 of another tau is valid, but not one mixing two taus
+	contribution := PtauContribution{Type: CONTRIBUTION_TYPE_CONTRIBUTION, Name: "second"}
+	_, err = ptauFile.contribute(context.Background(), newSnarkjsRng(make([]byte, 32)), &contribution, dir+"/second.ptau")
+	assert.NoError(err)
+	secondFile, err := InitPtau(dir + "/second.ptau")
+	assert.NoError(err)
+	defer secondFile.Close()
+	assert.NoError(WritePhase1FromPtauFile(context.Background(), secondFile, dir+"/second.ph1"))
+	second, err := os.ReadFile(dir + "/second.ph1")
+	assert.NoError(err)
+	assert.NoError(verify(second))
+
+	mixed := append(append([]byte(nil), ph1[:layout.tauG2]...), second[layout.tauG2:]...)
+	assert.ErrorAs(verify(mixed), &invalid)
+	assert.Equal("TauG1", invalid.Section)
+}
+
+func TestWritePhase1sFromPtauFile(t *testing.T) {
+	assert := require.New(t)
+	dir := t.TempDir()
+
+	defer func(size int) {
+		fanOutChunkSize = size
+	}(fanOutChunkSize)
+	fanOutChunkSize = 100
+
+	ptauFile, err := InitPtau(testPtauPath)
+	assert.NoError(err)
+	defer ptauFile.Close()
+	assert.NoError(WritePhase1FromPtauFile(context.Background(), ptauFile, dir+"/full.ph1"))
+	full, err := os.ReadFile(dir + "/full.ph1")
+	assert.NoError(err)
+
+	outputPaths := map[uint32]string{}
+	for _, power := range []uint32{1, 3, 7, 8} {
+		outputPaths[power] = fmt.Sprintf("%s/%02d.ph1", dir, power)
+	}
+	assert.NoError(WritePhase1sFromPtauFile(context.Background(), ptauFile, outputPaths))
+
+	// every output holds the prefixes of the sections of the full conversion
+	fullLayout := newPhase1Layout(8)
+	for power, path := range outputPaths {
+		layout := newPhase1Layout(byte(power))
+		expected := []byte{byte(power), 0, 1}
+		for s, offset := range fullLayout.offsets() {
+			size := int64(phase1Sections[s].numPoints(1<<power)) * phase1Sections[s].pointSize()
+			expected = append(expected, full[offset:offset+size]...)
+		}
+		actual, err := os.ReadFile(path)
+		assert.NoError(err)
+		assert.Equal(int(layout.end), len(actual))
+		assert.Equal(expected, actual, "power %d", power)
+
+		phase1File, err := InitPhase1(path)
+		assert.NoError(err)
+		assert.NoError(phase1File.Verify(context.Background()))
+		phase1File.Close()
+	}
+
+	// nothing is written when a power is out of reach
+	err = WritePhase1sFromPtauFile(context.Background(), ptauFile, map[uint32]string{4: dir + "/04.ph1", 9: dir + "/09.ph1"})
+	assert.Error(err)
+	_, err = os.Stat(dir + "/04.ph1")
+	assert.ErrorIs(err, os.ErrNotExist)
+}
+
+func TestTruncate(t *testing.T) {
+	assert := require.New(t)
+	dir := t.TempDir()
+
+	ptauFile, err := InitPtau(testPtauPath)
+	assert.NoError(err)
+	defer ptauFile.Close()
+	contributions, err := ptauFile.ReadContributions()
+	assert.NoError(err)
+
+	assert.NoError(ptauFile.Truncate(context.Background(), 5, dir+"/05.ptau"))
+
+	truncated, err := InitPtau(dir + "/05.ptau")
+	assert.NoError(err)
+	defer truncated.Close()
+	assert.Equal(uint32(5), truncated.Header.Power)
+	ceremonyPower, err := truncated.ceremonyPower()
+	assert.NoError(err)
+	assert.Equal(uint32(8), ceremonyPower)
+	truncatedContributions, err := truncated.ReadContributions()
+	assert.NoError(err)
+	assert.Equal(contributions, truncatedContributions)
+	for sectionId := uint32(2); sectionId <= 6; sectionId++ {
+		assert.Equal(ptauPointsSize(sectionId, 5), truncated.Sections[sectionId][0].size)
+	}
+
+	// the points are the prefixes of the original sections
+	diff, err := Diff(context.Background(), testPtauPath, dir+"/05.ptau", DIFF_MAX_INDICES)
+	assert.NoError(err)
+	assert.Empty(diff.ContributionMismatches)
+	for _, section := range diff.Sections {
+		assert.Equal(section.Counts[1], section.Compared, section.Name)
+		assert.Zero(section.Mismatches, section.Name)
+	}
+
+	assert.NoError(WritePhase1FromPtauFile(context.Background(), truncated, dir+"/05.ph1"))
+	phase1File, err := InitPhase1(dir + "/05.ph1")
+	assert.NoError(err)
+	defer phase1File.Close()
+	assert.NoError(phase1File.Verify(context.Background()))
+
+	// like snarkjs, a reduced ptau can't be contributed to
+	var reduced *ReducedPtau
+	_, err = truncated.Contribute(context.Background(), []byte("entropy"), "reduced", dir+"/contributed.ptau")
+	assert.ErrorAs(err, &reduced)
+	assert.Equal(ReducedPtau{Power: 5, CeremonyPower: 8}, *reduced)
+	_, err = truncated.Beacon(context.Background(), make([]byte, 32), 10, "reduced", dir+"/beacon.ptau")
+	assert.ErrorAs(err, &reduced)
+	assert.NoFileExists(dir + "/contributed.ptau")
+	assert.NoFileExists(dir + "/beacon.ptau")
+
+	assert.Error(ptauFile.Truncate(context.Background(), 8, dir+"/08.ptau"))
+	assert.Error(ptauFile.Truncate(context.Background(), 0, dir+"/00.ptau"))
+}
+
+func TestPreparePhase2(t *testing.T) {
+	assert := require.New(t)
+	dir := t.TempDir()
+
+	ptauFile, err := InitPtau(testPtauPath)
+	assert.NoError(err)
+	defer ptauFile.Close()
+	assert.False(ptauFile.preparedForPhase2())
+	assert.NoError(ptauFile.Truncate(context.Background(), 5, dir+"/05.ptau"))
+	truncated, err := InitPtau(dir + "/05.ptau")
+	assert.NoError(err)
+	defer truncated.Close()
+
+	// room for 32 points of G1 and 16 of G2 splits the largest FFTs in
+	// several blocks of columns and rows
+	assert.NoError(truncated.PreparePhase2(context.Background(), dir+"/05_prepared.ptau", 1<<30))
+	assert.NoError(truncated.PreparePhase2(context.Background(), dir+"/05_split.ptau", 32*2*3*BN254_FIELD_ELEMENT_SIZE))
+	expected, err := os.ReadFile(dir + "/05_prepared.ptau")
+	assert.NoError(err)
+	actual, err := os.ReadFile(dir + "/05_split.ptau")
+	assert.NoError(err)
+	assert.Equal(expected, actual)
+	matches, err := filepath.Glob(dir + "/*.tmp")
+	assert.NoError(err)
+	assert.Empty(matches)
+
+	prepared, err := InitPtau(dir + "/05_prepared.ptau")
+	assert.NoError(err)
+	defer prepared.Close()
+	assert.True(prepared.preparedForPhase2())
+	assert.Equal(uint32(5), prepared.Header.Power)
+	ceremonyPower, err := prepared.ceremonyPower()
+	assert.NoError(err)
+	assert.Equal(uint32(8), ceremonyPower)
+	for _, sectionId := range []uint32{12, 13, 14, 15} {
+		assert.Equal(ptauPointsSize(sectionId, 5), prepared.Sections[sectionId][0].size)
+	}
+
+	// the sections of the ptau are unchanged
+	diff, err := Diff(context.Background(), dir+"/05.ptau", dir+"/05_prepared.ptau", DIFF_MAX_INDICES)
+	assert.NoError(err)
+	assert.True(diff.Equal())
+
+	readG1s := func(ptauFile *PtauFile, sectionId uint32, start, count int) []curve.G1Affine {
+		reader := io.NewSectionReader(ptauFile.Reader, int64(ptauFile.Sections[sectionId][0].pos)+int64(start)*64, int64(count)*64)
+		buffer := make([]byte, BN254_FIELD_ELEMENT_SIZE)
+		points := make([]curve.G1Affine, count)
+		for i := range points {
+			points[i], err = readG1Affine(reader, buffer)
+			assert.NoError(err)
+		}
+		return points
+	}
+	tauG1 := readG1s(prepared, 2, 0, 63)
+	alphaTauG1 := readG1s(prepared, 4, 0, 32)
+	for p := 0; p <= 6; p++ {
+		n := 1 << p
+		domain := fft.NewDomain(uint64(n))
+		assert.Equal(rootOfUnity(uint32(p)), domain.Generator)
+
+		// the largest domain of tauG1 ends with a zero point
+		powers := append(append([]curve.G1Affine{}, tauG1[:min(n, 63)]...), make([]curve.G1Affine, n-min(n, 63))...)
+		assert.Equal(lagrangeG1(powers, domain.Generator), readG1s(prepared, 12, n-1, n), "power %d", p)
+		if p <= 5 {
+			assert.Equal(lagrangeG1(alphaTauG1[:n], domain.Generator), readG1s(prepared, 14, n-1, n), "power %d", p)
+		}
+	}
+
+	// e([Lᵢ(τ)]₁, [1]₂) = e([1]₁, [Lᵢ(τ)]₂)
+	_, _, g1, g2 := curve.Generators()
+	lTauG1 := readG1s(prepared, 12, 7, 8)
+	reader := io.NewSectionReader(prepared.Reader, int64(prepared.Sections[13][0].pos)+7*128, 8*128)
+	buffer := make([]byte, BN254_FIELD_ELEMENT_SIZE)
+	for i := range lTauG1 {
+		lTauG2, err := readG2Affine(reader, buffer)
+		assert.NoError(err)
+		ok, err := samePairing(&lTauG1[i], &g2, &g1, &lTauG2)
+		assert.NoError(err)
+		assert.True(ok, "point %d", i)
+	}
+
+	// truncating keeps the prefixes of the Lagrange sections
+	assert.NoError(prepared.Truncate(context.Background(), 3, dir+"/03.ptau"))
+	truncatedPrepared, err := InitPtau(dir + "/03.ptau")
+	assert.NoError(err)
+	defer truncatedPrepared.Close()
+	assert.True(truncatedPrepared.preparedForPhase2())
+	for _, sectionId := range []uint32{12, 13, 14, 15} {
+		size := ptauPointsSize(sectionId, 3)
+		assert.Equal(size, truncatedPrepared.Sections[sectionId][0].size)
+		b := make([]byte, size)
+		_, err := truncatedPrepared.Reader.ReadAt(b, int64(truncatedPrepared.Sections[sectionId][0].pos))
+		assert.NoError(err)
+		start := prepared.Sections[sectionId][0].pos
+		assert.Equal(expected[start:start+size], b, "section %d", sectionId)
+	}
+
+	// past the largest domain, lTauG1 is taken over it and its coset: the
+	// powers of its points dⱼ give back tauG1, ∑ⱼ dⱼᵏ [Lⱼ(τ)]₁ = [τᵏ]₁
+	defer func(power uint32) {
+		lagrangeMaxPower = power
+	}(lagrangeMaxPower)
+	lagrangeMaxPower = 5
+	assert.NoError(truncated.PreparePhase2(context.Background(), dir+"/05_coset.ptau", 1<<30))
+	assert.NoError(truncated.PreparePhase2(context.Background(), dir+"/05_coset_split.ptau", 32*2*3*BN254_FIELD_ELEMENT_SIZE))
+	coset, err := os.ReadFile(dir + "/05_coset.ptau")
+	assert.NoError(err)
+	actual, err = os.ReadFile(dir + "/05_coset_split.ptau")
+	assert.NoError(err)
+	assert.Equal(coset, actual)
+	lastDomain := prepared.Sections[12][0].pos + 63*64
+	assert.Equal(expected[:lastDomain], coset[:lastDomain])
+
+	cosetFile, err := InitPtau(dir + "/05_coset.ptau")
+	assert.NoError(err)
+	defer cosetFile.Close()
+	lagrange := readG1s(cosetFile, 12, 63, 64)
+	domain := make([]fr.Element, 64)
+	omega := rootOfUnity(5)
+	var shift fr.Element
+	shift.SetUint64(LAGRANGE_COSET_SHIFT)
+	domain[0].SetOne()
+	domain[32] = shift
+	for j := 1; j < 32; j++ {
+		domain[j].Mul(&domain[j-1], &omega)
+		domain[32+j].Mul(&domain[31+j], &omega)
+	}
+	powers := make([]fr.Element, 64)
+	for j := range powers {
+		powers[j].SetOne()
+	}
+	for k := 0; k < 64; k++ {
+		var sum curve.G1Affine
+		_, err := sum.MultiExp(lagrange, powers, ecc.MultiExpConfig{})
+		assert.NoError(err)
+		if k < 63 {
+			assert.Equal(tauG1[k], sum, "power %d", k)
+		} else {
+			assert.True(sum.IsInfinity())
+		}
+		for j := range powers {
+			powers[j].Mul(&powers[j], &domain[j])
+		}
+	}
+	lagrangeMaxPower = 4
+	assert.Error(truncated.PreparePhase2(context.Background(), dir+"/06.ptau", 1<<30))
+	lagrangeMaxPower = BN254_TWO_ADICITY
 
-	fmt.Printf("power is: %v \n", zkey.protocolHeader.power)
+	// the largest FFTs can't be split to hold 2 points at once
+	assert.Error(truncated.PreparePhase2(context.Background(), dir+"/invalid.ptau", 2*2*3*BN254_FIELD_ELEMENT_SIZE))
+	_, err = os.Stat(dir + "/invalid.ptau")
+	assert.True(os.IsNotExist(err))
 }
@@ -0,0 +1,233 @@
+package deserializer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+)
+
+///////////////////////////////////////////////////////////////////
+///                             DIFF                            ///
+///////////////////////////////////////////////////////////////////
+
+// Two ptau or .ph1 files, or one of each, are compared section by section in
+// the order of phase1Sections. Points are compared as curve points, so that a
+// ptau and the .ph1 converted from it are equal, and sections of different
+// lengths, such as the ones of a reduced ptau, are compared on their common
+// prefix. The contribution records are only compared when both files are
+// ptau, a .ph1 only holds their count.
+
+// DIFF_MAX_INDICES is the default number of differing indices reported per
+// section.
+const DIFF_MAX_INDICES = 10
+
+// diffChunkSize is the number of points read from each file and compared at
+// once.
+var diffChunkSize = 1 << 16
+
+// FileDiff is the result of comparing two files, the arrays hold the value
+// of the first then the second file.
+type FileDiff struct {
+	// Types are ptau or ph1
+	Types         [2]string `json:"types"`
+	Powers        [2]uint32 `json:"powers"`
+	Contributions [2]int    `json:"contributions"`
+	// ContributionMismatches are the indices of the contributions recorded
+	// differently in both ptau files, or missing from one of them
+	ContributionMismatches []int         `json:"contributionMismatches,omitempty"`
+	Sections               []SectionDiff `json:"sections"`
+}
+
+// SectionDiff is the comparison of a section of points.
+type SectionDiff struct {
+	Name   string `json:"name"`
+	Counts [2]int `json:"counts"`
+	// Compared is the number of points both files hold
+	Compared   int `json:"compared"`
+	Mismatches int `json:"mismatches"`
+	// FirstMismatches are the indices of the first differing points
+	FirstMismatches []int `json:"firstMismatches,omitempty"`
+}
+
+// Equal reports whether both files hold the same header and points.
+func (diff *FileDiff) Equal() bool {
+	if diff.Powers[0] != diff.Powers[1] || diff.Contributions[0] != diff.Contributions[1] || len(diff.ContributionMismatches) > 0 {
+		return false
+	}
+	for _, section := range diff.Sections {
+		if section.Mismatches > 0 || section.Counts[0] != section.Counts[1] {
+			return false
+		}
+	}
+	return true
+}
+
+// diffFile is one of the files of a diff.
+type diffFile struct {
+	source        phase1Source
+	closer        io.Closer
+	kind          string
+	contributions []PtauContribution
+	count         int
+}
+
+// openDiffFile opens the ptau or .ph1 at path, telling them apart from their
+// magic.
+func openDiffFile(path string) (*diffFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	magic := make([]byte, 4)
+	_, err = io.ReadFull(file, magic)
+	file.Close()
+	if err != nil {
+		return nil, fmt.Errorf("%s is too small to be a ptau or ph1 file", path)
+	}
+
+	if string(magic) != ptauMagic {
+		phase1File, err := InitPhase1(path)
+		if err != nil {
+			return nil, err
+		}
+		return &diffFile{source: phase1File, closer: phase1File, kind: "ph1", count: int(phase1File.Header.Contributions)}, nil
+	}
+
+	ptauFile, err := InitPtau(path)
+	if err != nil {
+		return nil, err
+	}
+	contributions, err := ptauFile.ReadContributions()
+	if err != nil {
+		ptauFile.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &diffFile{source: ptauFile, closer: ptauFile, kind: ptauMagic, contributions: contributions, count: len(contributions)}, nil
+}
+
+// Diff compares the ptau or .ph1 files at pathA and pathB, reporting up to
+// maxIndices differing indices per section.
+func Diff(ctx context.Context, pathA, pathB string, maxIndices int) (*FileDiff, error) {
+	a, err := openDiffFile(pathA)
+	if err != nil {
+		return nil, err
+	}
+	defer a.closer.Close()
+	b, err := openDiffFile(pathB)
+	if err != nil {
+		return nil, err
+	}
+	defer b.closer.Close()
+
+	diff := &FileDiff{
+		Types:         [2]string{a.kind, b.kind},
+		Powers:        [2]uint32{a.source.phase1Power(), b.source.phase1Power()},
+		Contributions: [2]int{a.count, b.count},
+	}
+
+	if a.kind == ptauMagic && b.kind == ptauMagic {
+		for i := 0; i < max(len(a.contributions), len(b.contributions)); i++ {
+			if i >= len(a.contributions) || i >= len(b.contributions) || !reflect.DeepEqual(a.contributions[i], b.contributions[i]) {
+				diff.ContributionMismatches = append(diff.ContributionMismatches, i)
+			}
+		}
+	}
+
+	for _, section := range phase1Sections {
+		sectionDiff, err := diffSection(ctx, a.source, b.source, section, maxIndices)
+		if err != nil {
+			return nil, err
+		}
+		log().Debug("compared section", "section", section.name, "points", sectionDiff.Compared, "mismatches", sectionDiff.Mismatches)
+		diff.Sections = append(diff.Sections, sectionDiff)
+	}
+
+	return diff, nil
+}
+
+// diffSection compares the points of section both sources hold, a chunk at a
+// time. The chunks are read from each file in turn, then decoded and compared
+// in parallel. Points stored in the same encoding are equal when their bytes
+// are, the others are decoded, and a point that can't be decoded differs from
+// any other.
+func diffSection(ctx context.Context, a, b phase1Source, section phase1Section, maxIndices int) (SectionDiff, error) {
+	diff := SectionDiff{
+		Name:   section.name,
+		Counts: [2]int{section.numPoints(1 << a.phase1Power()), section.numPoints(1 << b.phase1Power())},
+	}
+	diff.Compared = min(diff.Counts[0], diff.Counts[1])
+
+	readerA, err := a.phase1SectionReader(section, 0)
+	if err != nil {
+		return diff, err
+	}
+	readerB, err := b.phase1SectionReader(section, 0)
+	if err != nil {
+		return diff, err
+	}
+
+	sizeA, sizeB := int(a.phase1PointSize(section)), int(b.phase1PointSize(section))
+	chunkA := make([]byte, diffChunkSize*sizeA)
+	chunkB := make([]byte, diffChunkSize*sizeB)
+	mismatches := make([]bool, diffChunkSize)
+
+	for start := 0; start < diff.Compared; start += diffChunkSize {
+		if err := ctx.Err(); err != nil {
+			return diff, err
+		}
+
+		n := min(diffChunkSize, diff.Compared-start)
+		if _, err := io.ReadFull(readerA, chunkA[:n*sizeA]); err != nil {
+			return diff, fmt.Errorf("%s point %d: %w", section.name, start, err)
+		}
+		if _, err := io.ReadFull(readerB, chunkB[:n*sizeB]); err != nil {
+			return diff, fmt.Errorf("%s point %d: %w", section.name, start, err)
+		}
+
+		parallelize(n, func(first, end int) {
+			buffer := make([]byte, 4*BN254_FIELD_ELEMENT_SIZE)
+			for i := first; i < end; i++ {
+				pointA, pointB := chunkA[i*sizeA:(i+1)*sizeA], chunkB[i*sizeB:(i+1)*sizeB]
+				if sizeA == sizeB && bytes.Equal(pointA, pointB) {
+					mismatches[i] = false
+					continue
+				}
+				mismatches[i] = !equalPoints(a, b, section, pointA, pointB, buffer)
+			}
+		})
+
+		for i, mismatch := range mismatches[:n] {
+			if !mismatch {
+				continue
+			}
+			diff.Mismatches++
+			if len(diff.FirstMismatches) < maxIndices {
+				diff.FirstMismatches = append(diff.FirstMismatches, start+i)
+			}
+		}
+	}
+
+	return diff, nil
+}
+
+// equalPoints decodes the point of section that a stores as pointA, and the
+// one b stores as pointB, and compares them.
+func equalPoints(a, b phase1Source, section phase1Section, pointA, pointB []byte, buffer []byte) bool {
+	if section.isG2 {
+		p, err := a.readPhase1G2(bytes.NewReader(pointA), buffer)
+		if err != nil {
+			return false
+		}
+		q, err := b.readPhase1G2(bytes.NewReader(pointB), buffer)
+		return err == nil && p.Equal(&q)
+	}
+	p, err := a.readPhase1G1(bytes.NewReader(pointA), buffer)
+	if err != nil {
+		return false
+	}
+	q, err := b.readPhase1G1(bytes.NewReader(pointB), buffer)
+	return err == nil && p.Equal(&q)
+}
@@ -0,0 +1,46 @@
+package deserializer_test
+
+import (
+	"fmt"
+
+	deserializer "github.com/worldcoin/ptau-deserializer/deserialize"
+)
+
+// testdata/groth16.zkey only holds the header sections of a Groth16 zkey.
+
+func ExampleReadZkey() {
+	zkey, err := deserializer.ReadZkey("testdata/groth16.zkey")
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println("curve:", zkey.Curve())
+	fmt.Println("domain size:", zkey.DomainSize())
+	fmt.Println("power:", zkey.Power())
+	fmt.Println("public inputs:", zkey.NPublic())
+	// Output:
+	// curve: bn254
+	// domain size: 128
+	// power: 7
+	// public inputs: 2
+}
+
+func ExampleZkey_Groth16Header() {
+	zkey, err := deserializer.ReadZkey("testdata/groth16.zkey")
+	if err != nil {
+		panic(err)
+	}
+
+	header, ok := zkey.Groth16Header()
+	if !ok {
+		panic("not a Groth16 zkey")
+	}
+
+	fmt.Println("nVars:", header.NVars)
+	fmt.Println("nPublic:", header.NPublic)
+	fmt.Println("r:", header.R.String())
+	// Output:
+	// nVars: 100
+	// nPublic: 2
+	// r: 21888242871839275222246405745257275088548364400416034343698204186575808495617
+}
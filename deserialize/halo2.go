@@ -0,0 +1,182 @@
+package deserializer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+///////////////////////////////////////////////////////////////////
+///                        HALO2 PARAMS                         ///
+///////////////////////////////////////////////////////////////////
+
+// Taken from the privacy-scaling-explorations/halo2 repo,
+// halo2_proofs/src/poly/kzg/commitment.rs (ParamsKZG::write_custom)
+// https://github.com/privacy-scaling-explorations/halo2/blob/main/halo2_proofs/src/poly/kzg/commitment.rs
+/*
+4 bytes, k (little-endian)
+{2^k}[
+    s^i*G1
+]
+{2^k}[
+    L_i(s)*G1, over the domain of size 2^k
+]
+G2
+s*G2
+*/
+// The points are laid out according to the SerdeFormat:
+//   - Processed: compressed, the x coordinate in canonical little-endian form
+//     with the sign of y in the top bit of its last byte, its parity in G1,
+//     the parity of c0, or of c1 when c0 is zero, in G2
+//   - RawBytes: the x and y coordinates in little-endian Montgomery form, like
+//     in a ptau
+// The coordinates of G2 points are elements of Fq2, written c0 then c1.
+
+type Halo2SerdeFormat int
+
+const (
+	HALO2_SERDE_PROCESSED Halo2SerdeFormat = iota
+	HALO2_SERDE_RAW_BYTES
+)
+
+// HALO2_ROOT_OF_UNITY generates the 2^28 roots of unity of the bn256 scalar
+// field in halo2curves: 7^t where r-1 = t*2^28. gnark picks 5^t, so the
+// domains of both libraries don't enumerate their points in the same order.
+const HALO2_ROOT_OF_UNITY = "0x03ddb9f5166d18b798865ea93dd31f743215cf6dd39329c8d34f1ed960c37c9c"
+
+// WriteHalo2Params writes the halo2 ParamsKZG of size 2^k held by the ptau
+// to outputPath, in the given serialization format. k can't exceed the power
+// of the ptau.
+func WriteHalo2Params(ptauFile *PtauFile, k uint32, format Halo2SerdeFormat, outputPath string) error {
+	if k < 1 || k > ptauFile.Header.Power {
+		return fmt.Errorf("halo2 params of k %d can't be made from a ptau of power %d, k must be between 1 and %d", k, ptauFile.Header.Power, ptauFile.Header.Power)
+	}
+	if format != HALO2_SERDE_PROCESSED && format != HALO2_SERDE_RAW_BYTES {
+		return fmt.Errorf("unknown halo2 serde format %d", format)
+	}
+
+	srs, err := PtauToKZGSRS(ptauFile, 1<<k)
+	if err != nil {
+		return err
+	}
+
+	log().Info("computing the Lagrange form of the params", "k", k)
+	lagrange := lagrangeG1(srs.G1, halo2Omega(k))
+
+	outputFile, err := createAtomicFile(outputPath)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(outputFile)
+	if err := writeHalo2Params(writer, k, srs.G1, lagrange, srs.G2, format); err != nil {
+		outputFile.Abort()
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		outputFile.Abort()
+		return err
+	}
+
+	return outputFile.Commit()
+}
+
+func writeHalo2Params(writer io.Writer, k uint32, g []bn254.G1Affine, gLagrange []bn254.G1Affine, g2 [2]bn254.G2Affine, format Halo2SerdeFormat) error {
+	if err := binary.Write(writer, binary.LittleEndian, k); err != nil {
+		return err
+	}
+
+	for _, points := range [][]bn254.G1Affine{g, gLagrange} {
+		for i := range points {
+			if _, err := writer.Write(halo2G1Bytes(&points[i], format)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i := range g2 {
+		if _, err := writer.Write(halo2G2Bytes(&g2[i], format)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// halo2Omega is the generator halo2 uses for the domain of size 2^k.
+func halo2Omega(k uint32) fr.Element {
+	var omega fr.Element
+	if _, err := omega.SetString(HALO2_ROOT_OF_UNITY); err != nil {
+		panic(err)
+	}
+	for i := k; i < BN254_TWO_ADICITY; i++ {
+		omega.Square(&omega)
+	}
+	return omega
+}
+
+func halo2G1Bytes(p *bn254.G1Affine, format Halo2SerdeFormat) []byte {
+	if format == HALO2_SERDE_RAW_BYTES {
+		return montgomeryLE(p.X, p.Y)
+	}
+
+	if p.IsInfinity() {
+		return make([]byte, BN254_FIELD_ELEMENT_SIZE)
+	}
+	b := canonicalLE(p.X)
+	b[len(b)-1] |= byte(parity(p.Y)) << 7
+	return b
+}
+
+func halo2G2Bytes(p *bn254.G2Affine, format Halo2SerdeFormat) []byte {
+	if format == HALO2_SERDE_RAW_BYTES {
+		return montgomeryLE(p.X.A0, p.X.A1, p.Y.A0, p.Y.A1)
+	}
+
+	if p.IsInfinity() {
+		return make([]byte, 2*BN254_FIELD_ELEMENT_SIZE)
+	}
+	// the sign of y follows the lexicographic order of Fq2: the parity of c0,
+	// or of c1 when c0 is zero
+	sign := parity(p.Y.A0)
+	if p.Y.A0.IsZero() {
+		sign = parity(p.Y.A1)
+	}
+	b := canonicalLE(p.X.A0, p.X.A1)
+	b[len(b)-1] |= byte(sign) << 7
+	return b
+}
+
+// montgomeryLE lays out the limbs of the Montgomery form of the elements in
+// little-endian, the way snarkjs and halo2curves store them raw.
+func montgomeryLE(elements ...fp.Element) []byte {
+	b := make([]byte, 0, len(elements)*BN254_FIELD_ELEMENT_SIZE)
+	for _, e := range elements {
+		for _, limb := range e {
+			b = binary.LittleEndian.AppendUint64(b, limb)
+		}
+	}
+	return b
+}
+
+// canonicalLE lays out the elements as little-endian integers.
+func canonicalLE(elements ...fp.Element) []byte {
+	b := make([]byte, 0, len(elements)*BN254_FIELD_ELEMENT_SIZE)
+	for _, e := range elements {
+		bytes := e.Bytes()
+		b = append(b, reverseSlice(bytes[:])...)
+	}
+	return b
+}
+
+func parity(e fp.Element) uint {
+	var b big.Int
+	e.BigInt(&b)
+	return b.Bit(0)
+}
@@ -0,0 +1,390 @@
+package deserializer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
+	"golang.org/x/crypto/blake2b"
+)
+
+///////////////////////////////////////////////////////////////////
+///                    AZTEC IGNITION TRANSCRIPTS               ///
+///////////////////////////////////////////////////////////////////
+
+// Taken from the AztecProtocol/barretenberg repo, srs/io.hpp
+// https://github.com/AztecProtocol/barretenberg/blob/master/cpp/src/barretenberg/srs/io.hpp
+/*
+transcriptNN.dat
+    manifest, 4 bytes big-endian each
+        transcript_number
+        total_transcripts
+        total_g1_points
+        total_g2_points
+        num_g1_points
+        num_g2_points
+        start_from
+    {num_g1_points}[
+        x^(start_from+i+1)*G1
+    ]
+    {num_g2_points}[
+        x^(i+1)*G2
+    ]
+    64 bytes, Blake2b hash of everything above
+*/
+// The G1 powers are spread across the transcripts in order, starting from
+// x*G1: the generators are not part of the ceremony. Coordinates are 4 limbs
+// of the Montgomery form, least significant first, each limb big-endian. G2
+// coordinates are elements of Fq2, written c0 then c1.
+//
+// Ignition only computed powers of tau, there is nothing to make the
+// alphaTauG1, betaTauG1 and betaG2 sections of a .ph1 from.
+
+const IGNITION_MANIFEST_SIZE = 7 * 4
+
+// IgnitionManifest is the header of a transcript.
+type IgnitionManifest struct {
+	TranscriptNumber uint32
+	TotalTranscripts uint32
+	TotalG1Points    uint32
+	TotalG2Points    uint32
+	NumG1Points      uint32
+	NumG2Points      uint32
+	StartFrom        uint32
+}
+
+// InvalidIgnitionManifest is returned when the manifests of the transcripts
+// don't describe a single, complete ceremony.
+type InvalidIgnitionManifest struct {
+	Path string
+	Err  error
+}
+
+func (r *InvalidIgnitionManifest) Error() string {
+	return fmt.Sprintf("invalid ignition manifest in %s: %v", r.Path, r.Err)
+}
+
+func (r *InvalidIgnitionManifest) Unwrap() error {
+	return r.Err
+}
+
+// NoGroth16Sections is returned when a ceremony only holds powers of tau, which
+// are enough for PLONK but not for the .ph1 of a Groth16 setup.
+type NoGroth16Sections struct {
+	Ceremony string
+}
+
+func (r *NoGroth16Sections) Error() string {
+	return fmt.Sprintf("%s has no alphaTauG1, betaTauG1 and betaG2, it can't seed a Groth16 setup (.ph1), only a KZG SRS for PLONK", r.Ceremony)
+}
+
+// IgnitionTranscripts are the transcripts of an Aztec Ignition ceremony, in
+// the order of their transcript numbers.
+type IgnitionTranscripts struct {
+	Paths     []string
+	Manifests []IgnitionManifest
+}
+
+// InitIgnition reads the manifests of the transcript*.dat files of dir and
+// checks that they make up a whole ceremony. The points are only read later.
+func InitIgnition(dir string) (*IgnitionTranscripts, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "transcript*.dat"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no transcript*.dat in %s", dir)
+	}
+
+	manifests := make([]IgnitionManifest, len(paths))
+	ordered := make([]string, len(paths))
+	for _, path := range paths {
+		manifest, err := readIgnitionManifest(path)
+		if err != nil {
+			return nil, err
+		}
+		if manifest.TotalTranscripts != uint32(len(paths)) {
+			return nil, &InvalidIgnitionManifest{Path: path, Err: fmt.Errorf("%d transcripts expected, %d found", manifest.TotalTranscripts, len(paths))}
+		}
+		if manifest.TranscriptNumber >= manifest.TotalTranscripts || ordered[manifest.TranscriptNumber] != "" {
+			return nil, &InvalidIgnitionManifest{Path: path, Err: fmt.Errorf("unexpected transcript number %d", manifest.TranscriptNumber)}
+		}
+		manifests[manifest.TranscriptNumber] = manifest
+		ordered[manifest.TranscriptNumber] = path
+	}
+
+	var g1Points, g2Points uint32
+	for i, manifest := range manifests {
+		path := ordered[i]
+		if manifest.TotalG1Points != manifests[0].TotalG1Points || manifest.TotalG2Points != manifests[0].TotalG2Points {
+			return nil, &InvalidIgnitionManifest{Path: path, Err: fmt.Errorf("the point totals differ from the ones of %s", ordered[0])}
+		}
+		if manifest.StartFrom != g1Points {
+			return nil, &InvalidIgnitionManifest{Path: path, Err: fmt.Errorf("starts from point %d, but the previous transcripts end at %d", manifest.StartFrom, g1Points)}
+		}
+		g1Points += manifest.NumG1Points
+		g2Points += manifest.NumG2Points
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if info.Size() != manifest.size() {
+			return nil, &InvalidIgnitionManifest{Path: path, Err: fmt.Errorf("the file is %d bytes long, the manifest describes %d", info.Size(), manifest.size())}
+		}
+	}
+	if g1Points != manifests[0].TotalG1Points || g2Points != manifests[0].TotalG2Points {
+		return nil, &InvalidIgnitionManifest{Path: dir, Err: fmt.Errorf("the transcripts hold %d G1 and %d G2 points, %d and %d expected", g1Points, g2Points, manifests[0].TotalG1Points, manifests[0].TotalG2Points)}
+	}
+	if manifests[0].NumG2Points == 0 {
+		return nil, &InvalidIgnitionManifest{Path: ordered[0], Err: fmt.Errorf("the first transcript holds no G2 point")}
+	}
+
+	log().Debug("read ignition manifests", "transcripts", len(manifests), "g1Points", g1Points, "g2Points", g2Points)
+
+	return &IgnitionTranscripts{Paths: ordered, Manifests: manifests}, nil
+}
+
+func readIgnitionManifest(path string) (IgnitionManifest, error) {
+	var manifest IgnitionManifest
+
+	file, err := os.Open(path)
+	if err != nil {
+		return manifest, err
+	}
+	defer file.Close()
+
+	if err := binary.Read(file, binary.BigEndian, &manifest); err != nil {
+		return manifest, &InvalidIgnitionManifest{Path: path, Err: err}
+	}
+	return manifest, nil
+}
+
+// size is the size of the transcript the manifest heads.
+func (manifest IgnitionManifest) size() int64 {
+	return IGNITION_MANIFEST_SIZE +
+		int64(manifest.NumG1Points)*2*BN254_FIELD_ELEMENT_SIZE +
+		int64(manifest.NumG2Points)*4*BN254_FIELD_ELEMENT_SIZE +
+		blake2b.Size
+}
+
+// NumG1Powers is the number of powers of tau in G1, including the generator.
+func (transcripts *IgnitionTranscripts) NumG1Powers() int {
+	return int(transcripts.Manifests[0].TotalG1Points) + 1
+}
+
+// VerifyChecksums checks the Blake2b hash closing every transcript.
+func (transcripts *IgnitionTranscripts) VerifyChecksums() error {
+	for i, path := range transcripts.Paths {
+		log().Debug("verifying checksum", "transcript", path)
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+
+		hasher, err := blake2b.New512(nil)
+		if err != nil {
+			file.Close()
+			return err
+		}
+		reader := bufio.NewReader(file)
+		_, err = io.CopyN(hasher, reader, transcripts.Manifests[i].size()-blake2b.Size)
+		checksum := make([]byte, blake2b.Size)
+		if err == nil {
+			_, err = io.ReadFull(reader, checksum)
+		}
+		file.Close()
+		if err != nil {
+			return err
+		}
+
+		if !bytes.Equal(hasher.Sum(nil), checksum) {
+			return fmt.Errorf("%s does not match its checksum", path)
+		}
+	}
+	return nil
+}
+
+// ReadG1Powers sends the first count powers of tau in G1 to out, starting
+// with the generator, and closes it once they have all been sent, an error
+// occurred or ctx is cancelled.
+func (transcripts *IgnitionTranscripts) ReadG1Powers(ctx context.Context, out chan bn254.G1Affine, count int) error {
+	defer close(out)
+	if count < 1 || count > transcripts.NumG1Powers() {
+		return fmt.Errorf("the transcripts hold %d powers of tau in G1, %d requested", transcripts.NumG1Powers(), count)
+	}
+
+	send := func(p bn254.G1Affine) error {
+		select {
+		case out <- p:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	_, _, g1, _ := bn254.Generators()
+	if err := send(g1); err != nil {
+		return err
+	}
+	sent := 1
+
+	buffer := make([]byte, BN254_FIELD_ELEMENT_SIZE)
+	for i, path := range transcripts.Paths {
+		if sent == count {
+			break
+		}
+		numPoints := int(transcripts.Manifests[i].NumG1Points)
+		if numPoints > count-sent {
+			numPoints = count - sent
+		}
+		log().Debug("reading transcript", "transcript", path, "points", numPoints)
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		reader := bufio.NewReader(io.NewSectionReader(file, IGNITION_MANIFEST_SIZE, transcripts.Manifests[i].size()))
+		for j := 0; j < numPoints; j++ {
+			p, err := readIgnitionG1(reader, buffer)
+			if err == nil {
+				err = send(p)
+			}
+			if err != nil {
+				file.Close()
+				return fmt.Errorf("%s: index %d: %w", path, j, err)
+			}
+		}
+		file.Close()
+		sent += numPoints
+	}
+
+	return nil
+}
+
+// ReadG2Powers reads [1]₂ and [τ]₂.
+func (transcripts *IgnitionTranscripts) ReadG2Powers() ([2]bn254.G2Affine, error) {
+	var g2s [2]bn254.G2Affine
+	_, _, _, g2s[0] = bn254.Generators()
+
+	file, err := os.Open(transcripts.Paths[0])
+	if err != nil {
+		return g2s, err
+	}
+	defer file.Close()
+
+	offset := IGNITION_MANIFEST_SIZE + int64(transcripts.Manifests[0].NumG1Points)*2*BN254_FIELD_ELEMENT_SIZE
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return g2s, err
+	}
+	if g2s[1], err = readIgnitionG2(file, make([]byte, BN254_FIELD_ELEMENT_SIZE)); err != nil {
+		return g2s, fmt.Errorf("%s: tauG2: %w", transcripts.Paths[0], err)
+	}
+	return g2s, nil
+}
+
+// IgnitionToKZGSRS reads the first size powers of tau in G1 of the
+// transcripts, along with [1]₂ and [τ]₂, into gnark's KZG SRS.
+func IgnitionToKZGSRS(transcripts *IgnitionTranscripts, size int) (*kzg.SRS, error) {
+	if size < 2 || size > transcripts.NumG1Powers() {
+		return nil, fmt.Errorf("a KZG SRS of size %d can't be made from the transcripts, the size must be between 2 and %d", size, transcripts.NumG1Powers())
+	}
+
+	var srs kzg.SRS
+	var err error
+	if srs.G2, err = transcripts.ReadG2Powers(); err != nil {
+		return nil, err
+	}
+
+	srs.G1 = make([]bn254.G1Affine, 0, size)
+	points := make(chan bn254.G1Affine, 1024)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- transcripts.ReadG1Powers(context.Background(), points, size)
+	}()
+	for p := range points {
+		srs.G1 = append(srs.G1, p)
+	}
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+
+	return &srs, nil
+}
+
+// IgnitionToLagrangeKZGSRS reads the KZG SRS of the transcripts in Lagrange
+// form, like PtauToLagrangeKZGSRS. size must be a power of two.
+func IgnitionToLagrangeKZGSRS(transcripts *IgnitionTranscripts, size int) (*LagrangeSRS, error) {
+	if size < 2 || size&(size-1) != 0 || size > 1<<BN254_TWO_ADICITY {
+		return nil, fmt.Errorf("a Lagrange KZG SRS of size %d can't be made, the size must be a power of two", size)
+	}
+
+	srs, err := IgnitionToKZGSRS(transcripts, size)
+	if err != nil {
+		return nil, err
+	}
+
+	log().Info("computing the Lagrange form of the SRS", "size", size)
+	return &LagrangeSRS{G1: lagrangeG1(srs.G1, fft.NewDomain(uint64(size)).Generator), G2: srs.G2}, nil
+}
+
+func readIgnitionG1(reader io.Reader, buffer []byte) (bn254.G1Affine, error) {
+	var g1Affine bn254.G1Affine
+	var err error
+
+	if g1Affine.X, err = readIgnitionElement(reader, buffer); err != nil {
+		return g1Affine, err
+	}
+	if g1Affine.Y, err = readIgnitionElement(reader, buffer); err != nil {
+		return g1Affine, err
+	}
+
+	if !g1Affine.IsOnCurve() {
+		return g1Affine, fmt.Errorf("g1Affine is not on curve: X: %v Y: %v", g1Affine.X.String(), g1Affine.Y.String())
+	}
+
+	return g1Affine, nil
+}
+
+func readIgnitionG2(reader io.Reader, buffer []byte) (bn254.G2Affine, error) {
+	var g2Affine bn254.G2Affine
+	var err error
+
+	for _, e := range []*fp.Element{&g2Affine.X.A0, &g2Affine.X.A1, &g2Affine.Y.A0, &g2Affine.Y.A1} {
+		if *e, err = readIgnitionElement(reader, buffer); err != nil {
+			return g2Affine, err
+		}
+	}
+
+	if !g2Affine.IsOnCurve() {
+		return g2Affine, fmt.Errorf("g2Affine is not on curve: X: %v Y: %v", g2Affine.X.String(), g2Affine.Y.String())
+	}
+
+	return g2Affine, nil
+}
+
+// readIgnitionElement reads the Montgomery form of a base field element,
+// stored as little-endian limbs that are each big-endian.
+func readIgnitionElement(reader io.Reader, buffer []byte) (fp.Element, error) {
+	var z fp.Element
+
+	if _, err := io.ReadFull(reader, buffer[:BN254_FIELD_ELEMENT_SIZE]); err != nil {
+		return z, err
+	}
+
+	z[0] = binary.BigEndian.Uint64(buffer[0:8])
+	z[1] = binary.BigEndian.Uint64(buffer[8:16])
+	z[2] = binary.BigEndian.Uint64(buffer[16:24])
+	z[3] = binary.BigEndian.Uint64(buffer[24:32])
+
+	return z, nil
+}
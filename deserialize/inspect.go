@@ -0,0 +1,379 @@
+package deserializer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+///////////////////////////////////////////////////////////////////
+///                           INSPECT                           ///
+///////////////////////////////////////////////////////////////////
+
+// ptau, zkey, r1cs and wtns files all share the iden3 binary file layout:
+// 4 bytes of magic, 4 bytes of version, then a table of sections each made of
+// 4 bytes of id and 8 bytes of size followed by the section data. A .ph1 file
+// has no magic, it starts with its 3 byte Header.
+
+// FileInfo summarises a ptau, zkey, r1cs, wtns or ph1 file.
+type FileInfo struct {
+	// Type is one of ptau, zkey, r1cs, wtns or ph1
+	Type     string        `json:"type"`
+	Size     int64         `json:"size"`
+	Version  uint32        `json:"version,omitempty"`
+	Sections []SectionInfo `json:"sections,omitempty"`
+	Ptau     *PtauInfo     `json:"ptau,omitempty"`
+	Zkey     *ZkeyInfo     `json:"zkey,omitempty"`
+	R1CS     *R1CSInfo     `json:"r1cs,omitempty"`
+	Wtns     *WtnsInfo     `json:"wtns,omitempty"`
+	Phase1   *Phase1Info   `json:"ph1,omitempty"`
+}
+
+type SectionInfo struct {
+	ID uint32 `json:"id"`
+	// Offset of the section data, right after its id and size
+	Offset uint64 `json:"offset"`
+	Size   uint64 `json:"size"`
+}
+
+type PtauInfo struct {
+	// Curve is the name of the curve identified from the prime, "unknown" if
+	// it matches none
+	Curve         string             `json:"curve"`
+	N8            uint32             `json:"n8"`
+	Prime         string             `json:"prime"`
+	Power         uint32             `json:"power"`
+	CeremonyPower uint32             `json:"ceremonyPower"`
+	Contributions []ContributionInfo `json:"contributions"`
+}
+
+type ContributionInfo struct {
+	Index            int    `json:"index"`
+	Type             string `json:"type"`
+	Name             string `json:"name,omitempty"`
+	NumIterationsExp uint8  `json:"numIterationsExp,omitempty"`
+	BeaconHash       string `json:"beaconHash,omitempty"`
+	NextChallenge    string `json:"nextChallenge"`
+}
+
+type ZkeyInfo struct {
+	ProtocolID uint32 `json:"protocolId"`
+	Protocol   string `json:"protocol"`
+	// header fields, only set for Groth16, PLONK and FFLONK zkeys
+	Curve      string `json:"curve,omitempty"`
+	N8q        uint32 `json:"n8q,omitempty"`
+	Q          string `json:"q,omitempty"`
+	N8r        uint32 `json:"n8r,omitempty"`
+	R          string `json:"r,omitempty"`
+	NVars      uint32 `json:"nVars,omitempty"`
+	NPublic    uint32 `json:"nPublic,omitempty"`
+	DomainSize uint32 `json:"domainSize,omitempty"`
+	Power      uint32 `json:"power,omitempty"`
+	// PLONK and FFLONK only
+	NAdditions   uint32 `json:"nAdditions,omitempty"`
+	NConstraints uint32 `json:"nConstraints,omitempty"`
+}
+
+type R1CSInfo struct {
+	N8           uint32 `json:"n8"`
+	Prime        string `json:"prime"`
+	NWires       uint32 `json:"nWires"`
+	NPubOut      uint32 `json:"nPubOut"`
+	NPubIn       uint32 `json:"nPubIn"`
+	NPrvIn       uint32 `json:"nPrvIn"`
+	NLabels      uint64 `json:"nLabels"`
+	NConstraints uint32 `json:"nConstraints"`
+}
+
+type WtnsInfo struct {
+	N8       uint32 `json:"n8"`
+	Prime    string `json:"prime"`
+	NWitness uint32 `json:"nWitness"`
+}
+
+type Phase1Info struct {
+	Power         byte   `json:"power"`
+	Contributions uint16 `json:"contributions"`
+	// TrailingBytes is the number of bytes found after the BetaG2 point
+	TrailingBytes int64 `json:"trailingBytes"`
+}
+
+// Inspect detects the type of the file at path from its magic and reads the
+// metadata it holds, without loading any of its points.
+func Inspect(path string) (*FileInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	info := &FileInfo{Size: stat.Size()}
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(file, magic); err != nil {
+		return nil, fmt.Errorf("%s is too small to be a ptau, zkey, r1cs, wtns or ph1 file", path)
+	}
+
+	switch string(magic) {
+	case ptauMagic, "zkey", "r1cs", "wtns":
+		info.Type = string(magic)
+	default:
+		info.Type = "ph1"
+		info.Phase1, err = inspectPhase1(file, info.Size)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return info, nil
+	}
+
+	info.Version, info.Sections, err = readSectionTable(file, info.Size)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	// all of the formats keep their header in section 1
+	header, err := openSection(file, info.Sections, 1)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	switch info.Type {
+	case ptauMagic:
+		info.Ptau, err = inspectPtau(file, header, info.Sections)
+	case "zkey":
+		info.Zkey, err = inspectZkey(file, header, info.Sections)
+	case "r1cs":
+		info.R1CS, err = inspectR1CS(header)
+	case "wtns":
+		info.Wtns, err = inspectWtns(header)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return info, nil
+}
+
+// readSectionTable reads the version and the section table of an iden3 binary
+// file, the reader being positioned right after the magic.
+func readSectionTable(reader io.ReadSeeker, fileSize int64) (uint32, []SectionInfo, error) {
+	version, err := readULE32(reader)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	numSections, err := readULE32(reader)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	sections := make([]SectionInfo, 0, numSections)
+	for i := uint32(0); i < numSections; i++ {
+		id, err := readULE32(reader)
+		if err != nil {
+			return 0, nil, err
+		}
+		size, err := readULE64(reader)
+		if err != nil {
+			return 0, nil, err
+		}
+		pos, err := reader.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, nil, err
+		}
+		if size > uint64(fileSize-pos) {
+			return 0, nil, fmt.Errorf("section %d of size %d goes past the end of the file", id, size)
+		}
+		sections = append(sections, SectionInfo{ID: id, Offset: uint64(pos), Size: size})
+		if _, err := reader.Seek(int64(size), io.SeekCurrent); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	return version, sections, nil
+}
+
+// openSection returns a reader over the data of the unique section id.
+func openSection(reader io.ReaderAt, sections []SectionInfo, id uint32) (io.Reader, error) {
+	var found *SectionInfo
+	for i := range sections {
+		if sections[i].ID == id {
+			if found != nil {
+				return nil, fmt.Errorf("section %d has more than one segment", id)
+			}
+			found = &sections[i]
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("section %d is missing", id)
+	}
+
+	return bufio.NewReader(io.NewSectionReader(reader, int64(found.Offset), int64(found.Size))), nil
+}
+
+func inspectPtau(file *os.File, header io.Reader, sections []SectionInfo) (*PtauInfo, error) {
+	ptauHeader, err := readPtauHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &PtauInfo{Curve: ptauHeader.Curve().String(), N8: ptauHeader.N8, Prime: ptauHeader.Prime.String(), Power: ptauHeader.Power}
+
+	// older files stop right after the power
+	if info.CeremonyPower, err = readULE32(header); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	contributionsSection, err := openSection(file, sections, 7)
+	if err != nil {
+		return nil, err
+	}
+	contributions, err := readContributions(contributionsSection)
+	if err != nil {
+		return nil, err
+	}
+
+	info.Contributions = make([]ContributionInfo, len(contributions))
+	for i, c := range contributions {
+		info.Contributions[i] = ContributionInfo{
+			Index:            i + 1,
+			Type:             "contribution",
+			Name:             c.Name,
+			NumIterationsExp: c.NumIterationsExp,
+			BeaconHash:       fmt.Sprintf("%x", c.BeaconHash),
+			NextChallenge:    fmt.Sprintf("%x", c.NextChallenge),
+		}
+		if c.Type == CONTRIBUTION_TYPE_BEACON {
+			info.Contributions[i].Type = "beacon"
+		}
+	}
+
+	return info, nil
+}
+
+func inspectZkey(file *os.File, header io.Reader, sections []SectionInfo) (*ZkeyInfo, error) {
+	protocolID, err := readULE32(header)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &ZkeyInfo{ProtocolID: protocolID, Protocol: "unknown"}
+	switch protocolID {
+	case GROTH_16_PROTOCOL_ID:
+		info.Protocol = "groth16"
+	case PLONK_PROTOCOL_ID:
+		info.Protocol = "plonk"
+	case FFLONK_PROTOCOL_ID:
+		info.Protocol = "fflonk"
+	default:
+		return info, nil
+	}
+
+	// the three protocols start their header (section 2) with the same fields
+	protocolSection, err := openSection(file, sections, 2)
+	if err != nil {
+		return nil, err
+	}
+	headerGroth, err := readHeaderGroth16(protocolSection)
+	if err != nil {
+		return nil, err
+	}
+	if protocolID != GROTH_16_PROTOCOL_ID {
+		if info.NAdditions, err = readULE32(protocolSection); err != nil {
+			return nil, err
+		}
+		if info.NConstraints, err = readULE32(protocolSection); err != nil {
+			return nil, err
+		}
+	}
+
+	info.Curve = headerGroth.Curve().String()
+	info.N8q = headerGroth.N8q
+	info.Q = headerGroth.Q.String()
+	info.N8r = headerGroth.N8r
+	info.R = headerGroth.R.String()
+	info.NVars = headerGroth.NVars
+	info.NPublic = headerGroth.NPublic
+	info.DomainSize = headerGroth.DomainSize
+	info.Power = headerGroth.Power
+
+	return info, nil
+}
+
+func inspectR1CS(header io.Reader) (*R1CSInfo, error) {
+	var info R1CSInfo
+	var err error
+
+	if info.N8, err = readULE32(header); err != nil {
+		return nil, err
+	}
+	prime, err := readBigInt(header, info.N8)
+	if err != nil {
+		return nil, err
+	}
+	info.Prime = prime.String()
+
+	for _, field := range []*uint32{&info.NWires, &info.NPubOut, &info.NPubIn, &info.NPrvIn} {
+		if *field, err = readULE32(header); err != nil {
+			return nil, err
+		}
+	}
+	if info.NLabels, err = readULE64(header); err != nil {
+		return nil, err
+	}
+	if info.NConstraints, err = readULE32(header); err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+func inspectWtns(header io.Reader) (*WtnsInfo, error) {
+	var info WtnsInfo
+	var err error
+
+	if info.N8, err = readULE32(header); err != nil {
+		return nil, err
+	}
+	prime, err := readBigInt(header, info.N8)
+	if err != nil {
+		return nil, err
+	}
+	info.Prime = prime.String()
+
+	if info.NWitness, err = readULE32(header); err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+func inspectPhase1(file *os.File, fileSize int64) (*Phase1Info, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var header Header
+	if err := header.ReadFrom(file); err != nil {
+		return nil, err
+	}
+
+	// anything bigger than the bn254 two-adicity can't be a .ph1
+	if header.Power > BN254_TWO_ADICITY {
+		return nil, fmt.Errorf("unknown file type")
+	}
+
+	layout := newPhase1Layout(header.Power)
+	if fileSize < layout.end {
+		return nil, fmt.Errorf("unknown file type, or a .ph1 of power %d truncated to %d bytes out of %d", header.Power, fileSize, layout.end)
+	}
+
+	return &Phase1Info{Power: header.Power, Contributions: header.Contributions, TrailingBytes: fileSize - layout.end}, nil
+}
@@ -0,0 +1,226 @@
+package deserializer
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/chacha20"
+)
+
+///////////////////////////////////////////////////////////////////
+///                    CONTRIBUTION KEY PAIRS                   ///
+///////////////////////////////////////////////////////////////////
+
+// Taken from the iden3/snarkjs repo, keypair.js (createPTauKey), and from
+// iden3/ffjavascript, chacha.js and the fromRng functions of the fields and
+// curves. snarkjs draws the secrets of a contribution and its public key from
+// a ChaCha20 generator, and derives the G2 points of the proof of knowledge
+// from the challenge hash with another one:
+/*
+tau, alpha, beta = Fr.fromRng(rng) x3
+for tau (personalization 0), alpha (1), beta (2), with secret x
+    g1_s   = G1.fromRng(rng)
+    g1_sx  = x*g1_s
+    g2_sp  = G2.fromRng(ChaCha(blake2b(personalization || challengeHash || g1_s || g1_sx)))
+    g2_spx = x*g2_sp
+*/
+// The points are hashed uncompressed. Seeds are the first 32 bytes of a hash,
+// read as 8 big-endian words. Field elements are drawn as 4 64-bit limbs of
+// their Montgomery form, masked to the size of the modulus and redrawn until
+// they are below it. Points are drawn as x and a bit telling whether y is the
+// lexicographically largest root, until x is on the curve, then G2 points
+// are multiplied by the cofactor.
+
+// G2_COFACTOR clears the cofactor of the twist of bn254, 2p - r.
+const G2_COFACTOR = "0x30644e72e131a029b85045b68181585e06ceecda572a2489345f2299c0f9fa8d"
+
+// PtauSecrets are the toxic waste of a contribution.
+type PtauSecrets struct {
+	Tau   fr.Element
+	Alpha fr.Element
+	Beta  fr.Element
+}
+
+// snarkjsRng reproduces the ChaCha20 generator of ffjavascript: the seed
+// words are the key, the counter spans the last 4 words of the state.
+type snarkjsRng struct {
+	cipher *chacha20.Cipher
+	block  [64]byte
+	idx    int
+}
+
+// newSnarkjsRng seeds a generator with the first 32 bytes of hash.
+func newSnarkjsRng(hash []byte) *snarkjsRng {
+	key := make([]byte, chacha20.KeySize)
+	for i := 0; i < 8; i++ {
+		binary.LittleEndian.PutUint32(key[4*i:], binary.BigEndian.Uint32(hash[4*i:]))
+	}
+	cipher, err := chacha20.NewUnauthenticatedCipher(key, make([]byte, chacha20.NonceSize))
+	if err != nil {
+		panic(err)
+	}
+	return &snarkjsRng{cipher: cipher, idx: len(snarkjsRng{}.block)}
+}
+
+func (rng *snarkjsRng) nextU32() uint32 {
+	if rng.idx == len(rng.block) {
+		rng.block = [64]byte{}
+		rng.cipher.XORKeyStream(rng.block[:], rng.block[:])
+		rng.idx = 0
+	}
+	word := binary.LittleEndian.Uint32(rng.block[rng.idx:])
+	rng.idx += 4
+	return word
+}
+
+func (rng *snarkjsRng) nextU64() uint64 {
+	high := rng.nextU32()
+	return uint64(high)<<32 | uint64(rng.nextU32())
+}
+
+func (rng *snarkjsRng) nextBool() bool {
+	return rng.nextU32()&1 == 1
+}
+
+// limbsFromRng draws the Montgomery form of an element of the field of
+// modulus q.
+func (rng *snarkjsRng) limbsFromRng(q *big.Int) [4]uint64 {
+	topMask := uint64(1)<<(q.BitLen()-192) - 1
+	var limbs [4]uint64
+	var v big.Int
+	for {
+		for i := range limbs {
+			limbs[i] = rng.nextU64()
+		}
+		limbs[3] &= topMask
+		if v.SetBits(littleEndianWords(limbs)).Cmp(q) < 0 {
+			return limbs
+		}
+	}
+}
+
+func (rng *snarkjsRng) frFromRng() fr.Element {
+	return fr.Element(rng.limbsFromRng(fr.Modulus()))
+}
+
+func (rng *snarkjsRng) fpFromRng() fp.Element {
+	return fp.Element(rng.limbsFromRng(fp.Modulus()))
+}
+
+func (rng *snarkjsRng) g1FromRng() bn254.G1Affine {
+	var p bn254.G1Affine
+	var b, y2 fp.Element
+	b.SetUint64(3)
+	for {
+		p.X = rng.fpFromRng()
+		greatest := rng.nextBool()
+		y2.Square(&p.X).Mul(&y2, &p.X).Add(&y2, &b)
+		if y2.Legendre() == -1 {
+			continue
+		}
+		p.Y.Sqrt(&y2)
+		if p.Y.LexicographicallyLargest() != greatest {
+			p.Y.Neg(&p.Y)
+		}
+		return p
+	}
+}
+
+func (rng *snarkjsRng) g2FromRng() bn254.G2Affine {
+	var p bn254.G2Affine
+
+	// b of the twist, 3/(9+u)
+	b := p.X
+	b.A0.SetUint64(9)
+	b.A1.SetOne()
+	b.Inverse(&b)
+	var three fp.Element
+	three.SetUint64(3)
+	b.MulByElement(&b, &three)
+
+	y2 := p.Y
+	for {
+		p.X.A0 = rng.fpFromRng()
+		p.X.A1 = rng.fpFromRng()
+		greatest := rng.nextBool()
+		y2.Square(&p.X).Mul(&y2, &p.X).Add(&y2, &b)
+		if y2.Legendre() == -1 {
+			continue
+		}
+		p.Y.Sqrt(&y2)
+		if p.Y.LexicographicallyLargest() != greatest {
+			p.Y.Neg(&p.Y)
+		}
+		break
+	}
+
+	// the point is not in the subgroup yet, so gnark's GLV multiplication
+	// can't be used to clear the cofactor
+	var cofactor big.Int
+	cofactor.SetString(G2_COFACTOR, 0)
+	var base, res bn254.G2Jac
+	base.FromAffine(&p)
+	for i := cofactor.BitLen() - 1; i >= 0; i-- {
+		res.DoubleAssign()
+		if cofactor.Bit(i) == 1 {
+			res.AddAssign(&base)
+		}
+	}
+	p.FromJacobian(&res)
+	return p
+}
+
+// newPtauKey draws the secrets of a contribution to the challenge of hash
+// challengeHash, and the public key proving knowledge of them.
+func newPtauKey(rng *snarkjsRng, challengeHash [BELLMAN_HASH_SIZE]byte) (PtauSecrets, PtauPublicKey) {
+	var secrets PtauSecrets
+	var key PtauPublicKey
+
+	secrets.Tau = rng.frFromRng()
+	secrets.Alpha = rng.frFromRng()
+	secrets.Beta = rng.frFromRng()
+
+	key.TauG1S, key.TauG1SX, key.TauG2SPX = proofOfKnowledge(rng, 0, challengeHash, &secrets.Tau)
+	key.AlphaG1S, key.AlphaG1SX, key.AlphaG2SPX = proofOfKnowledge(rng, 1, challengeHash, &secrets.Alpha)
+	key.BetaG1S, key.BetaG1SX, key.BetaG2SPX = proofOfKnowledge(rng, 2, challengeHash, &secrets.Beta)
+
+	return secrets, key
+}
+
+func proofOfKnowledge(rng *snarkjsRng, personalization byte, challengeHash [BELLMAN_HASH_SIZE]byte, secret *fr.Element) (g1S, g1SX bn254.G1Affine, g2SPX bn254.G2Affine) {
+	var x big.Int
+	secret.BigInt(&x)
+
+	g1S = rng.g1FromRng()
+	g1SX.ScalarMultiplication(&g1S, &x)
+	g2SP := g2SP(personalization, challengeHash, &g1S, &g1SX)
+	g2SPX.ScalarMultiplication(&g2SP, &x)
+	return g1S, g1SX, g2SPX
+}
+
+// g2SP derives the G2 point a proof of knowledge is checked against.
+func g2SP(personalization byte, challengeHash [BELLMAN_HASH_SIZE]byte, g1S, g1SX *bn254.G1Affine) bn254.G2Affine {
+	hasher, _ := blake2b.New512(nil)
+	hasher.Write([]byte{personalization})
+	hasher.Write(challengeHash[:])
+	hasher.Write(bellmanG1Bytes(g1S))
+	hasher.Write(bellmanG1Bytes(g1SX))
+	return newSnarkjsRng(hasher.Sum(nil)).g2FromRng()
+}
+
+// littleEndianWords converts limbs to the words of a big.Int.
+func littleEndianWords(limbs [4]uint64) []big.Word {
+	words := make([]big.Word, 0, 4*64/bitsPerWord)
+	for _, limb := range limbs {
+		for shift := 0; shift < 64; shift += bitsPerWord {
+			words = append(words, big.Word(limb>>shift))
+		}
+	}
+	return words
+}
+
+const bitsPerWord = 32 << (^uint(0) >> 63)
@@ -0,0 +1,214 @@
+package deserializer
+
+import (
+	"bufio"
+	"fmt"
+	"math/big"
+	"math/bits"
+	"runtime"
+	"sync"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
+)
+
+///////////////////////////////////////////////////////////////////
+///                           KZG SRS                           ///
+///////////////////////////////////////////////////////////////////
+
+// The KZG SRS gnark PLONK commits with is made of the powers of tau in G1,
+// [1]₂ and [τ]₂: the beginning of the tauG1 (2) and tauG2 (3) sections of the
+// ptau, nothing else has to be converted.
+
+// PtauToKZGSRS reads the first size powers of tau in G1 of the ptau, along
+// with [1]₂ and [τ]₂, into gnark's KZG SRS.
+func PtauToKZGSRS(ptauFile *PtauFile, size int) (*kzg.SRS, error) {
+	if maxSize := ptauFile.DomainSize()*2 - 1; size < 2 || size > maxSize {
+		return nil, fmt.Errorf("a KZG SRS of size %d can't be made from a ptau of power %d, the size must be between 2 and %d", size, ptauFile.Header.Power, maxSize)
+	}
+
+	var srs kzg.SRS
+	buffer := make([]byte, BN254_FIELD_ELEMENT_SIZE)
+
+	seekToUniqueSection(ptauFile.Reader, ptauFile.Sections, 2)
+	log().Debug("reading section", "section", "tauG1", "points", size)
+	reader := bufio.NewReader(ptauFile.Reader)
+	srs.G1 = make([]bn254.G1Affine, size)
+	for i := range srs.G1 {
+		var err error
+		if srs.G1[i], err = readG1Affine(reader, buffer); err != nil {
+			return nil, fmt.Errorf("tauG1: index %d: %w", i, err)
+		}
+	}
+
+	seekToUniqueSection(ptauFile.Reader, ptauFile.Sections, 3)
+	log().Debug("reading section", "section", "tauG2", "points", 2)
+	reader = bufio.NewReader(ptauFile.Reader)
+	for i := range srs.G2 {
+		var err error
+		if srs.G2[i], err = readG2Affine(reader, buffer); err != nil {
+			return nil, fmt.Errorf("tauG2: index %d: %w", i, err)
+		}
+	}
+
+	return &srs, nil
+}
+
+// LagrangeSRS is a KZG SRS in Lagrange form: G1 holds [Lᵢ(τ)]₁ for the
+// Lagrange polynomials Lᵢ of a domain, G2 holds [1]₂ and [τ]₂. It is kept
+// apart from kzg.SRS on purpose. gnark-crypto v0.9.1 has no serialization
+// for a Lagrange SRS, and one written in the kzg.SRS layout is loaded by
+// gnark v0.8 as a canonical SRS, which it silently builds wrong PLONK keys
+// from. So a LagrangeSRS can only be committed with, not written.
+type LagrangeSRS struct {
+	G1 []bn254.G1Affine
+	G2 [2]bn254.G2Affine
+}
+
+// Commit commits to the polynomial given by its evaluations on the domain of
+// the SRS, without an FFT.
+func (srs *LagrangeSRS) Commit(evaluations []fr.Element) (kzg.Digest, error) {
+	return kzg.Commit(evaluations, &kzg.SRS{G1: srs.G1, G2: srs.G2})
+}
+
+// PtauToLagrangeKZGSRS reads the KZG SRS of the ptau in Lagrange form, over
+// the domain of the given size, which must be a power of two no larger than
+// 2^power.
+func PtauToLagrangeKZGSRS(ptauFile *PtauFile, size int) (*LagrangeSRS, error) {
+	if size < 2 || size&(size-1) != 0 || size > ptauFile.DomainSize() {
+		return nil, fmt.Errorf("a Lagrange KZG SRS of size %d can't be made from a ptau of power %d, the size must be a power of two between 2 and %d", size, ptauFile.Header.Power, ptauFile.DomainSize())
+	}
+
+	srs, err := PtauToKZGSRS(ptauFile, size)
+	if err != nil {
+		return nil, err
+	}
+
+	log().Info("computing the Lagrange form of the SRS", "size", size)
+	return &LagrangeSRS{G1: lagrangeG1(srs.G1, fft.NewDomain(uint64(size)).Generator), G2: srs.G2}, nil
+}
+
+// WriteKZGSRS writes the serialization of srs to outputPath, replacing it
+// only once the whole SRS is on disk. The layout is the one of kzg.SRS.WriteTo
+// in gnark-crypto v0.9.1, the version go.mod pins and gnark v0.8.0 builds on:
+// G2[0], G2[1], then the length of G1 and its points, all compressed. Later
+// gnark-crypto versions split the SRS into a proving and a verifying key,
+// serialized differently, and can't read these files. A LagrangeSRS can't be
+// written, see its doc.
+func WriteKZGSRS(srs *kzg.SRS, outputPath string) error {
+	outputFile, err := createAtomicFile(outputPath)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(outputFile)
+	if _, err := srs.WriteTo(writer); err != nil {
+		outputFile.Abort()
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		outputFile.Abort()
+		return err
+	}
+
+	return outputFile.Commit()
+}
+
+// lagrangeG1 turns [τⁱ]₁ for i < n into [Lᵢ(τ)]₁ for the domain of size n
+// generated by the n-th root of unity ω. Lᵢ(X) = 1/n ∑ⱼ ω⁻ⁱʲ Xʲ, so this is
+// an inverse FFT carried out in G1.
+func lagrangeG1(powers []bn254.G1Affine, omega fr.Element) []bn254.G1Affine {
+	points := make([]bn254.G1Jac, len(powers))
+	for i := range powers {
+		points[i].FromAffine(&powers[i])
+	}
+
+	var omegaInv, nInv fr.Element
+	omegaInv.Inverse(&omega)
+	nInv.SetUint64(uint64(len(points))).Inverse(&nInv)
+	fftJacobian(points, omegaInv)
+	scaleJacobian(points, nInv)
+
+	return bn254.BatchJacobianToAffineG1(points)
+}
+
+// jacobian is implemented by the Jacobian points of G1 and G2.
+type jacobian[J any] interface {
+	*J
+	Set(a *J) *J
+	ScalarMultiplication(a *J, s *big.Int) *J
+	AddAssign(a *J) *J
+	SubAssign(a *J) *J
+}
+
+// fftJacobian replaces points with ∑ⱼ ωⁱʲ points[j] in place, for the n-th
+// root of unity ω, n being the number of points.
+func fftJacobian[J any, PJ jacobian[J]](points []J, omega fr.Element) {
+	n := len(points)
+
+	// bit reversal, then the butterflies of a decimation in time FFT
+	shift := 64 - bits.TrailingZeros(uint(n))
+	for i := range points {
+		if j := int(bits.Reverse64(uint64(i)) >> shift); i < j {
+			points[i], points[j] = points[j], points[i]
+		}
+	}
+
+	twiddles := make([]big.Int, n/2)
+	var w fr.Element
+	w.SetOne()
+	for i := range twiddles {
+		w.BigInt(&twiddles[i])
+		w.Mul(&w, &omega)
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		half := size / 2
+		stride := n / size
+		parallelize(n/2, func(start, end int) {
+			var t J
+			for b := start; b < end; b++ {
+				// butterfly b combines points k and k+half of its block
+				k := (b/half)*size + b%half
+				PJ(&t).Set(&points[k+half])
+				if j := (b % half) * stride; j != 0 {
+					PJ(&t).ScalarMultiplication(&t, &twiddles[j])
+				}
+				PJ(&points[k+half]).Set(&points[k])
+				PJ(&points[k+half]).SubAssign(&t)
+				PJ(&points[k]).AddAssign(&t)
+			}
+		})
+	}
+}
+
+// scaleJacobian multiplies points by s.
+func scaleJacobian[J any, PJ jacobian[J]](points []J, s fr.Element) {
+	var scalar big.Int
+	s.BigInt(&scalar)
+	parallelize(len(points), func(start, end int) {
+		for i := start; i < end; i++ {
+			PJ(&points[i]).ScalarMultiplication(&points[i], &scalar)
+		}
+	})
+}
+
+// parallelize splits [0, n) into one chunk per CPU and runs work on each.
+func parallelize(n int, work func(start, end int)) {
+	chunks := runtime.NumCPU()
+	if chunks > n {
+		chunks = n
+	}
+
+	var wg sync.WaitGroup
+	for c := 0; c < chunks; c++ {
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			work(start, end)
+		}(c*n/chunks, (c+1)*n/chunks)
+	}
+	wg.Wait()
+}
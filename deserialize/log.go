@@ -0,0 +1,36 @@
+package deserializer
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+// The package never writes to stdout or stderr by itself. Its diagnostics go
+// through a log/slog logger that discards everything until SetLogger is called.
+
+var logger atomic.Pointer[slog.Logger]
+
+func init() {
+	logger.Store(slog.New(discardHandler{}))
+}
+
+// SetLogger sends the diagnostics of the package to l. Passing nil silences
+// the package again.
+func SetLogger(l *slog.Logger) {
+	if l == nil {
+		l = slog.New(discardHandler{})
+	}
+	logger.Store(l)
+}
+
+func log() *slog.Logger {
+	return logger.Load()
+}
+
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
@@ -2,9 +2,17 @@ package deserializer
 
 import (
 	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash"
+	"io"
+	"io/fs"
 	"math"
 	"os"
+	"path/filepath"
 
 	"github.com/consensys/gnark-crypto/ecc/bn254"
 	curve "github.com/consensys/gnark-crypto/ecc/bn254"
@@ -16,6 +24,82 @@ type Phase1 struct {
 	betaTauG1  []curve.G1Affine
 	tauG2      []curve.G2Affine
 	betaG2     curve.G2Affine
+	// contributions is the number of contributions of the ptau
+	contributions uint16
+}
+
+// phase1Layout holds the byte offset of every section of a .ph1 file. Points
+// are written compressed, so each one has a fixed size and the offsets only
+// depend on the power.
+type phase1Layout struct {
+	tauG1      int64
+	alphaTauG1 int64
+	betaTauG1  int64
+	tauG2      int64
+	betaG2     int64
+	end        int64
+}
+
+func newPhase1Layout(power byte) phase1Layout {
+	N := int64(1) << power
+
+	var layout phase1Layout
+	layout.tauG1 = 3
+	layout.alphaTauG1 = layout.tauG1 + (2*N-1)*curve.SizeOfG1AffineCompressed
+	layout.betaTauG1 = layout.alphaTauG1 + N*curve.SizeOfG1AffineCompressed
+	layout.tauG2 = layout.betaTauG1 + N*curve.SizeOfG1AffineCompressed
+	layout.betaG2 = layout.tauG2 + N*curve.SizeOfG2AffineCompressed
+	layout.end = layout.betaG2 + curve.SizeOfG2AffineCompressed
+	return layout
+}
+
+// offsets returns the section offsets in the order of phase1Sections.
+func (layout phase1Layout) offsets() []int64 {
+	return []int64{layout.tauG1, layout.alphaTauG1, layout.betaTauG1, layout.tauG2, layout.betaG2}
+}
+
+type phase1Section struct {
+	name          string
+	ptauSectionId uint32
+	isG2          bool
+}
+
+// phase1Sections lists the .ph1 sections in the order they are written, along
+// with the ptau section each one is copied from.
+var phase1Sections = []phase1Section{
+	{name: "TauG1", ptauSectionId: 2},
+	{name: "AlphaTauG1", ptauSectionId: 4},
+	{name: "BetaTauG1", ptauSectionId: 5},
+	{name: "TauG2", ptauSectionId: 3, isG2: true},
+	{name: "BetaG2", ptauSectionId: 6, isG2: true},
+}
+
+// numPoints returns how many points of the section a .ph1 of domain size N holds.
+func (section phase1Section) numPoints(N int) int {
+	switch section.ptauSectionId {
+	case 2:
+		return 2*N - 1
+	case 6:
+		return 1
+	default:
+		return N
+	}
+}
+
+// pointSize is the size of a compressed point of the section in the .ph1.
+func (section phase1Section) pointSize() int64 {
+	if section.isG2 {
+		return curve.SizeOfG2AffineCompressed
+	}
+	return curve.SizeOfG1AffineCompressed
+}
+
+// ptauPointSize is the size of an uncompressed point of the section in the ptau.
+func (section phase1Section) ptauPointSize() int64 {
+	if section.isG2 {
+		return 4 * BN254_FIELD_ELEMENT_SIZE
+	}
+	return 2 * BN254_FIELD_ELEMENT_SIZE
 }
 
 func ConvertPtauToPhase1(ptau Ptau) (phase1 Phase1, err error) {
@@ -30,7 +114,7 @@ func ConvertPtauToPhase1(ptau Ptau) (phase1 Phase1, err error) {
 		// fmt.Printf("Y: %v \n", g1Affine.Y.String())
 		// fmt.Printf("g1Affine: %v \n", g1Affine)
 		if !g1Affine.IsOnCurve() {
-			fmt.Printf("tauG1: \n index: %v g1Affine.X: %v \n g1Affine.Y: %v \n", i, g1Affine.X.String(), g1Affine.Y.String())
+			log().Error("point is not on curve", "section", "tauG1", "index", i, "x", g1Affine.X.String(), "y", g1Affine.Y.String())
 			panic("g1Affine is not on curve")
 		}
 		tauG1[i] = g1Affine
@@ -44,7 +128,7 @@ func ConvertPtauToPhase1(ptau Ptau) (phase1 Phase1, err error) {
 		y := bytesToElement(g1[1].Bytes())
 		g1Affine.Y = y
 		if !g1Affine.IsOnCurve() {
-			fmt.Printf("alphaTauG1: \n index: %v g1Affine.X: %v \n g1Affine.Y: %v \n", i, g1Affine.X.String(), g1Affine.Y.String())
+			log().Error("point is not on curve", "section", "alphaTauG1", "index", i, "x", g1Affine.X.String(), "y", g1Affine.Y.String())
 			panic("g1Affine is not on curve")
 		}
 		alphaTauG1[i] = g1Affine
@@ -60,7 +144,7 @@ func ConvertPtauToPhase1(ptau Ptau) (phase1 Phase1, err error) {
 		y := bytesToElement(g1[1].Bytes())
 		g1Affine.Y = y
 		if !g1Affine.IsOnCurve() {
-			fmt.Printf("betaTauG1: \n index: %v, g1Affine.X: %v \n g1Affine.Y: %v \n", i, g1Affine.X.String(), g1Affine.Y.String())
+			log().Error("point is not on curve", "section", "betaTauG1", "index", i, "x", g1Affine.X.String(), "y", g1Affine.Y.String())
 			panic("g1Affine is not on curve")
 		}
 		betaTauG1[i] = g1Affine
@@ -85,7 +169,7 @@ func ConvertPtauToPhase1(ptau Ptau) (phase1 Phase1, err error) {
 		// fmt.Printf("Y: %v \n", g2Affine.Y.String())
 		// fmt.Printf("g2Affine %v: %v \n", i, g2Affine)
 		if !g2Affine.IsOnCurve() {
-			fmt.Printf("tauG2: \n index: %v, g2Affine.X.A0: %v \n g2Affine.X.A1: %v \n g2Affine.Y.A0: %v \n g2Affine.Y.A1 %v \n", i, g2Affine.X.A0.String(), g2Affine.X.A1.String(), g2Affine.Y.A0.String(), g2Affine.Y.A1.String())
+			log().Error("point is not on curve", "section", "tauG2", "index", i, "x", g2Affine.X.String(), "y", g2Affine.Y.String())
 			panic("g2Affine is not on curve")
 		}
 		tauG2[i] = g2Affine
@@ -107,18 +191,233 @@ func ConvertPtauToPhase1(ptau Ptau) (phase1 Phase1, err error) {
 		betaG2.Y.A1 = y1
 
 		if !betaG2.IsOnCurve() {
-			fmt.Printf("g2Affine.X.A0: %v \n g2Affine.X.A1: %v \n g2Affine.Y.A0: %v \n g2Affine.Y.A1 %v \n", betaG2.X.A0.String(), betaG2.X.String(), betaG2.Y.A0.String(), betaG2.Y.A1.String())
+			log().Error("point is not on curve", "section", "betaG2", "x", betaG2.X.String(), "y", betaG2.Y.String())
 			panic("g2Affine is not on curve")
 		}
 	}
 
 	//fmt.Printf("BetaG2: %v \n", BetaG2)
 
-	return Phase1{tauG1: tauG1, tauG2: tauG2, alphaTauG1: alphaTauG1, betaTauG1: betaTauG1, betaG2: betaG2}, nil
+	contributions, err := phase1ContributionCount(ptau.NumContributions)
+	if err != nil {
+		return Phase1{}, err
+	}
+
+	return Phase1{tauG1: tauG1, tauG2: tauG2, alphaTauG1: alphaTauG1, betaTauG1: betaTauG1, betaG2: betaG2, contributions: contributions}, nil
+}
+
+// WritePhase1FromPtauFile converts ptauFile into the .ph1 format. It stops
+// when ctx is cancelled, in which case the conversion can be picked up again
+// with ResumePhase1FromPtauFile.
+func WritePhase1FromPtauFile(ctx context.Context, ptauFile *PtauFile, outputPath string, opts ...ConvertOption) error {
+	return writePhase1FromSource(ctx, ptauFile, outputPath, opts...)
+}
+
+// ResumePhase1FromPtauFile continues a WritePhase1FromPtauFile conversion that
+// was interrupted, starting from the last checkpoint recorded next to
+// outputPath. The already-written prefix of the .ph1 is checked against the
+// checkpoint before anything is appended to it.
+func ResumePhase1FromPtauFile(ctx context.Context, ptauFile *PtauFile, outputPath string, opts ...ConvertOption) error {
+	return resumePhase1FromSource(ctx, ptauFile, outputPath, opts...)
+}
+
+// WritePhase1FromBellmanFile converts a bellman challenge or response into the
+// .ph1 format, like WritePhase1FromPtauFile.
+func WritePhase1FromBellmanFile(ctx context.Context, bellmanFile *BellmanFile, outputPath string, opts ...ConvertOption) error {
+	return writePhase1FromSource(ctx, bellmanFile, outputPath, opts...)
+}
+
+// ResumePhase1FromBellmanFile continues an interrupted
+// WritePhase1FromBellmanFile conversion, like ResumePhase1FromPtauFile.
+func ResumePhase1FromBellmanFile(ctx context.Context, bellmanFile *BellmanFile, outputPath string, opts ...ConvertOption) error {
+	return resumePhase1FromSource(ctx, bellmanFile, outputPath, opts...)
+}
+
+// phase1Source is a file the .ph1 sections can be copied from: a ptau or a
+// bellman challenge or response.
+type phase1Source interface {
+	phase1Power() uint32
+	// phase1SectionReader returns a reader positioned on point i of section
+	phase1SectionReader(section phase1Section, i int) (io.Reader, error)
+	// phase1PointSize is the size of a point of section in the source
+	phase1PointSize(section phase1Section) int64
+	// readPhase1G1 and readPhase1G2 read a point, buffer holds at least 128 bytes
+	readPhase1G1(reader io.Reader, buffer []byte) (bn254.G1Affine, error)
+	readPhase1G2(reader io.Reader, buffer []byte) (bn254.G2Affine, error)
+	// phase1Contributions is the number of contributions in the .ph1 header
+	phase1Contributions() (uint16, error)
+}
+
+func (ptauFile *PtauFile) phase1Power() uint32 {
+	return ptauFile.Header.Power
+}
+
+func (ptauFile *PtauFile) phase1SectionReader(section phase1Section, i int) (io.Reader, error) {
+	seekToUniqueSection(ptauFile.Reader, ptauFile.Sections, section.ptauSectionId)
+	if _, err := ptauFile.Reader.Seek(int64(i)*section.ptauPointSize(), io.SeekCurrent); err != nil {
+		return nil, err
+	}
+	return ptauFile.Reader, nil
+}
+
+func (ptauFile *PtauFile) phase1PointSize(section phase1Section) int64 {
+	return section.ptauPointSize()
+}
+
+func (ptauFile *PtauFile) phase1Contributions() (uint16, error) {
+	if len(ptauFile.Sections) <= 7 || len(ptauFile.Sections[7]) == 0 {
+		return 0, fmt.Errorf("ptau has no contributions section")
+	}
+	if _, err := ptauFile.Reader.Seek(int64(ptauFile.Sections[7][0].pos), io.SeekStart); err != nil {
+		return 0, err
+	}
+	numContributions, err := readULE32(ptauFile.Reader)
+	if err != nil {
+		return 0, err
+	}
+	return phase1ContributionCount(numContributions)
+}
+
+// phase1ContributionCount checks that the number of contributions of a ptau
+// fits in a .ph1 header.
+func phase1ContributionCount(numContributions uint32) (uint16, error) {
+	if numContributions > math.MaxUint16 {
+		return 0, fmt.Errorf("%d contributions don't fit in a ph1 header", numContributions)
+	}
+	return uint16(numContributions), nil
+}
+
+func (ptauFile *PtauFile) readPhase1G1(reader io.Reader, buffer []byte) (bn254.G1Affine, error) {
+	return readG1Affine(reader, buffer)
+}
+
+func (ptauFile *PtauFile) readPhase1G2(reader io.Reader, buffer []byte) (bn254.G2Affine, error) {
+	return readG2Affine(reader, buffer)
+}
+
+// Phase1File is an opened .ph1 file, whose points are read from disk as
+// they are needed.
+type Phase1File struct {
+	Header Header
+	Reader *os.File
+}
+
+// InitPhase1 opens the .ph1 at path and reads its header.
+func InitPhase1(path string) (*Phase1File, error) {
+	reader, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := reader.Stat()
+	if err != nil {
+		reader.Close()
+		return nil, err
+	}
+
+	info, err := inspectPhase1(reader, stat.Size())
+	if err != nil {
+		reader.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return &Phase1File{Header: Header{Power: info.Power, Contributions: info.Contributions}, Reader: reader}, nil
+}
+
+func (phase1File *Phase1File) Close() error {
+	return phase1File.Reader.Close()
+}
+
+func (phase1File *Phase1File) phase1Power() uint32 {
+	return uint32(phase1File.Header.Power)
+}
+
+func (phase1File *Phase1File) phase1SectionReader(section phase1Section, i int) (io.Reader, error) {
+	layout := newPhase1Layout(phase1File.Header.Power)
+	for j, s := range phase1Sections {
+		if s.name != section.name {
+			continue
+		}
+		if _, err := phase1File.Reader.Seek(layout.offsets()[j]+int64(i)*section.pointSize(), io.SeekStart); err != nil {
+			return nil, err
+		}
+		return phase1File.Reader, nil
+	}
+	return nil, fmt.Errorf("unknown .ph1 section %s", section.name)
+}
+
+func (phase1File *Phase1File) phase1PointSize(section phase1Section) int64 {
+	return section.pointSize()
+}
+
+func (phase1File *Phase1File) phase1Contributions() (uint16, error) {
+	return phase1File.Header.Contributions, nil
+}
+
+func (phase1File *Phase1File) readPhase1G1(reader io.Reader, buffer []byte) (bn254.G1Affine, error) {
+	var point bn254.G1Affine
+	if _, err := io.ReadFull(reader, buffer[:curve.SizeOfG1AffineCompressed]); err != nil {
+		return point, err
+	}
+	return point, decodeCompressed(buffer[:curve.SizeOfG1AffineCompressed], &point)
+}
+
+func (phase1File *Phase1File) readPhase1G2(reader io.Reader, buffer []byte) (bn254.G2Affine, error) {
+	var point bn254.G2Affine
+	if _, err := io.ReadFull(reader, buffer[:curve.SizeOfG2AffineCompressed]); err != nil {
+		return point, err
+	}
+	return point, decodeCompressed(buffer[:curve.SizeOfG2AffineCompressed], &point)
+}
+
+func (bellmanFile *BellmanFile) phase1Power() uint32 {
+	return bellmanFile.Power
 }
 
-func WritePhase1FromPtauFile(ptauFile *PtauFile, outputPath string) error {
-	outputFile, err := os.Create(outputPath)
+func (bellmanFile *BellmanFile) phase1SectionReader(section phase1Section, i int) (io.Reader, error) {
+	offset := bellmanFile.sectionOffset(section.ptauSectionId) + int64(i)*bellmanFile.phase1PointSize(section)
+	if _, err := bellmanFile.Reader.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return bellmanFile.Reader, nil
+}
+
+func (bellmanFile *BellmanFile) phase1PointSize(section phase1Section) int64 {
+	if section.isG2 {
+		return bellmanFile.g2Size()
+	}
+	return bellmanFile.g1Size()
+}
+
+// phase1Contributions is 0, bellman files don't record how many contributions
+// they went through.
+func (bellmanFile *BellmanFile) phase1Contributions() (uint16, error) {
+	return 0, nil
+}
+
+func (bellmanFile *BellmanFile) readPhase1G1(reader io.Reader, buffer []byte) (bn254.G1Affine, error) {
+	return readBellmanG1(reader, buffer, bellmanFile.Compressed)
+}
+
+func (bellmanFile *BellmanFile) readPhase1G2(reader io.Reader, buffer []byte) (bn254.G2Affine, error) {
+	return readBellmanG2(reader, buffer, bellmanFile.Compressed)
+}
+
+func writePhase1FromSource(ctx context.Context, source phase1Source, outputPath string, opts ...ConvertOption) error {
+	// starting over would leave the partial file of an interrupted conversion
+	// behind and overwrite the checkpoint that resumes it
+	checkpointPath := phase1CheckpointPath(outputPath)
+	if _, err := os.Stat(checkpointPath); err == nil {
+		partial := "its partial file"
+		if checkpoint, err := readPhase1Checkpoint(checkpointPath); err == nil {
+			partial = filepath.Join(filepath.Dir(outputPath), checkpoint.Partial)
+		}
+		return fmt.Errorf("a conversion to %s was interrupted, resume it, or delete %s and %s to start over", outputPath, checkpointPath, partial)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	// the partial file is kept on failure so that the conversion can be resumed
+	outputFile, err := createAtomicFile(outputPath)
 	if err != nil {
 		return err
 	}
@@ -126,101 +425,226 @@ func WritePhase1FromPtauFile(ptauFile *PtauFile, outputPath string) error {
 
 	var header Header
 
-	writer := bufio.NewWriter(outputFile)
-	defer writer.Flush()
+	hasher := sha256.New()
+	writer := bufio.NewWriter(io.MultiWriter(outputFile, hasher))
 
-	N := ptauFile.DomainSize()
+	power := source.phase1Power()
 
-	fmt.Printf("Power %d supports up to %d constraints\n", ptauFile.Header.Power, N)
+	log().Info("converting to ph1", "power", power, "constraints", 1<<power)
 
-	header.Power = byte(ptauFile.Header.Power)
-
-	// can be extracted from ptau.Contributions (7) but hardcoding for now
-	// ptau link: https://github.com/iden3/snarkjs/tree/master#7-prepare-phase-2
-	header.Contributions = 54
+	header.Power = byte(power)
+	header.Contributions, err = source.phase1Contributions()
+	if err != nil {
+		outputFile.Abort()
+		return err
+	}
 
 	// Write the header
-	err = header.writeTo(outputFile)
+	err = header.writeTo(writer)
 	if err != nil {
+		outputFile.Abort()
 		return err
 	}
 
-	// BN254 encoder using compressed representation of points to save storage space
-	enc := bn254.NewEncoder(writer)
-	fmt.Println("1. Writing TauG1")
-	tauG1 := make(chan curve.G1Affine, 10000)
-	go ptauFile.ReadTauG1(tauG1)
-	for point := range tauG1 {
-		if err := enc.Encode(&point); err != nil {
-			return err
-		}
+	checkpoint := phase1Checkpoint{Power: power, Partial: outputFile.partialName()}
+	return writePhase1Sections(ctx, source, outputFile, writer, hasher, checkpoint, newConvertOptions(opts))
+}
+
+func resumePhase1FromSource(ctx context.Context, source phase1Source, outputPath string, opts ...ConvertOption) error {
+	checkpoint, err := readPhase1Checkpoint(phase1CheckpointPath(outputPath))
+	if err != nil {
+		return err
 	}
 
-	// Write α[τ⁰]₁, α[τ¹]₁, α[τ²]₁, …, α[τᴺ⁻¹]₁
-	fmt.Println("2. Writing AlphaTauG1")
-	alphaTauG1 := make(chan curve.G1Affine, 10000)
-	go ptauFile.ReadAlphaTauG1(alphaTauG1)
-	for point := range alphaTauG1 {
-		if err := enc.Encode(&point); err != nil {
+	if checkpoint.Power != source.phase1Power() {
+		return fmt.Errorf("checkpoint was recorded for power %d, but the input has power %d", checkpoint.Power, source.phase1Power())
+	}
+
+	outputFile, err := openAtomicFile(outputPath, checkpoint.Partial)
+	if err != nil {
+		return err
+	}
+	defer outputFile.Close()
+
+	// Check the prefix before appending to it, anything written after the
+	// checkpoint is discarded and written again
+	hasher := sha256.New()
+	if _, err := io.CopyN(hasher, outputFile, checkpoint.Offset); err != nil {
+		return fmt.Errorf("reading the already-written prefix: %w", err)
+	}
+	if hex.EncodeToString(hasher.Sum(nil)) != checkpoint.Hash {
+		outputFile.Abort()
+		os.Remove(phase1CheckpointPath(outputPath))
+		return fmt.Errorf("%s does not match its checkpoint, the conversion has to be restarted", outputFile.Name())
+	}
+	if err := outputFile.Truncate(checkpoint.Offset); err != nil {
+		return err
+	}
+	if _, err := outputFile.Seek(checkpoint.Offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	log().Info("resuming conversion", "output", outputPath, "section", checkpoint.Section+1, "points", checkpoint.Points)
+
+	writer := bufio.NewWriter(io.MultiWriter(outputFile, hasher))
+	return writePhase1Sections(ctx, source, outputFile, writer, hasher, checkpoint, newConvertOptions(opts))
+}
+
+// writePhase1Sections copies the sections of source into the .ph1 output, starting
+// at the position held by checkpoint and recording a new checkpoint every
+// phase1CheckpointInterval points. When ctx is cancelled, a last checkpoint is
+// recorded before returning.
+func writePhase1Sections(ctx context.Context, source phase1Source, outputFile *atomicFile, writer *bufio.Writer, hasher hash.Hash, checkpoint phase1Checkpoint, options convertOptions) error {
+	N := 1 << source.phase1Power()
+	tracker := newProgressTracker(options.progress)
+	offsets := newPhase1Layout(byte(source.phase1Power())).offsets()
+	checkpointPath := phase1CheckpointPath(outputFile.path)
+
+	// invalid input fails the same way once resumed, the partial file and its
+	// checkpoint are only kept after interruptions and I/O errors
+	resumable := true
+	defer func() {
+		if !resumable {
+			outputFile.Abort()
+			os.Remove(checkpointPath)
+		}
+	}()
+
+	save := func() error {
+		if err := writer.Flush(); err != nil {
 			return err
 		}
+		// the points have to be on disk before the checkpoint refers to them
+		if err := outputFile.Sync(); err != nil {
+			return err
+		}
+		checkpoint.Offset = offsets[checkpoint.Section] + int64(checkpoint.Points)*phase1Sections[checkpoint.Section].pointSize()
+		checkpoint.Hash = hex.EncodeToString(hasher.Sum(nil))
+		if err := writePhase1Checkpoint(checkpointPath, checkpoint); err != nil {
+			return err
+		}
+		if phase1CheckpointHook != nil {
+			return phase1CheckpointHook(checkpoint)
+		}
+		return nil
 	}
 
-	// Write β[τ⁰]₁, β[τ¹]₁, β[τ²]₁, …, β[τᴺ⁻¹]₁
-	fmt.Println("3. Writing BetaTauG1")
-	betaTauG1 := make(chan curve.G1Affine, 10000)
-	go ptauFile.ReadBetaTauG1(betaTauG1)
-	for point := range betaTauG1 {
-		if err := enc.Encode(&point); err != nil {
+	// BN254 encoder using compressed representation of points to save storage space
+	enc := bn254.NewEncoder(writer)
+	buffer := make([]byte, 4*BN254_FIELD_ELEMENT_SIZE)
+
+	for ; checkpoint.Section < len(phase1Sections); checkpoint.Section, checkpoint.Points = checkpoint.Section+1, 0 {
+		section := phase1Sections[checkpoint.Section]
+		numPoints := section.numPoints(N)
+
+		log().Info("writing section", "section", section.name, "points", numPoints)
+
+		if err := save(); err != nil {
 			return err
 		}
-	}
 
-	// Write {[τ⁰]₂, [τ¹]₂, [τ²]₂, …, [τᴺ⁻¹]₂}
-	fmt.Println("4. Writing TauG2")
-	tauG2 := make(chan curve.G2Affine, 10000)
-	go ptauFile.ReadTauG2(tauG2)
-	for point := range tauG2 {
-		if err := enc.Encode(&point); err != nil {
+		// Seek to the first point that has not been written yet
+		sectionReader, err := source.phase1SectionReader(section, checkpoint.Points)
+		if err != nil {
 			return err
 		}
+		reader := bufio.NewReader(sectionReader)
+
+		for checkpoint.Points < numPoints {
+			if checkpoint.Points%pointsPerUpdate == 0 {
+				if err := ctx.Err(); err != nil {
+					if err := save(); err != nil {
+						return err
+					}
+					return err
+				}
+				tracker.report(section.name, checkpoint.Points, numPoints)
+			}
+
+			if section.isG2 {
+				point, err := source.readPhase1G2(reader, buffer)
+				if err != nil {
+					resumable = isIOError(err)
+					return fmt.Errorf("%s point %d: %w", section.name, checkpoint.Points, err)
+				}
+				if err := enc.Encode(&point); err != nil {
+					return err
+				}
+			} else {
+				point, err := source.readPhase1G1(reader, buffer)
+				if err != nil {
+					resumable = isIOError(err)
+					return fmt.Errorf("%s point %d: %w", section.name, checkpoint.Points, err)
+				}
+				if err := enc.Encode(&point); err != nil {
+					return err
+				}
+			}
+			checkpoint.Points++
+			tracker.read(source.phase1PointSize(section))
+
+			if checkpoint.Points%phase1CheckpointInterval == 0 && checkpoint.Points < numPoints {
+				if err := save(); err != nil {
+					return err
+				}
+			}
+		}
+		tracker.report(section.name, numPoints, numPoints)
 	}
 
-	// Write [β]₂
-	fmt.Println("5. Writing BetaG2")
-	betaG2, err := ptauFile.ReadBetaG2()
-	if err != nil {
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if err := outputFile.Commit(); err != nil {
+		return err
+	}
+
+	// the conversion is complete, the checkpoint is not needed anymore
+	if err := os.Remove(checkpointPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
 		return err
 	}
-	enc.Encode(&betaG2)
+
 	return nil
 }
 
+// isIOError reports whether err comes from reading or writing a file, rather
+// than from its content.
+func isIOError(err error) bool {
+	var pathErr *fs.PathError
+	return errors.As(err, &pathErr)
+}
+
 func WritePhase1(phase1 Phase1, power byte, outputPath string) error {
 	// output outputFile
-	outputFile, err := os.Create(outputPath)
+	outputFile, err := createAtomicFile(outputPath)
 	if err != nil {
 		return err
 	}
-	defer outputFile.Close()
 
+	if err := writePhase1(phase1, power, outputFile); err != nil {
+		outputFile.Abort()
+		return err
+	}
+
+	return outputFile.Commit()
+}
+
+func writePhase1(phase1 Phase1, power byte, outputFile io.Writer) error {
 	var header Header
 
 	writer := bufio.NewWriter(outputFile)
-	defer writer.Flush()
 
 	N := int(math.Pow(2, float64(power)))
 
-	fmt.Printf("Power %d supports up to %d constraints\n", power, N)
+	log().Info("writing ph1", "power", power, "constraints", N)
 
 	header.Power = power
-
-	// can be extracted from ptau.Contributions (7) but hardcoding for now
-	// ptau link: https://github.com/iden3/snarkjs/tree/master#7-prepare-phase-2
-	header.Contributions = 54
+	header.Contributions = phase1.contributions
 
 	// Write the header
-	header.writeTo(outputFile)
+	if err := header.writeTo(writer); err != nil {
+		return err
+	}
 
 	// BN254 encoder using compressed representation of points to save storage space
 	enc := bn254.NewEncoder(writer)
@@ -228,7 +652,7 @@ func WritePhase1(phase1 Phase1, power byte, outputPath string) error {
 	// Taken from https://github.com/worldcoin/semaphore-mtb-setup/blob/main/phase1/phase1.go
 	// In the initialization, τ = α = β = 1, so we are writing the generators directly
 	// Write [τ⁰]₁, [τ¹]₁, [τ²]₁, …, [τ²ᴺ⁻²]₁
-	fmt.Println("1. Writing TauG1")
+	log().Info("writing section", "section", "TauG1", "points", 2*N-1)
 	for i := 0; i < 2*N-1; i++ {
 		if err := enc.Encode(&phase1.tauG1[i]); err != nil {
 			return err
@@ -236,7 +660,7 @@ func WritePhase1(phase1 Phase1, power byte, outputPath string) error {
 	}
 
 	// Write α[τ⁰]₁, α[τ¹]₁, α[τ²]₁, …, α[τᴺ⁻¹]₁
-	fmt.Println("2. Writing AlphaTauG1")
+	log().Info("writing section", "section", "AlphaTauG1", "points", N)
 	for i := 0; i < N; i++ {
 		if err := enc.Encode(&phase1.alphaTauG1[i]); err != nil {
 			return err
@@ -244,7 +668,7 @@ func WritePhase1(phase1 Phase1, power byte, outputPath string) error {
 	}
 
 	// Write β[τ⁰]₁, β[τ¹]₁, β[τ²]₁, …, β[τᴺ⁻¹]₁
-	fmt.Println("3. Writing BetaTauG1")
+	log().Info("writing section", "section", "BetaTauG1", "points", N)
 	for i := 0; i < N; i++ {
 		if err := enc.Encode(&phase1.betaTauG1[i]); err != nil {
 			return err
@@ -252,7 +676,7 @@ func WritePhase1(phase1 Phase1, power byte, outputPath string) error {
 	}
 
 	// Write {[τ⁰]₂, [τ¹]₂, [τ²]₂, …, [τᴺ⁻¹]₂}
-	fmt.Println("4. Writing TauG2")
+	log().Info("writing section", "section", "TauG2", "points", N)
 	for i := 0; i < N; i++ {
 		if err := enc.Encode(&phase1.tauG2[i]); err != nil {
 			return err
@@ -260,8 +684,10 @@ func WritePhase1(phase1 Phase1, power byte, outputPath string) error {
 	}
 
 	// Write [β]₂
-	fmt.Println("5. Writing BetaG2")
-	enc.Encode(&phase1.betaG2)
+	log().Info("writing section", "section", "BetaG2", "points", 1)
+	if err := enc.Encode(&phase1.betaG2); err != nil {
+		return err
+	}
 
-	return nil
+	return writer.Flush()
 }
@@ -0,0 +1,167 @@
+package deserializer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// fanOutChunkSize is the number of points read from the ptau and compressed at
+// once.
+var fanOutChunkSize = 1 << 16
+
+// WritePhase1sFromPtauFile converts ptauFile into one .ph1 per power of
+// outputPaths, reading the ptau once: every point is compressed once, then
+// written to all of the outputs whose section holds it. Only the points of the
+// largest power are read. Such conversions can't be resumed.
+func WritePhase1sFromPtauFile(ctx context.Context, ptauFile *PtauFile, outputPaths map[uint32]string, opts ...ConvertOption) error {
+	options := newConvertOptions(opts)
+
+	powers := make([]uint32, 0, len(outputPaths))
+	for power := range outputPaths {
+		if power > ptauFile.Header.Power {
+			return fmt.Errorf("can't write a .ph1 of power %d from a ptau of power %d", power, ptauFile.Header.Power)
+		}
+		powers = append(powers, power)
+	}
+	if len(powers) == 0 {
+		return fmt.Errorf("no power to convert to")
+	}
+	sort.Slice(powers, func(i, j int) bool { return powers[i] < powers[j] })
+
+	contributions, err := ptauFile.phase1Contributions()
+	if err != nil {
+		return err
+	}
+
+	log().Info("converting to ph1", "powers", powers)
+
+	outputFiles := make([]*atomicFile, 0, len(powers))
+	defer func() {
+		// the outputs are only left behind once all of them are committed
+		for _, outputFile := range outputFiles {
+			outputFile.Abort()
+		}
+	}()
+	for _, power := range powers {
+		outputFile, err := createAtomicFile(outputPaths[power])
+		if err != nil {
+			return err
+		}
+		outputFiles = append(outputFiles, outputFile)
+
+		header := Header{Power: byte(power), Contributions: contributions}
+		if err := header.writeTo(outputFile); err != nil {
+			return err
+		}
+	}
+
+	// go through the ptau sections in the order they are stored
+	order := make([]int, len(phase1Sections))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return ptauFile.Sections[phase1Sections[order[i]].ptauSectionId][0].pos < ptauFile.Sections[phase1Sections[order[j]].ptauSectionId][0].pos
+	})
+
+	tracker := newProgressTracker(options.progress)
+	for _, s := range order {
+		if err := fanOutPhase1Section(ctx, ptauFile, s, powers, outputFiles, tracker); err != nil {
+			return err
+		}
+	}
+
+	for len(outputFiles) > 0 {
+		if err := outputFiles[0].Commit(); err != nil {
+			return err
+		}
+		outputFiles = outputFiles[1:]
+	}
+	return nil
+}
+
+// fanOutPhase1Section writes the points of phase1Sections[s] to the outputs,
+// the i-th of which has the i-th power of powers, a chunk at a time. The
+// points of a chunk are read, then compressed in parallel.
+func fanOutPhase1Section(ctx context.Context, ptauFile *PtauFile, s int, powers []uint32, outputFiles []*atomicFile, tracker *progressTracker) error {
+	section := phase1Sections[s]
+	count := section.numPoints(1 << powers[len(powers)-1])
+
+	log().Info("writing section", "section", section.name, "points", count, "outputs", len(outputFiles))
+
+	writers := make([]*bufio.Writer, len(outputFiles))
+	for i, outputFile := range outputFiles {
+		offset := newPhase1Layout(byte(powers[i])).offsets()[s]
+		if _, err := outputFile.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+		writers[i] = bufio.NewWriter(outputFile)
+	}
+
+	reader, err := ptauFile.phase1SectionReader(section, 0)
+	if err != nil {
+		return err
+	}
+	ptauSize, size := int(section.ptauPointSize()), int(section.pointSize())
+	chunk := make([]byte, fanOutChunkSize*ptauSize)
+	compressed := make([]byte, fanOutChunkSize*size)
+	errs := make([]error, fanOutChunkSize)
+
+	for start := 0; start < count; start += fanOutChunkSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		tracker.report(section.name, start, count)
+
+		n := min(fanOutChunkSize, count-start)
+		if _, err := io.ReadFull(reader, chunk[:n*ptauSize]); err != nil {
+			return fmt.Errorf("%s point %d: %w", section.name, start, err)
+		}
+		tracker.read(int64(n * ptauSize))
+
+		parallelize(n, func(first, end int) {
+			reader := bytes.NewReader(chunk[first*ptauSize : end*ptauSize])
+			buffer := make([]byte, ptauSize)
+			for i := first; i < end; i++ {
+				if section.isG2 {
+					point, err := ptauFile.readPhase1G2(reader, buffer)
+					b := point.Bytes()
+					copy(compressed[i*size:], b[:])
+					errs[i] = err
+					continue
+				}
+				point, err := ptauFile.readPhase1G1(reader, buffer)
+				b := point.Bytes()
+				copy(compressed[i*size:], b[:])
+				errs[i] = err
+			}
+		})
+		for i, err := range errs[:n] {
+			if err != nil {
+				return fmt.Errorf("%s point %d: %w", section.name, start+i, err)
+			}
+		}
+
+		for i, writer := range writers {
+			m := min(n, section.numPoints(1<<powers[i])-start)
+			if m <= 0 {
+				continue
+			}
+			if _, err := writer.Write(compressed[:m*size]); err != nil {
+				return err
+			}
+		}
+	}
+	tracker.report(section.name, count, count)
+
+	for _, writer := range writers {
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,355 @@
+package deserializer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+///////////////////////////////////////////////////////////////////
+///                         PH1 VERIFY                          ///
+///////////////////////////////////////////////////////////////////
+
+// Without the ptau it was converted from, a .ph1 can still be checked to hold
+// the powers of a single tau, like snarkjs powersoftau verify checks the
+// sections of a ptau:
+/*
+e(tauG1[i+1], g2)      == e(tauG1[i], tauG2[1])
+e(alphaTauG1[i+1], g2) == e(alphaTauG1[i], tauG2[1])
+e(betaTauG1[i+1], g2)  == e(betaTauG1[i], tauG2[1])
+e(g1, tauG2[i+1])      == e(tauG1[1], tauG2[i])
+e(betaTauG1[0], g2)    == e(g1, betaG2)
+*/
+// Each relation is checked once per section, on random linear combinations
+// of its points: with r_i the powers of a random rho, sum(r_i*p[i]) and
+// sum(r_i*p[i+1]) are computed by multi-exponentiations, then paired.
+
+// InvalidPhase1 is returned when the points of a .ph1 are not the powers of
+// a single tau.
+type InvalidPhase1 struct {
+	Section string
+	Reason  string
+}
+
+func (r *InvalidPhase1) Error() string {
+	return fmt.Sprintf("invalid .ph1, %s: %s", r.Section, r.Reason)
+}
+
+// verifyChunkSize is the number of points read and combined at once.
+var verifyChunkSize = 1 << 16
+
+// Verify checks that the points of phase1 are the powers of a single tau.
+func (phase1 *Phase1) Verify() error {
+	verifier, err := newPhase1Verifier()
+	if err != nil {
+		return err
+	}
+
+	for _, sums := range []struct {
+		sums   *shiftedSumsG1
+		points []bn254.G1Affine
+	}{
+		{&verifier.tauG1, phase1.tauG1},
+		{&verifier.alphaTauG1, phase1.alphaTauG1},
+		{&verifier.betaTauG1, phase1.betaTauG1},
+	} {
+		if err := sums.sums.add(sums.points); err != nil {
+			return err
+		}
+	}
+	if err := verifier.tauG2.add(phase1.tauG2); err != nil {
+		return err
+	}
+	verifier.betaG2 = phase1.betaG2
+
+	return verifier.verify()
+}
+
+// Verify checks that the points of the .ph1 are the powers of a single tau,
+// like Phase1.Verify, reading them a chunk at a time.
+func (phase1File *Phase1File) Verify(ctx context.Context) error {
+	verifier, err := newPhase1Verifier()
+	if err != nil {
+		return err
+	}
+
+	N := 1 << phase1File.Header.Power
+	g1s := make([]bn254.G1Affine, verifyChunkSize)
+	g2s := make([]bn254.G2Affine, verifyChunkSize)
+	errs := make([]error, verifyChunkSize)
+
+	for _, section := range phase1Sections {
+		count := section.numPoints(N)
+		log().Info("verifying section", "section", section.name, "points", count)
+
+		reader, err := phase1File.phase1SectionReader(section, 0)
+		if err != nil {
+			return err
+		}
+		size := int(section.pointSize())
+		chunk := make([]byte, verifyChunkSize*size)
+
+		for start := 0; start < count; start += verifyChunkSize {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			n := min(verifyChunkSize, count-start)
+			if _, err := io.ReadFull(reader, chunk[:n*size]); err != nil {
+				return fmt.Errorf("%s point %d: %w", section.name, start, err)
+			}
+
+			parallelize(n, func(first, end int) {
+				reader := bytes.NewReader(chunk[first*size : end*size])
+				buffer := make([]byte, size)
+				for i := first; i < end; i++ {
+					if section.isG2 {
+						g2s[i], errs[i] = phase1File.readPhase1G2(reader, buffer)
+					} else {
+						g1s[i], errs[i] = phase1File.readPhase1G1(reader, buffer)
+					}
+				}
+			})
+			for i, err := range errs[:n] {
+				if err != nil {
+					return &InvalidPhase1{Section: section.name, Reason: fmt.Sprintf("point %d: %v", start+i, err)}
+				}
+			}
+
+			switch section.ptauSectionId {
+			case 2:
+				err = verifier.tauG1.add(g1s[:n])
+			case 4:
+				err = verifier.alphaTauG1.add(g1s[:n])
+			case 5:
+				err = verifier.betaTauG1.add(g1s[:n])
+			case 3:
+				err = verifier.tauG2.add(g2s[:n])
+			case 6:
+				verifier.betaG2 = g2s[0]
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := verifier.verify(); err != nil {
+		return err
+	}
+	log().Info("the .ph1 is valid", "power", phase1File.Header.Power)
+	return nil
+}
+
+// phase1Verifier holds the random linear combinations of the sections of a
+// .ph1, and the points the relations between them use.
+type phase1Verifier struct {
+	tauG1, alphaTauG1, betaTauG1 shiftedSumsG1
+	tauG2                        shiftedSumsG2
+	betaG2                       bn254.G2Affine
+}
+
+func newPhase1Verifier() (*phase1Verifier, error) {
+	var verifier phase1Verifier
+	for _, sums := range []struct {
+		sums    *shiftedSumsG1
+		section string
+	}{
+		{&verifier.tauG1, "TauG1"},
+		{&verifier.alphaTauG1, "AlphaTauG1"},
+		{&verifier.betaTauG1, "BetaTauG1"},
+	} {
+		if err := sums.sums.init(sums.section); err != nil {
+			return nil, err
+		}
+	}
+	if err := verifier.tauG2.init("TauG2"); err != nil {
+		return nil, err
+	}
+	return &verifier, nil
+}
+
+// verify checks the relations between the sections, once all of their points
+// were added.
+func (verifier *phase1Verifier) verify() error {
+	_, _, g1, g2 := bn254.Generators()
+
+	if len(verifier.tauG2.head) < 2 {
+		return &InvalidPhase1{Section: "TauG2", Reason: "a .ph1 of power 0 holds no power of tau in G2"}
+	}
+	if !verifier.tauG1.head[0].Equal(&g1) {
+		return &InvalidPhase1{Section: "TauG1", Reason: "the first point is not the generator"}
+	}
+	if !verifier.tauG2.head[0].Equal(&g2) {
+		return &InvalidPhase1{Section: "TauG2", Reason: "the first point is not the generator"}
+	}
+	for _, sums := range []*shiftedSumsG1{&verifier.tauG1, &verifier.alphaTauG1, &verifier.betaTauG1} {
+		if sums.head[len(sums.head)-1].IsInfinity() {
+			return &InvalidPhase1{Section: sums.section, Reason: "a secret is zero"}
+		}
+	}
+	if verifier.betaG2.IsInfinity() || !verifier.betaG2.IsInSubGroup() {
+		return &InvalidPhase1{Section: "BetaG2", Reason: "the point is not in the subgroup"}
+	}
+
+	tauG1, tauG2 := verifier.tauG1.head[1], verifier.tauG2.head[1]
+	for _, sums := range []*shiftedSumsG1{&verifier.tauG1, &verifier.alphaTauG1, &verifier.betaTauG1} {
+		var lhs, rhs bn254.G1Affine
+		lhs.FromJacobian(&sums.lhs)
+		rhs.FromJacobian(&sums.rhs)
+		if ok, err := samePairing(&rhs, &g2, &lhs, &tauG2); err != nil || !ok {
+			return &InvalidPhase1{Section: sums.section, Reason: "the points are not successive powers of tau"}
+		}
+	}
+
+	var lhs, rhs bn254.G2Affine
+	lhs.FromJacobian(&verifier.tauG2.lhs)
+	rhs.FromJacobian(&verifier.tauG2.rhs)
+	if ok, err := samePairing(&g1, &rhs, &tauG1, &lhs); err != nil || !ok {
+		return &InvalidPhase1{Section: "TauG2", Reason: "the points are not successive powers of tau"}
+	}
+
+	if ok, err := samePairing(&verifier.betaTauG1.head[0], &g2, &g1, &verifier.betaG2); err != nil || !ok {
+		return &InvalidPhase1{Section: "BetaG2", Reason: "beta differs from the one of BetaTauG1"}
+	}
+
+	return nil
+}
+
+// samePairing reports whether e(a1, a2) == e(b1, b2).
+func samePairing(a1 *bn254.G1Affine, a2 *bn254.G2Affine, b1 *bn254.G1Affine, b2 *bn254.G2Affine) (bool, error) {
+	var negB1 bn254.G1Affine
+	negB1.Neg(b1)
+	return bn254.PairingCheck([]bn254.G1Affine{*a1, negB1}, []bn254.G2Affine{*a2, *b2})
+}
+
+// shiftedSumsG1 accumulates sum(r_i*p[i]) and sum(r_i*p[i+1]) over the points
+// of a section, given a chunk at a time, and keeps its first two points.
+type shiftedSumsG1 struct {
+	section  string
+	rho, r   fr.Element
+	head     []bn254.G1Affine
+	last     bn254.G1Affine
+	count    int
+	lhs, rhs bn254.G1Jac
+}
+
+func (sums *shiftedSumsG1) init(section string) error {
+	sums.section = section
+	sums.r.SetOne()
+	_, err := sums.rho.SetRandom()
+	return err
+}
+
+func (sums *shiftedSumsG1) add(points []bn254.G1Affine) error {
+	if len(points) == 0 {
+		return nil
+	}
+	// count is the number of points of the section added so far
+	defer func(added int) {
+		sums.count += added
+	}(len(points))
+	for i := 0; i < len(points) && len(sums.head) < 2; i++ {
+		sums.head = append(sums.head, points[i])
+	}
+
+	// the first pair straddles the previous chunk and this one
+	if sums.count > 0 {
+		points = append([]bn254.G1Affine{sums.last}, points...)
+	}
+	sums.last = points[len(points)-1]
+	n := len(points) - 1
+	if n == 0 {
+		return nil
+	}
+
+	scalars := make([]fr.Element, n)
+	for i := range scalars {
+		scalars[i] = sums.r
+		sums.r.Mul(&sums.r, &sums.rho)
+	}
+	var lhs, rhs bn254.G1Jac
+	if _, err := lhs.MultiExp(points[:n], scalars, ecc.MultiExpConfig{}); err != nil {
+		return err
+	}
+	if _, err := rhs.MultiExp(points[1:], scalars, ecc.MultiExpConfig{}); err != nil {
+		return err
+	}
+	sums.lhs.AddAssign(&lhs)
+	sums.rhs.AddAssign(&rhs)
+	return nil
+}
+
+// shiftedSumsG2 is shiftedSumsG1 over G2, whose points are also checked to be
+// in the subgroup.
+type shiftedSumsG2 struct {
+	section  string
+	rho, r   fr.Element
+	head     []bn254.G2Affine
+	last     bn254.G2Affine
+	count    int
+	lhs, rhs bn254.G2Jac
+}
+
+func (sums *shiftedSumsG2) init(section string) error {
+	sums.section = section
+	sums.r.SetOne()
+	_, err := sums.rho.SetRandom()
+	return err
+}
+
+func (sums *shiftedSumsG2) add(points []bn254.G2Affine) error {
+	if len(points) == 0 {
+		return nil
+	}
+	// count is the number of points of the section added so far
+	defer func(added int) {
+		sums.count += added
+	}(len(points))
+	for i := 0; i < len(points) && len(sums.head) < 2; i++ {
+		sums.head = append(sums.head, points[i])
+	}
+
+	outside := make([]bool, len(points))
+	parallelize(len(points), func(start, end int) {
+		for i := start; i < end; i++ {
+			outside[i] = !points[i].IsInSubGroup()
+		}
+	})
+	for i := range outside {
+		if outside[i] {
+			return &InvalidPhase1{Section: sums.section, Reason: fmt.Sprintf("point %d is not in the subgroup", sums.count+i)}
+		}
+	}
+
+	// the first pair straddles the previous chunk and this one
+	if sums.count > 0 {
+		points = append([]bn254.G2Affine{sums.last}, points...)
+	}
+	sums.last = points[len(points)-1]
+	n := len(points) - 1
+	if n == 0 {
+		return nil
+	}
+
+	scalars := make([]fr.Element, n)
+	for i := range scalars {
+		scalars[i] = sums.r
+		sums.r.Mul(&sums.r, &sums.rho)
+	}
+	var lhs, rhs bn254.G2Jac
+	if _, err := lhs.MultiExp(points[:n], scalars, ecc.MultiExpConfig{}); err != nil {
+		return err
+	}
+	if _, err := rhs.MultiExp(points[1:], scalars, ecc.MultiExpConfig{}); err != nil {
+		return err
+	}
+	sums.lhs.AddAssign(&lhs)
+	sums.rhs.AddAssign(&rhs)
+	return nil
+}
@@ -0,0 +1,241 @@
+package deserializer
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
+	"github.com/consensys/gnark/backend/plonk"
+)
+
+///////////////////////////////////////////////////////////////////
+///                        PLONK TO GNARK                       ///
+///////////////////////////////////////////////////////////////////
+
+// gnark keeps the fields of its bn254 PLONK keys internal, the keys are built
+// by serializing them the way gnark does and reading them back with ReadFrom:
+// see internal/backend/bn254/plonk/marshal.go in gnark v0.8.0.
+
+// GnarkKeys converts the zkey into gnark's bn254 PLONK proving and verifying
+// keys, along with the KZG SRS made of its PTau section.
+//
+// snarkjs and gnark build the same PLONK constraints, but differ in two
+// conventions that the conversion translates:
+//   - public inputs are checked by rows a + PI = 0 where qL is 1 and PI is
+//     minus the input in snarkjs, by rows -a + qK = 0 where qK is the input
+//     in gnark, so the public rows are negated;
+//   - the permutation labels the wires of b and c with the cosets k1*H and
+//     k2*H in snarkjs, with u*H and u^2*H in gnark (u being the multiplicative
+//     generator of the scalar field), so the sigma polynomials are rebuilt.
+//
+// The keys encode the circuit of the zkey, proving with them still needs a
+// gnark constraint system with the same wiring to solve the witness: the
+// additions and the A, B and C wire maps of the zkey are not converted.
+func (zkey *PlonkZkey) GnarkKeys() (plonk.ProvingKey, plonk.VerifyingKey, *kzg.SRS, error) {
+	header := zkey.Header
+	n := uint64(header.DomainSize)
+	nPublic := int(header.NPublic)
+
+	domain := fft.NewDomain(n)
+	if domain.Cardinality != n {
+		return nil, nil, nil, fmt.Errorf("no domain of size %d", n)
+	}
+
+	// gnark commits to the blinded wire polynomials, of degree n+2
+	if len(zkey.PTau) < int(n)+3 {
+		return nil, nil, nil, fmt.Errorf("the PTau section holds %d points, at least %d are needed", len(zkey.PTau), n+3)
+	}
+	srs := &kzg.SRS{G1: zkey.PTau}
+	_, _, _, srs.G2[0] = bn254.Generators()
+	srs.G2[1] = header.X2
+
+	ql := lagrangeForm(zkey.Ql)
+	qr := lagrangeForm(zkey.Qr)
+	qm := lagrangeForm(zkey.Qm)
+	qo := lagrangeForm(zkey.Qo)
+	qk := lagrangeForm(zkey.Qc)
+	for i := 0; i < nPublic; i++ {
+		for _, q := range [][]fr.Element{ql, qr, qm, qo, qk} {
+			q[i].Neg(&q[i])
+		}
+		// completed with the public inputs by the prover
+		qk[i].SetZero()
+	}
+
+	permutation, err := zkey.permutation(domain)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// the labels of the wires in gnark, see getIDSmallDomain
+	ids := make([]fr.Element, 3*n)
+	shifts := make([]fr.Element, 3)
+	shifts[0].SetOne()
+	shifts[1].Set(&domain.FrMultiplicativeGen)
+	shifts[2].Square(&domain.FrMultiplicativeGen)
+	for k := range shifts {
+		label := shifts[k]
+		for i := uint64(0); i < n; i++ {
+			ids[uint64(k)*n+i] = label
+			label.Mul(&label, &domain.Generator)
+		}
+	}
+	var s [3][]fr.Element
+	for k := range s {
+		s[k] = make([]fr.Element, n)
+		for i := range s[k] {
+			s[k][i] = ids[permutation[uint64(k)*n+uint64(i)]]
+		}
+	}
+
+	// polynomials in canonical form, in the order gnark serializes them
+	lqk := append([]fr.Element{}, qk...)
+	polynomials := [][]fr.Element{ql, qr, qm, qo, qk, lqk, s[0], s[1], s[2]}
+	for i, p := range polynomials {
+		if i == 5 {
+			// LQk stays in Lagrange form
+			continue
+		}
+		polynomials[i] = canonicalForm(domain, p)
+	}
+
+	digests := make([]kzg.Digest, len(polynomials))
+	for i, p := range polynomials {
+		if i == 5 {
+			continue
+		}
+		if digests[i], err = kzg.Commit(p, srs); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	// the selectors other than qL are left untouched, their commitments must
+	// be the ones of the zkey
+	for _, c := range []struct {
+		name     string
+		computed kzg.Digest
+		zkey     bn254.G1Affine
+	}{{"Qr", digests[1], header.Qr}, {"Qm", digests[2], header.Qm}, {"Qo", digests[3], header.Qo}, {"Qc", digests[4], header.Qc}} {
+		if !c.computed.Equal(&c.zkey) {
+			return nil, nil, nil, fmt.Errorf("the commitment to %s doesn't match the one of the zkey, the PTau section or the polynomials are corrupted", c.name)
+		}
+	}
+
+	var sizeInv fr.Element
+	sizeInv.SetUint64(n).Inverse(&sizeInv)
+
+	var buffer bytes.Buffer
+	enc := bn254.NewEncoder(&buffer)
+	for _, v := range []interface{}{
+		n,
+		&sizeInv,
+		&domain.Generator,
+		uint64(nPublic),
+		&domain.FrMultiplicativeGen,
+		&digests[6], &digests[7], &digests[8],
+		&digests[0], &digests[1], &digests[2], &digests[3], &digests[4],
+	} {
+		if err := enc.Encode(v); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	vkBytes := append([]byte{}, buffer.Bytes()...)
+
+	// gnark sizes the domain of the quotient from the number of rows in use
+	nbRows := uint64(header.NConstraints)
+	if nbRows == 0 {
+		nbRows = 1
+	}
+	bigDomain := fft.NewDomain(4 * nbRows)
+	if nbRows < 6 {
+		bigDomain = fft.NewDomain(8 * nbRows)
+	}
+
+	if _, err := domain.WriteTo(&buffer); err != nil {
+		return nil, nil, nil, err
+	}
+	if _, err := bigDomain.WriteTo(&buffer); err != nil {
+		return nil, nil, nil, err
+	}
+	enc = bn254.NewEncoder(&buffer)
+	for _, p := range polynomials {
+		if err := enc.Encode(p); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	if err := enc.Encode(permutation); err != nil {
+		return nil, nil, nil, err
+	}
+
+	pk := plonk.NewProvingKey(ecc.BN254)
+	if _, err := pk.ReadFrom(&buffer); err != nil {
+		return nil, nil, nil, err
+	}
+	vk := plonk.NewVerifyingKey(ecc.BN254)
+	if _, err := vk.ReadFrom(bytes.NewReader(vkBytes)); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := pk.InitKZG(srs); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := vk.InitKZG(srs); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return pk, vk, srs, nil
+}
+
+// permutation recovers the copy constraints from the sigma polynomials:
+// sigma_k(w^i) is the label of the wire following wire k*n+i in its cycle.
+func (zkey *PlonkZkey) permutation(domain *fft.Domain) ([]int64, error) {
+	n := int(domain.Cardinality)
+
+	shifts := make([]fr.Element, 3)
+	shifts[0].SetOne()
+	shifts[1] = zkey.Header.K1
+	shifts[2] = zkey.Header.K2
+
+	wires := make(map[fr.Element]int64, 3*n)
+	for k := range shifts {
+		label := shifts[k]
+		for i := 0; i < n; i++ {
+			wires[label] = int64(k*n + i)
+			label.Mul(&label, &domain.Generator)
+		}
+	}
+	if len(wires) != 3*n {
+		return nil, fmt.Errorf("k1 and k2 don't shift the domain to distinct cosets")
+	}
+
+	permutation := make([]int64, 3*n)
+	for k := range zkey.Sigma {
+		if len(zkey.Sigma[k].Evaluations) != n {
+			return nil, fmt.Errorf("sigma %d has %d evaluations, expected %d", k+1, len(zkey.Sigma[k].Evaluations), n)
+		}
+		for i, label := range zkey.Sigma[k].Evaluations {
+			wire, ok := wires[label]
+			if !ok {
+				return nil, fmt.Errorf("sigma %d at w^%d doesn't label any wire", k+1, i)
+			}
+			permutation[k*n+i] = wire
+		}
+	}
+
+	return permutation, nil
+}
+
+func lagrangeForm(p PlonkPolynomial) []fr.Element {
+	return append([]fr.Element{}, p.Evaluations...)
+}
+
+// canonicalForm interpolates the evaluations of a polynomial on the domain
+// into its coefficients, in place.
+func canonicalForm(domain *fft.Domain, evaluations []fr.Element) []fr.Element {
+	domain.FFTInverse(evaluations, fft.DIF)
+	fft.BitReverse(evaluations)
+	return evaluations
+}
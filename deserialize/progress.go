@@ -0,0 +1,83 @@
+package deserializer
+
+import (
+	"time"
+)
+
+// Progress receives updates while a conversion runs, to draw a progress bar
+// or to export metrics.
+type Progress interface {
+	Update(update ProgressUpdate)
+}
+
+// ProgressFunc adapts an ordinary function to the Progress interface.
+type ProgressFunc func(update ProgressUpdate)
+
+func (f ProgressFunc) Update(update ProgressUpdate) {
+	f(update)
+}
+
+type ProgressUpdate struct {
+	// Section is the name of the .ph1 section being written (TauG1, AlphaTauG1, ...)
+	Section string
+	// Done is the number of points of Section written so far, out of Total
+	Done  int
+	Total int
+	// BytesPerSecond is the rate at which the input has been read since the
+	// conversion started
+	BytesPerSecond float64
+}
+
+// ConvertOption configures the ptau to .ph1 conversions.
+type ConvertOption func(*convertOptions)
+
+type convertOptions struct {
+	progress Progress
+}
+
+// WithProgress reports the progress of the conversion to progress.
+func WithProgress(progress Progress) ConvertOption {
+	return func(options *convertOptions) {
+		options.progress = progress
+	}
+}
+
+func newConvertOptions(opts []ConvertOption) convertOptions {
+	var options convertOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// pointsPerUpdate is the number of points converted between two checks of the
+// context and two progress updates.
+const pointsPerUpdate = 1 << 12
+
+type progressTracker struct {
+	progress Progress
+	start    time.Time
+	bytes    int64
+}
+
+func newProgressTracker(progress Progress) *progressTracker {
+	return &progressTracker{progress: progress, start: time.Now()}
+}
+
+// read records that n more bytes of the input have been read.
+func (tracker *progressTracker) read(n int64) {
+	tracker.bytes += n
+}
+
+func (tracker *progressTracker) report(section string, done int, total int) {
+	if tracker.progress == nil {
+		return
+	}
+
+	var bytesPerSecond float64
+	if elapsed := time.Since(tracker.start).Seconds(); elapsed > 0 {
+		bytesPerSecond = float64(tracker.bytes) / elapsed
+	}
+
+	tracker.progress.Update(ProgressUpdate{Section: section, Done: done, Total: total, BytesPerSecond: bytesPerSecond})
+}
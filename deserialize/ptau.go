@@ -1,12 +1,17 @@
 package deserializer
 
 import (
+	"bufio"
+	"context"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"math/big"
 	"os"
 
+	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
 )
 
 ///////////////////////////////////////////////////////////////////
@@ -67,6 +72,10 @@ contributions(7) - Ignore contributions, users can verify using snarkjs
 // in bytes
 const BN254_FIELD_ELEMENT_SIZE = 32
 
+// PTAU_MAX_SECTION_ID is the id of the last section of a ptau prepared for
+// phase 2, see ptau_prepare.go
+const PTAU_MAX_SECTION_ID = 15
+
 type G1 [2]big.Int
 type G2 [4]big.Int
 
@@ -79,6 +88,8 @@ type PtauHeader struct {
 type Ptau struct {
 	Header     PtauHeader
 	PTauPubKey PtauPubKey
+	// NumContributions is the number of contributions section 7 records
+	NumContributions uint32
 }
 
 type PtauPubKey struct {
@@ -102,54 +113,144 @@ func InitPtau(path string) (*PtauFile, error) {
 		return nil, err
 	}
 
+	ptauFile, err := readPtauFile(reader)
+	if err != nil {
+		reader.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return ptauFile, nil
+}
+
+// readPtauFile reads the section table and the header of the ptau opened as
+// reader.
+func readPtauFile(reader *os.File) (*PtauFile, error) {
 	var ptauStr = make([]byte, 4)
-	_, err = reader.Read(ptauStr)
+	if _, err := io.ReadFull(reader, ptauStr); err != nil {
+		return nil, fmt.Errorf("reading magic: %w", err)
+	}
 
-	fmt.Printf("zkeyStr: %s \n", string(ptauStr))
+	log().Debug("read magic", "magic", string(ptauStr))
+	if string(ptauStr) != ptauMagic {
+		return nil, fmt.Errorf("invalid magic %q, expected %q", ptauStr, ptauMagic)
+	}
 
 	// version
-	_, err = readULE32(reader)
+	if _, err := readULE32(reader); err != nil {
+		return nil, fmt.Errorf("reading version: %w", err)
+	}
 
-	// number of sections
-	_, err = readULE32(reader)
+	// number of sections, 7 or 11 once the Lagrange sections of phase 2 were
+	// prepared
+	numSections, err := readULE32(reader)
+	if err != nil {
+		return nil, fmt.Errorf("reading number of sections: %w", err)
+	}
+	log().Debug("read number of sections", "sections", numSections)
 
-	numSections := uint32(7)
-	fmt.Printf("num sections: %v \n", numSections)
+	info, err := reader.Stat()
+	if err != nil {
+		return nil, err
+	}
 
 	// in practice, all sections have only one segment, but who knows...
 	// 1-based indexing, so we need to allocate one more than the number of sections
-	sections := make([][]SectionSegment, numSections+1)
+	sections := make([][]SectionSegment, 8)
 	for i := uint32(0); i < numSections; i++ {
-		ht, _ := readULE32(reader)
-		hl, _ := readULE64(reader)
-		fmt.Printf("ht: %v \n", ht)
-		fmt.Printf("hl: %v \n", hl)
+		ht, err := readULE32(reader)
+		if err != nil {
+			return nil, fmt.Errorf("reading section table: %w", err)
+		}
+		hl, err := readULE64(reader)
+		if err != nil {
+			return nil, fmt.Errorf("reading section table: %w", err)
+		}
+		log().Debug("read section", "id", ht, "size", hl)
+		if ht > PTAU_MAX_SECTION_ID {
+			return nil, fmt.Errorf("unknown ptau section %d", ht)
+		}
+		for uint32(len(sections)) <= ht {
+			sections = append(sections, nil)
+		}
 		if sections[ht] == nil {
 			sections[ht] = make([]SectionSegment, 0)
 		}
-		pos, _ := reader.Seek(0, io.SeekCurrent)
+		pos, err := reader.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		if hl > uint64(info.Size()-pos) {
+			return nil, fmt.Errorf("ptau section %d ends past the end of the file", ht)
+		}
 		sections[ht] = append(sections[ht], SectionSegment{pos: uint64(pos), size: hl})
-		reader.Seek(int64(hl), io.SeekCurrent)
+		if _, err := reader.Seek(int64(hl), io.SeekCurrent); err != nil {
+			return nil, err
+		}
 	}
 
-	fmt.Printf("sections: %v \n", sections)
-
-	// section size
-	_, err = readBigInt(reader, 8)
+	log().Debug("read section table", "sections", sections)
 
 	// Header (1)
+	if len(sections[1]) != 1 {
+		return nil, fmt.Errorf("ptau must have a single header section")
+	}
 	seekToUniqueSection(reader, sections, 1)
 
 	// Read header
 	header, err := readPtauHeader(reader)
 
 	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	if err := checkBN254(header.Curve(), header.N8, &header.Prime); err != nil {
+		return nil, err
+	}
+	if header.Power < 1 || header.Power > PTAU_MAX_POWER {
+		return nil, fmt.Errorf("ptau power %d is not between 1 and %d", header.Power, PTAU_MAX_POWER)
+	}
+	if err := checkPtauSections(sections, header.Power); err != nil {
 		return nil, err
 	}
 
 	return &PtauFile{Header: header, Sections: sections, Reader: reader}, nil
 }
 
+// checkPtauSections checks that the point sections (2 to 6) and the
+// contributions (7) are there, along with the Lagrange sections (12 to 15)
+// of a prepared ptau, each in a single segment, and that the point sections
+// hold the points of a ptau of the given power. The readers seek to them
+// without checking again.
+func checkPtauSections(sections [][]SectionSegment, power uint32) error {
+	for sectionId := uint32(2); sectionId < uint32(len(sections)); sectionId++ {
+		segments := sections[sectionId]
+		if len(segments) == 0 {
+			if sectionId <= 7 {
+				return fmt.Errorf("ptau has no section %d", sectionId)
+			}
+			continue
+		}
+		if sectionId > 7 && sectionId < 12 {
+			continue
+		}
+		if len(segments) > 1 {
+			return fmt.Errorf("ptau section %d is split in %d segments", sectionId, len(segments))
+		}
+		if sectionId == 7 {
+			continue
+		}
+		if size := ptauPointsSize(sectionId, power); segments[0].size != size {
+			return fmt.Errorf("ptau section %d holds %d bytes, a ptau of power %d holds %d", sectionId, segments[0].size, power, size)
+		}
+	}
+	return nil
+}
+
+// Curve returns the curve the ptau is defined over.
+func (ptauFile *PtauFile) Curve() ecc.ID {
+	return ptauFile.Header.Curve()
+}
+
 func (ptauFile *PtauFile) Close() error {
 	return ptauFile.Reader.Close()
 }
@@ -158,97 +259,91 @@ func (ptauFile *PtauFile) DomainSize() int {
 	return 1 << ptauFile.Header.Power
 }
 
-func (ptauFile *PtauFile) readG1s(out chan bn254.G1Affine, count int) error {
+func (ptauFile *PtauFile) readG1s(ctx context.Context, out chan bn254.G1Affine, count int) error {
+	reader := bufio.NewReader(ptauFile.Reader)
+	buffer := make([]byte, BN254_FIELD_ELEMENT_SIZE)
 	for i := 0; i < count; i++ {
-		g1, err := readG1(ptauFile.Reader)
+		g1Affine, err := readG1Affine(reader, buffer)
 		if err != nil {
-			return err
+			return fmt.Errorf("readG1s: index %d: %w", i, err)
 		}
-		g1Affine := bn254.G1Affine{}
-		x := bytesToElement(g1[0].Bytes())
-		g1Affine.X = x
-		y := bytesToElement(g1[1].Bytes())
-		g1Affine.Y = y
-		if !g1Affine.IsOnCurve() {
-			fmt.Printf("readG1s: \n index: %v g1Affine.X: %v \n g1Affine.Y: %v \n", i, g1Affine.X.String(), g1Affine.Y.String())
-			panic("g1Affine is not on curve")
+		// stop as soon as the consumer gives up, instead of blocking forever
+		select {
+		case out <- g1Affine:
+		case <-ctx.Done():
+			return ctx.Err()
 		}
-		out <- g1Affine
 	}
 	return nil
 }
 
 func (ptauFile *PtauFile) readG2() (bn254.G2Affine, error) {
-	g2, err := readG2(ptauFile.Reader)
+	g2Affine, err := readG2Affine(ptauFile.Reader, make([]byte, BN254_FIELD_ELEMENT_SIZE))
 	if err != nil {
-		return bn254.G2Affine{}, err
-	}
-	g2Affine := bn254.G2Affine{}
-	x0 := bytesToElement(g2[0].Bytes())
-	x1 := bytesToElement(g2[1].Bytes())
-	g2Affine.X.A0 = x0
-	g2Affine.X.A1 = x1
-	y0 := bytesToElement(g2[2].Bytes())
-	y1 := bytesToElement(g2[3].Bytes())
-	g2Affine.Y.A0 = y0
-	g2Affine.Y.A1 = y1
-	if !g2Affine.IsOnCurve() {
-
-		fmt.Printf("readG2s: \n, g2Affine.X.A0: %v \n g2Affine.X.A1: %v \n g2Affine.Y.A0: %v \n g2Affine.Y.A1 %v \n", g2Affine.X.A0.String(), g2Affine.X.A1.String(), g2Affine.Y.A0.String(), g2Affine.Y.A1.String())
-		panic("g2Affine is not on curve")
+		return bn254.G2Affine{}, fmt.Errorf("readG2: %w", err)
 	}
 	return g2Affine, nil
 }
 
-func (ptauFile *PtauFile) readG2s(out chan bn254.G2Affine, count int) error {
+func (ptauFile *PtauFile) readG2s(ctx context.Context, out chan bn254.G2Affine, count int) error {
+	reader := bufio.NewReader(ptauFile.Reader)
+	buffer := make([]byte, BN254_FIELD_ELEMENT_SIZE)
 	for i := 0; i < count; i++ {
-		g2Affine, err := ptauFile.readG2()
+		g2Affine, err := readG2Affine(reader, buffer)
 		if err != nil {
-			return err
+			return fmt.Errorf("readG2s: index %d: %w", i, err)
+		}
+		select {
+		case out <- g2Affine:
+		case <-ctx.Done():
+			return ctx.Err()
 		}
-		out <- g2Affine
 	}
 	return nil
 }
 
-func (ptauFile *PtauFile) ReadTauG1(out chan bn254.G1Affine) error {
+// ReadTauG1 sends the points of the tauG1 section to out, and closes it once
+// they have all been sent, an error occurred or ctx is cancelled.
+func (ptauFile *PtauFile) ReadTauG1(ctx context.Context, out chan bn254.G1Affine) error {
 	defer close(out)
 	seekToUniqueSection(ptauFile.Reader, ptauFile.Sections, 2)
 	numPoints := ptauFile.DomainSize()*2 - 1
-	fmt.Printf("tauG1 numPoints: %v \n", numPoints)
-	ptauFile.readG1s(out, numPoints)
-	return nil
+	log().Debug("reading section", "section", "tauG1", "points", numPoints)
+	return ptauFile.readG1s(ctx, out, numPoints)
 }
 
-func (ptauFile *PtauFile) ReadTauG2(out chan bn254.G2Affine) error {
+// ReadTauG2 sends the points of the tauG2 section to out, and closes it once
+// they have all been sent, an error occurred or ctx is cancelled.
+func (ptauFile *PtauFile) ReadTauG2(ctx context.Context, out chan bn254.G2Affine) error {
 	defer close(out)
 	seekToUniqueSection(ptauFile.Reader, ptauFile.Sections, 3)
 	numPoints := ptauFile.DomainSize()
-	fmt.Printf("tauG2 numPoints: %v \n", numPoints)
-	ptauFile.readG2s(out, numPoints)
-	return nil
+	log().Debug("reading section", "section", "tauG2", "points", numPoints)
+	return ptauFile.readG2s(ctx, out, numPoints)
 }
 
-func (ptauFile *PtauFile) ReadAlphaTauG1(out chan bn254.G1Affine) error {
+// ReadAlphaTauG1 sends the points of the alphaTauG1 section to out, and closes
+// it once they have all been sent, an error occurred or ctx is cancelled.
+func (ptauFile *PtauFile) ReadAlphaTauG1(ctx context.Context, out chan bn254.G1Affine) error {
 	defer close(out)
 	seekToUniqueSection(ptauFile.Reader, ptauFile.Sections, 4)
 	numPoints := ptauFile.DomainSize()
-	fmt.Printf("alphaTauG1 numPoints: %v \n", numPoints)
-	ptauFile.readG1s(out, numPoints)
-	return nil
+	log().Debug("reading section", "section", "alphaTauG1", "points", numPoints)
+	return ptauFile.readG1s(ctx, out, numPoints)
 }
 
-func (ptauFile *PtauFile) ReadBetaTauG1(out chan bn254.G1Affine) error {
+// ReadBetaTauG1 sends the points of the betaTauG1 section to out, and closes
+// it once they have all been sent, an error occurred or ctx is cancelled.
+func (ptauFile *PtauFile) ReadBetaTauG1(ctx context.Context, out chan bn254.G1Affine) error {
 	defer close(out)
 	seekToUniqueSection(ptauFile.Reader, ptauFile.Sections, 5)
 	numPoints := ptauFile.DomainSize()
-	fmt.Printf("betaTauG1 numPoints: %v \n", numPoints)
-	ptauFile.readG1s(out, numPoints)
-	return nil
+	log().Debug("reading section", "section", "betaTauG1", "points", numPoints)
+	return ptauFile.readG1s(ctx, out, numPoints)
 }
 
 func (ptauFile *PtauFile) ReadBetaG2() (bn254.G2Affine, error) {
-	fmt.Printf("betaG2: \n")
+	log().Debug("reading section", "section", "betaG2", "points", 1)
 	seekToUniqueSection(ptauFile.Reader, ptauFile.Sections, 6)
 	return ptauFile.readG2()
 }
@@ -265,7 +360,7 @@ func ReadPtau(zkeyPath string) (Ptau, error) {
 	var ptauStr = make([]byte, 4)
 	_, err = reader.Read(ptauStr)
 
-	fmt.Printf("zkeyStr: %s \n", string(ptauStr))
+	log().Debug("read magic", "magic", string(ptauStr))
 
 	// version
 	_, err = readULE32(reader)
@@ -274,7 +369,7 @@ func ReadPtau(zkeyPath string) (Ptau, error) {
 	_, err = readULE32(reader)
 
 	numSections := uint32(7)
-	fmt.Printf("num sections: %v \n", numSections)
+	log().Debug("read number of sections", "sections", numSections)
 
 	// in practice, all sections have only one segment, but who knows...
 	// 1-based indexing, so we need to allocate one more than the number of sections
@@ -282,8 +377,7 @@ func ReadPtau(zkeyPath string) (Ptau, error) {
 	for i := uint32(0); i < numSections; i++ {
 		ht, _ := readULE32(reader)
 		hl, _ := readULE64(reader)
-		fmt.Printf("ht: %v \n", ht)
-		fmt.Printf("hl: %v \n", hl)
+		log().Debug("read section", "id", ht, "size", hl)
 		if sections[ht] == nil {
 			sections[ht] = make([]SectionSegment, 0)
 		}
@@ -292,7 +386,7 @@ func ReadPtau(zkeyPath string) (Ptau, error) {
 		reader.Seek(int64(hl), io.SeekCurrent)
 	}
 
-	fmt.Printf("sections: %v \n", sections)
+	log().Debug("read section table", "sections", sections)
 
 	// section size
 	_, err = readBigInt(reader, 8)
@@ -307,6 +401,10 @@ func ReadPtau(zkeyPath string) (Ptau, error) {
 		return Ptau{}, err
 	}
 
+	if err := checkBN254(header.Curve(), header.N8, &header.Prime); err != nil {
+		return Ptau{}, err
+	}
+
 	// TauG1 (2)
 	seekToUniqueSection(reader, sections, 2)
 
@@ -314,7 +412,7 @@ func ReadPtau(zkeyPath string) (Ptau, error) {
 
 	twoToPower := uint32(1 << header.Power)
 
-	fmt.Printf("tauG1: \n")
+	log().Debug("reading section", "section", "tauG1", "points", twoToPower*2-1)
 
 	PtauPubKey.TauG1, err = readG1Array(reader, twoToPower*2-1)
 
@@ -325,7 +423,7 @@ func ReadPtau(zkeyPath string) (Ptau, error) {
 	// TauG2 (3)
 	seekToUniqueSection(reader, sections, 3)
 
-	fmt.Printf("tauG2: \n")
+	log().Debug("reading section", "section", "tauG2", "points", twoToPower)
 
 	PtauPubKey.TauG2, err = readG2Array(reader, twoToPower)
 
@@ -336,7 +434,7 @@ func ReadPtau(zkeyPath string) (Ptau, error) {
 	// AlphaTauG1 (4)
 	seekToUniqueSection(reader, sections, 4)
 
-	fmt.Printf("alphaTauG1: \n")
+	log().Debug("reading section", "section", "alphaTauG1", "points", twoToPower)
 
 	PtauPubKey.AlphaTauG1, err = readG1Array(reader, twoToPower)
 
@@ -347,7 +445,7 @@ func ReadPtau(zkeyPath string) (Ptau, error) {
 	// BetaTauG1 (5)
 	seekToUniqueSection(reader, sections, 5)
 
-	fmt.Printf("betaTauG1: \n")
+	log().Debug("reading section", "section", "betaTauG1", "points", twoToPower)
 
 	PtauPubKey.BetaTauG1, err = readG1Array(reader, twoToPower)
 
@@ -358,7 +456,7 @@ func ReadPtau(zkeyPath string) (Ptau, error) {
 	// BetaG2 (6)
 	seekToUniqueSection(reader, sections, 6)
 
-	fmt.Printf("betaG2: \n")
+	log().Debug("reading section", "section", "betaG2", "points", 1)
 
 	PtauPubKey.BetaG2, err = readG2(reader)
 
@@ -366,10 +464,25 @@ func ReadPtau(zkeyPath string) (Ptau, error) {
 		return Ptau{}, err
 	}
 
-	return Ptau{Header: header, PTauPubKey: PtauPubKey}, nil
+	// Contributions (7), only their number is needed
+	seekToUniqueSection(reader, sections, 7)
+
+	numContributions, err := readULE32(reader)
+
+	if err != nil {
+		return Ptau{}, err
+	}
+
+	return Ptau{Header: header, PTauPubKey: PtauPubKey, NumContributions: numContributions}, nil
+}
+
+// Curve returns the curve identified from the prime of the header,
+// ecc.UNKNOWN if it matches none of the curves known to gnark-crypto.
+func (header PtauHeader) Curve() ecc.ID {
+	return curveFromBaseField(header.N8, &header.Prime)
 }
 
-func readPtauHeader(reader io.ReadSeeker) (PtauHeader, error) {
+func readPtauHeader(reader io.Reader) (PtauHeader, error) {
 	var header PtauHeader
 
 	n8, err := readULE32(reader)
@@ -399,7 +512,7 @@ func readPtauHeader(reader io.ReadSeeker) (PtauHeader, error) {
 	return header, nil
 }
 
-func readG1Array(reader io.ReadSeeker, numPoints uint32) ([]G1, error) {
+func readG1Array(reader io.Reader, numPoints uint32) ([]G1, error) {
 	g1s := make([]G1, numPoints)
 	for i := uint32(0); i < numPoints; i++ {
 		g1, err := readG1(reader)
@@ -413,7 +526,7 @@ func readG1Array(reader io.ReadSeeker, numPoints uint32) ([]G1, error) {
 	return g1s, nil
 }
 
-func readG2Array(reader io.ReadSeeker, numPoints uint32) ([]G2, error) {
+func readG2Array(reader io.Reader, numPoints uint32) ([]G2, error) {
 	g2s := make([]G2, numPoints)
 
 	for i := uint32(0); i < numPoints; i++ {
@@ -429,7 +542,7 @@ func readG2Array(reader io.ReadSeeker, numPoints uint32) ([]G2, error) {
 	return g2s, nil
 }
 
-func readTauG2(reader io.ReadSeeker) ([]G2, error) {
+func readTauG2(reader io.Reader) ([]G2, error) {
 	tauG2_s, err := readG2(reader)
 
 	if err != nil {
@@ -445,7 +558,7 @@ func readTauG2(reader io.ReadSeeker) ([]G2, error) {
 	return []G2{tauG2_s, tauG2_sx}, nil
 }
 
-func readG1(reader io.ReadSeeker) (G1, error) {
+func readG1(reader io.Reader) (G1, error) {
 	var g1 G1
 
 	x, err := readBigInt(reader, BN254_FIELD_ELEMENT_SIZE)
@@ -467,7 +580,7 @@ func readG1(reader io.ReadSeeker) (G1, error) {
 	return g1, nil
 }
 
-func readG2(reader io.ReadSeeker) (G2, error) {
+func readG2(reader io.Reader) (G2, error) {
 	var g2 G2
 
 	x0, err := readBigInt(reader, BN254_FIELD_ELEMENT_SIZE)
@@ -504,3 +617,120 @@ func readG2(reader io.ReadSeeker) (G2, error) {
 
 	return g2, nil
 }
+
+// readG1Affine reads a G1 point straight into its affine representation and
+// checks that it lies on the curve.
+func readG1Affine(reader io.Reader, buffer []byte) (bn254.G1Affine, error) {
+	var g1Affine bn254.G1Affine
+	var err error
+
+	if g1Affine.X, err = readElement(reader, buffer); err != nil {
+		return g1Affine, err
+	}
+	if g1Affine.Y, err = readElement(reader, buffer); err != nil {
+		return g1Affine, err
+	}
+
+	if !g1Affine.IsOnCurve() {
+		return g1Affine, fmt.Errorf("g1Affine is not on curve: X: %v Y: %v", g1Affine.X.String(), g1Affine.Y.String())
+	}
+
+	return g1Affine, nil
+}
+
+// readG2Affine reads a G2 point straight into its affine representation and
+// checks that it lies on the curve.
+func readG2Affine(reader io.Reader, buffer []byte) (bn254.G2Affine, error) {
+	var g2Affine bn254.G2Affine
+	var err error
+
+	if g2Affine.X.A0, err = readElement(reader, buffer); err != nil {
+		return g2Affine, err
+	}
+	if g2Affine.X.A1, err = readElement(reader, buffer); err != nil {
+		return g2Affine, err
+	}
+	if g2Affine.Y.A0, err = readElement(reader, buffer); err != nil {
+		return g2Affine, err
+	}
+	if g2Affine.Y.A1, err = readElement(reader, buffer); err != nil {
+		return g2Affine, err
+	}
+
+	if !g2Affine.IsOnCurve() {
+		return g2Affine, fmt.Errorf("g2Affine is not on curve: X: %v Y: %v", g2Affine.X.String(), g2Affine.Y.String())
+	}
+
+	return g2Affine, nil
+}
+
+// ceremonyPower reads the power of the ceremony the ptau comes from, which is
+// larger than its power when it was reduced. Older files don't record it.
+func (ptauFile *PtauFile) ceremonyPower() (uint32, error) {
+	seekToUniqueSection(ptauFile.Reader, ptauFile.Sections, 1)
+	if ptauFile.Sections[1][0].size < uint64(4+ptauFile.Header.N8+4+4) {
+		return ptauFile.Header.Power, nil
+	}
+	if _, err := ptauFile.Reader.Seek(int64(4+ptauFile.Header.N8+4), io.SeekCurrent); err != nil {
+		return 0, err
+	}
+	return readULE32(ptauFile.Reader)
+}
+
+// ptauWriter writes the sections of a ptau one after the other, recording
+// where each one starts.
+type ptauWriter struct {
+	*bufio.Writer
+	offset   uint64
+	Sections [][]SectionSegment
+}
+
+// newPtauWriter writes the magic of a ptau with numSections sections, then its
+// header.
+func newPtauWriter(writer io.Writer, numSections, power, ceremonyPower uint32) *ptauWriter {
+	w := &ptauWriter{Writer: bufio.NewWriter(writer), offset: 12, Sections: make([][]SectionSegment, 8)}
+	w.WriteString("ptau")
+	binary.Write(w, binary.LittleEndian, []uint32{1, numSections})
+
+	// Header (1)
+	w.beginSection(1, 4+BN254_FIELD_ELEMENT_SIZE+4+4)
+	binary.Write(w, binary.LittleEndian, uint32(BN254_FIELD_ELEMENT_SIZE))
+	w.Write(reverseSlice(fp.Modulus().FillBytes(make([]byte, BN254_FIELD_ELEMENT_SIZE))))
+	binary.Write(w, binary.LittleEndian, []uint32{power, ceremonyPower})
+	return w
+}
+
+// beginSection writes the id and size of a section, its data must follow.
+func (w *ptauWriter) beginSection(id uint32, size uint64) {
+	binary.Write(w, binary.LittleEndian, id)
+	binary.Write(w, binary.LittleEndian, size)
+	for uint32(len(w.Sections)) <= id {
+		w.Sections = append(w.Sections, nil)
+	}
+	w.Sections[id] = []SectionSegment{{pos: w.offset + 12, size: size}}
+	w.offset += 12 + size
+}
+
+// ptauPointsSize is the size of the points of a section of a ptau of the
+// given power.
+func ptauPointsSize(sectionId uint32, power uint32) uint64 {
+	N := uint64(1) << power
+	g1Size, g2Size := uint64(2*BN254_FIELD_ELEMENT_SIZE), uint64(4*BN254_FIELD_ELEMENT_SIZE)
+	switch sectionId {
+	case 2:
+		return (2*N - 1) * g1Size
+	case 3:
+		return N * g2Size
+	case 4, 5:
+		return N * g1Size
+	case 6:
+		return g2Size
+	case 12:
+		return (4*N - 1) * g1Size
+	case 13:
+		return (2*N - 1) * g2Size
+	case 14, 15:
+		return (2*N - 1) * g1Size
+	}
+	return 0
+}
@@ -0,0 +1,811 @@
+package deserializer
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+)
+
+///////////////////////////////////////////////////////////////////
+///                          PTAU JSON                          ///
+///////////////////////////////////////////////////////////////////
+
+// Taken from the iden3/snarkjs repo, powersoftau_export_json.js. The points
+// are arrays of decimal strings, in projective coordinates with z = 1 (or
+// [0, 1, 0] for the point at infinity), and G2 coordinates are [c0, c1]. The
+// hashes are the decimal strings of their bytes read as a little-endian
+// integer, like ffjavascript stringifies byte arrays:
+/*
+{
+ "q": "21888242871839275222246405745257275088696311157297823662689037894645226208583",
+ "power": 8,
+ "contributions": [
+  {
+   "tauG1", "tauG2", "alphaG1", "betaG1", "betaG2",
+   "key": {
+    "tau": { "g1_s", "g1_sx", "g2_spx" }, "alpha": { ... }, "beta": { ... }
+   },
+   "partialHash", "nextChallenge", "type", "responseHash",
+   "name", "numIterationsExp", "beaconHash"
+  }
+ ],
+ "tauG1": [ ["x", "y", "1"], ... ],
+ "tauG2": [ [["x0", "x1"], ["y0", "y1"], ["1", "0"]], ... ],
+ "alphaTauG1": [ ... ],
+ "betaTauG1": [ ... ],
+ "betaG2": [ ... ],
+ "lTauG1": [ [ 2^0 points ], [ 2^1 points ], ..., [ 2^power points ] ],
+ "lTauG2": [ ... ],
+ "lAlphaTauG1": [ ... ],
+ "lBetaTauG1": [ ... ]
+}
+*/
+// It is indented like snarkjs does, so that both can be diffed. The Lagrange
+// sections are only there for a ptau prepared for phase 2, see
+// ptau_prepare.go. snarkjs leaves out the last domain of lTauG1, it is
+// computed again on import. snarkjs doesn't dump the ceremony power or the
+// length of a beacon hash either, so that a reduced ptau or a short beacon
+// hash comes back exactly, they are added as keys snarkjs ignores:
+/*
+ "ceremonyPower": 28,           after "power", when it isn't the power
+ "beaconHashSize": 16,          in a contribution, when the beacon hash isn't
+                                the shortest one of at least 32 bytes
+*/
+// and otherwise taken to be the power and the shortest length of at least
+// 32 bytes holding the hash.
+
+type jsonG1 [3]string
+type jsonG2 [3][2]string
+
+type jsonPublicKeyPart struct {
+	G1S   jsonG1 `json:"g1_s"`
+	G1SX  jsonG1 `json:"g1_sx"`
+	G2SPX jsonG2 `json:"g2_spx"`
+}
+
+type jsonContribution struct {
+	TauG1   jsonG1 `json:"tauG1"`
+	TauG2   jsonG2 `json:"tauG2"`
+	AlphaG1 jsonG1 `json:"alphaG1"`
+	BetaG1  jsonG1 `json:"betaG1"`
+	BetaG2  jsonG2 `json:"betaG2"`
+	Key     struct {
+		Tau   jsonPublicKeyPart `json:"tau"`
+		Alpha jsonPublicKeyPart `json:"alpha"`
+		Beta  jsonPublicKeyPart `json:"beta"`
+	} `json:"key"`
+	PartialHash      string `json:"partialHash"`
+	NextChallenge    string `json:"nextChallenge"`
+	Type             uint32 `json:"type"`
+	ResponseHash     string `json:"responseHash"`
+	Name             string `json:"name,omitempty"`
+	NumIterationsExp uint8  `json:"numIterationsExp,omitempty"`
+	BeaconHash       string `json:"beaconHash,omitempty"`
+	BeaconHashSize   int    `json:"beaconHashSize,omitempty"`
+}
+
+// InvalidPtauJSON is returned when a JSON dump can't be imported back.
+type InvalidPtauJSON struct {
+	Key    string
+	Reason string
+}
+
+func (r *InvalidPtauJSON) Error() string {
+	return fmt.Sprintf("invalid ptau JSON, %s: %s", r.Key, r.Reason)
+}
+
+// jsonSections are the point sections of the dump, by key.
+var jsonSections = map[string]uint32{
+	"tauG1":      2,
+	"tauG2":      3,
+	"alphaTauG1": 4,
+	"betaTauG1":  5,
+	"betaG2":     6,
+}
+
+// WriteJSONFromPtauFile dumps the ptau as JSON to outputPath, streaming the
+// points.
+func WriteJSONFromPtauFile(ctx context.Context, ptauFile *PtauFile, outputPath string) error {
+	outputFile, err := createAtomicFile(outputPath)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(outputFile)
+	if err := writeJSON(ctx, ptauFile, writer); err != nil {
+		outputFile.Abort()
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		outputFile.Abort()
+		return err
+	}
+
+	return outputFile.Commit()
+}
+
+func writeJSON(ctx context.Context, ptauFile *PtauFile, writer io.Writer) error {
+	contributions, err := ptauFile.ReadContributions()
+	if err != nil {
+		return err
+	}
+
+	jsonContributions := make([]jsonContribution, len(contributions))
+	for i := range contributions {
+		if jsonContributions[i], err = contributionToJSON(&contributions[i]); err != nil {
+			return err
+		}
+	}
+	contributionsJSON, err := json.MarshalIndent(jsonContributions, " ", " ")
+	if err != nil {
+		return err
+	}
+
+	ceremonyPower, err := ptauFile.ceremonyPower()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(writer, "{\n \"q\": \"%s\",\n \"power\": %d,", ptauFile.Header.Prime.String(), ptauFile.Header.Power)
+	if ceremonyPower != ptauFile.Header.Power {
+		fmt.Fprintf(writer, "\n \"ceremonyPower\": %d,", ceremonyPower)
+	}
+	fmt.Fprintf(writer, "\n \"contributions\": %s", contributionsJSON)
+
+	writeG1s := func(key string, read func(context.Context, chan bn254.G1Affine) error) error {
+		return writeJSONSection(ctx, writer, key, read, g1ToJSON)
+	}
+	writeG2s := func(key string, read func(context.Context, chan bn254.G2Affine) error) error {
+		return writeJSONSection(ctx, writer, key, read, g2ToJSON)
+	}
+	if err := writeG1s("tauG1", ptauFile.ReadTauG1); err != nil {
+		return err
+	}
+	if err := writeG2s("tauG2", ptauFile.ReadTauG2); err != nil {
+		return err
+	}
+	if err := writeG1s("alphaTauG1", ptauFile.ReadAlphaTauG1); err != nil {
+		return err
+	}
+	if err := writeG1s("betaTauG1", ptauFile.ReadBetaTauG1); err != nil {
+		return err
+	}
+	err = writeG2s("betaG2", func(ctx context.Context, out chan bn254.G2Affine) error {
+		defer close(out)
+		betaG2, err := ptauFile.ReadBetaG2()
+		if err == nil {
+			out <- betaG2
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if ptauFile.preparedForPhase2() {
+		for _, section := range lagrangeSections {
+			if err := writeJSONLagrangeSection(ctx, ptauFile, writer, section); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = io.WriteString(writer, "\n}")
+	return err
+}
+
+// writeJSONLagrangeSection writes the points of section as one array per
+// domain, up to the one of the power of the ptau.
+func writeJSONLagrangeSection(ctx context.Context, ptauFile *PtauFile, writer io.Writer, section lagrangeSection) error {
+	if _, err := fmt.Fprintf(writer, ",\n \"%s\": [", section.name); err != nil {
+		return err
+	}
+
+	seekToUniqueSection(ptauFile.Reader, ptauFile.Sections, section.sectionId)
+	reader := bufio.NewReader(ptauFile.Reader)
+	buffer := make([]byte, BN254_FIELD_ELEMENT_SIZE)
+	for p := uint32(0); p <= ptauFile.Header.Power; p++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		separator := ",\n  ["
+		if p == 0 {
+			separator = "\n  ["
+		}
+		if _, err := io.WriteString(writer, separator); err != nil {
+			return err
+		}
+
+		for i := 0; i < 1<<p; i++ {
+			var j any
+			if section.isG2 {
+				point, err := readG2Affine(reader, buffer)
+				if err != nil {
+					return fmt.Errorf("%s point %d: %w", section.name, 1<<p-1+i, err)
+				}
+				j = g2ToJSON(&point)
+			} else {
+				point, err := readG1Affine(reader, buffer)
+				if err != nil {
+					return fmt.Errorf("%s point %d: %w", section.name, 1<<p-1+i, err)
+				}
+				j = g1ToJSON(&point)
+			}
+			b, err := json.MarshalIndent(j, "   ", " ")
+			if err != nil {
+				return err
+			}
+			separator := ",\n   "
+			if i == 0 {
+				separator = "\n   "
+			}
+			if _, err := io.WriteString(writer, separator); err != nil {
+				return err
+			}
+			if _, err := writer.Write(b); err != nil {
+				return err
+			}
+		}
+
+		if _, err := io.WriteString(writer, "\n  ]"); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(writer, "\n ]")
+	return err
+}
+
+// writeJSONSection writes the points read sends as the array of key.
+func writeJSONSection[P any, J any](ctx context.Context, writer io.Writer, key string, read func(context.Context, chan P) error, toJSON func(*P) J) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	points := make(chan P, 1024)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- read(ctx, points)
+	}()
+
+	fail := func(err error) error {
+		cancel()
+		for range points {
+		}
+		<-errs
+		return err
+	}
+
+	if _, err := fmt.Fprintf(writer, ",\n \"%s\": [", key); err != nil {
+		return fail(err)
+	}
+	separator := "\n  "
+	for p := range points {
+		b, err := json.MarshalIndent(toJSON(&p), "  ", " ")
+		if err != nil {
+			return fail(err)
+		}
+		if _, err := io.WriteString(writer, separator); err != nil {
+			return fail(err)
+		}
+		if _, err := writer.Write(b); err != nil {
+			return fail(err)
+		}
+		separator = ",\n  "
+	}
+	if err := <-errs; err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(writer, "\n ]")
+	return err
+}
+
+// WritePtauFromJSON writes the ptau input dumps back to outputPath, streaming
+// the points.
+func WritePtauFromJSON(ctx context.Context, input io.Reader, outputPath string) error {
+	outputFile, err := createAtomicFile(outputPath)
+	if err != nil {
+		return err
+	}
+
+	if err := writePtauFromJSON(ctx, json.NewDecoder(bufio.NewReader(input)), outputFile.File); err != nil {
+		outputFile.Abort()
+		return err
+	}
+
+	return outputFile.Commit()
+}
+
+func writePtauFromJSON(ctx context.Context, decoder *json.Decoder, output *os.File) error {
+	if err := expectDelim(decoder, '{', "ptau"); err != nil {
+		return err
+	}
+
+	var power, ceremonyPower uint32
+	var contributions []PtauContribution
+	var writer *ptauWriter
+	seen := make(map[string]bool)
+
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		key := token.(string)
+		if seen[key] {
+			return &InvalidPtauJSON{Key: key, Reason: "the key appears twice"}
+		}
+		seen[key] = true
+
+		sectionId, isSection := jsonSections[key]
+		lagrange, isLagrange := jsonLagrangeSection(key)
+		if (isSection || isLagrange) && writer == nil {
+			if !seen["power"] {
+				return &InvalidPtauJSON{Key: key, Reason: "the power must come before the points"}
+			}
+			if !seen["ceremonyPower"] {
+				ceremonyPower = power
+			} else if ceremonyPower < power {
+				return &InvalidPtauJSON{Key: "ceremonyPower", Reason: fmt.Sprintf("%d is smaller than the power %d", ceremonyPower, power)}
+			}
+			// the number of sections is set once they are all written
+			writer = newPtauWriter(output, 7, power, ceremonyPower)
+		}
+
+		switch {
+		case key == "q":
+			var q string
+			if err := decoder.Decode(&q); err != nil {
+				return err
+			}
+			if q != fp.Modulus().String() {
+				return &InvalidPtauJSON{Key: key, Reason: "only bn254 is supported"}
+			}
+		case key == "power":
+			if err := decoder.Decode(&power); err != nil {
+				return err
+			}
+			if power < 1 || power > PTAU_MAX_POWER {
+				return &InvalidPtauJSON{Key: key, Reason: fmt.Sprintf("must be between 1 and %d", PTAU_MAX_POWER)}
+			}
+		case key == "ceremonyPower":
+			if writer != nil {
+				return &InvalidPtauJSON{Key: key, Reason: "the ceremony power must come before the points"}
+			}
+			if err := decoder.Decode(&ceremonyPower); err != nil {
+				return err
+			}
+			if ceremonyPower > PTAU_MAX_POWER {
+				return &InvalidPtauJSON{Key: key, Reason: fmt.Sprintf("must be at most %d", PTAU_MAX_POWER)}
+			}
+		case key == "contributions":
+			var jsonContributions []jsonContribution
+			if err := decoder.Decode(&jsonContributions); err != nil {
+				return err
+			}
+			contributions = make([]PtauContribution, len(jsonContributions))
+			for i := range jsonContributions {
+				if contributions[i], err = contributionFromJSON(&jsonContributions[i]); err != nil {
+					return &InvalidPtauJSON{Key: key, Reason: fmt.Sprintf("contribution %d: %v", i+1, err)}
+				}
+			}
+		case isSection:
+			log().Debug("reading section", "section", key)
+			writer.beginSection(sectionId, ptauPointsSize(sectionId, power))
+			if err := readJSONSection(ctx, decoder, writer, key, sectionId, power); err != nil {
+				return err
+			}
+		case isLagrange:
+			// a prepared ptau holds its contributions before the Lagrange sections
+			if !seen["contributions"] {
+				return &InvalidPtauJSON{Key: key, Reason: "the contributions must come before the Lagrange sections"}
+			}
+			if len(writer.Sections) <= 7 || len(writer.Sections[7]) == 0 {
+				writeJSONContributions(writer, contributions)
+			}
+			log().Debug("reading section", "section", key)
+			writer.beginSection(lagrange.sectionId, ptauPointsSize(lagrange.sectionId, power))
+			if err := readJSONLagrangeSection(ctx, decoder, writer, lagrange, power); err != nil {
+				return err
+			}
+		default:
+			log().Warn("skipping unknown key", "key", key)
+			if err := skipJSONValue(decoder); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := expectDelim(decoder, '}', "ptau"); err != nil {
+		return err
+	}
+	for key := range jsonSections {
+		if !seen[key] {
+			return &InvalidPtauJSON{Key: key, Reason: "missing"}
+		}
+	}
+
+	prepared := 0
+	for _, section := range lagrangeSections {
+		if seen[section.name] {
+			prepared++
+		}
+	}
+	if prepared != 0 && prepared != len(lagrangeSections) {
+		return &InvalidPtauJSON{Key: "lTauG1", Reason: "the Lagrange sections must all be there, or none of them"}
+	}
+
+	if prepared == 0 {
+		writeJSONContributions(writer, contributions)
+		return writer.Flush()
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	if _, err := output.WriteAt(binary.LittleEndian.AppendUint32(nil, 11), 8); err != nil {
+		return err
+	}
+	return writeLastLagrangeDomain(ctx, output, writer, power)
+}
+
+// writeJSONContributions writes the contributions section.
+func writeJSONContributions(writer *ptauWriter, contributions []PtauContribution) {
+	section := binary.LittleEndian.AppendUint32(nil, uint32(len(contributions)))
+	for i := range contributions {
+		section = appendContribution(section, &contributions[i])
+	}
+	writer.beginSection(7, uint64(len(section)))
+	writer.Write(section)
+}
+
+// jsonLagrangeSection returns the Lagrange section dumped under key.
+func jsonLagrangeSection(key string) (lagrangeSection, bool) {
+	for _, section := range lagrangeSections {
+		if section.name == key {
+			return section, true
+		}
+	}
+	return lagrangeSection{}, false
+}
+
+// readJSONLagrangeSection writes the points of the domains of section, then
+// zeroes in place of the last domain of lTauG1, which isn't dumped.
+func readJSONLagrangeSection(ctx context.Context, decoder *json.Decoder, writer io.Writer, section lagrangeSection, power uint32) error {
+	if err := expectDelim(decoder, '[', section.name); err != nil {
+		return err
+	}
+	for p := uint32(0); p <= power; p++ {
+		if !decoder.More() {
+			return &InvalidPtauJSON{Key: section.name, Reason: fmt.Sprintf("expected %d domains, got %d", power+1, p)}
+		}
+		if err := readJSONPoints(ctx, decoder, writer, fmt.Sprintf("%s[%d]", section.name, p), section.isG2, 1<<p); err != nil {
+			return err
+		}
+	}
+	if decoder.More() {
+		return &InvalidPtauJSON{Key: section.name, Reason: fmt.Sprintf("more than %d domains", power+1)}
+	}
+	if err := expectDelim(decoder, ']', section.name); err != nil {
+		return err
+	}
+
+	if section.sectionId == 12 {
+		zeroes := make([]byte, 2*BN254_FIELD_ELEMENT_SIZE)
+		for i := 0; i < 2<<power; i++ {
+			if _, err := writer.Write(zeroes); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeLastLagrangeDomain computes the last domain of lTauG1 from tauG1, once
+// both sections are in output.
+func writeLastLagrangeDomain(ctx context.Context, output *os.File, writer *ptauWriter, power uint32) error {
+	points := newLagrangePointsG1()
+	tauG1 := pointsFile[bn254.G1Affine, bn254.G1Jac]{
+		lagrangePoints: points,
+		file:           output,
+		offset:         int64(writer.Sections[2][0].pos),
+		count:          2<<power - 1,
+	}
+	lTauG1 := pointsFile[bn254.G1Affine, bn254.G1Jac]{
+		lagrangePoints: points,
+		file:           output,
+		offset:         int64(writer.Sections[12][0].pos) + int64(2<<power-1)*int64(points.size),
+		count:          2 << power,
+	}
+	maxPoints := int(PREPARE_MEMORY_LIMIT / int64(points.memory))
+
+	log().Info("computing Lagrange points", "section", "lTauG1", "power", power+1, "points", 2<<power)
+	return lagrangeEvaluations[bn254.G1Affine, bn254.G1Jac](ctx, tauG1, lTauG1, power+1, maxPoints)
+}
+
+// readJSONSection writes the points of the array of key, checking that there
+// are as many as the section holds.
+func readJSONSection(ctx context.Context, decoder *json.Decoder, writer io.Writer, key string, sectionId uint32, power uint32) error {
+
+	g2 := sectionId == 3 || sectionId == 6
+	pointSize := uint64(2 * BN254_FIELD_ELEMENT_SIZE)
+	if g2 {
+		pointSize *= 2
+	}
+	return readJSONPoints(ctx, decoder, writer, key, g2, ptauPointsSize(sectionId, power)/pointSize)
+}
+
+// readJSONPoints writes the count points of the array of key.
+func readJSONPoints(ctx context.Context, decoder *json.Decoder, writer io.Writer, key string, g2 bool, count uint64) error {
+	if err := expectDelim(decoder, '[', key); err != nil {
+		return err
+	}
+
+	i := uint64(0)
+	for ; decoder.More(); i++ {
+		if i == count {
+			return &InvalidPtauJSON{Key: key, Reason: fmt.Sprintf("more than %d points", count)}
+		}
+		if i%(1<<16) == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		var b []byte
+		if g2 {
+			var j jsonG2
+			if err := decoder.Decode(&j); err != nil {
+				return err
+			}
+			p, err := g2FromJSON(&j)
+			if err != nil {
+				return &InvalidPtauJSON{Key: key, Reason: fmt.Sprintf("point %d: %v", i, err)}
+			}
+			b = montgomeryLE(p.X.A0, p.X.A1, p.Y.A0, p.Y.A1)
+		} else {
+			var j jsonG1
+			if err := decoder.Decode(&j); err != nil {
+				return err
+			}
+			p, err := g1FromJSON(&j)
+			if err != nil {
+				return &InvalidPtauJSON{Key: key, Reason: fmt.Sprintf("point %d: %v", i, err)}
+			}
+			b = montgomeryLE(p.X, p.Y)
+		}
+		if _, err := writer.Write(b); err != nil {
+			return err
+		}
+	}
+	if i != count {
+		return &InvalidPtauJSON{Key: key, Reason: fmt.Sprintf("expected %d points, got %d", count, i)}
+	}
+
+	return expectDelim(decoder, ']', key)
+}
+
+func expectDelim(decoder *json.Decoder, delim json.Delim, key string) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return &InvalidPtauJSON{Key: key, Reason: fmt.Sprintf("expected %v, got %v", delim, token)}
+	}
+	return nil
+}
+
+// skipJSONValue consumes the next value without holding it in memory.
+func skipJSONValue(decoder *json.Decoder) error {
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('['), json.Delim('{'):
+			depth++
+		case json.Delim(']'), json.Delim('}'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+func g1ToJSON(p *bn254.G1Affine) jsonG1 {
+	if p.IsInfinity() {
+		return jsonG1{"0", "1", "0"}
+	}
+	return jsonG1{p.X.String(), p.Y.String(), "1"}
+}
+
+func g2ToJSON(p *bn254.G2Affine) jsonG2 {
+	if p.IsInfinity() {
+		return jsonG2{{"0", "0"}, {"1", "0"}, {"0", "0"}}
+	}
+	return jsonG2{{p.X.A0.String(), p.X.A1.String()}, {p.Y.A0.String(), p.Y.A1.String()}, {"1", "0"}}
+}
+
+func g1FromJSON(j *jsonG1) (bn254.G1Affine, error) {
+	var p bn254.G1Affine
+	if j[2] == "0" {
+		return p, nil
+	}
+	if j[2] != "1" {
+		return p, fmt.Errorf("z must be 0 or 1, got %s", j[2])
+	}
+	if err := setDecimals([]*fp.Element{&p.X, &p.Y}, j[0], j[1]); err != nil {
+		return p, err
+	}
+	if !p.IsOnCurve() {
+		return p, fmt.Errorf("not on curve")
+	}
+	return p, nil
+}
+
+func g2FromJSON(j *jsonG2) (bn254.G2Affine, error) {
+	var p bn254.G2Affine
+	if j[2] == [2]string{"0", "0"} {
+		return p, nil
+	}
+	if j[2] != [2]string{"1", "0"} {
+		return p, fmt.Errorf("z must be 0 or 1, got %v", j[2])
+	}
+	if err := setDecimals([]*fp.Element{&p.X.A0, &p.X.A1, &p.Y.A0, &p.Y.A1}, j[0][0], j[0][1], j[1][0], j[1][1]); err != nil {
+		return p, err
+	}
+	if !p.IsOnCurve() {
+		return p, fmt.Errorf("not on curve")
+	}
+	return p, nil
+}
+
+// setDecimals parses the coordinates, which must be reduced.
+func setDecimals(elements []*fp.Element, decimals ...string) error {
+	for i, e := range elements {
+		if _, err := e.SetString(decimals[i]); err != nil {
+			return err
+		}
+		if e.String() != decimals[i] {
+			return fmt.Errorf("%s is not a reduced decimal", decimals[i])
+		}
+	}
+	return nil
+}
+
+func contributionToJSON(c *PtauContribution) (jsonContribution, error) {
+	var j jsonContribution
+	j.TauG1 = g1ToJSON(&c.TauG1)
+	j.TauG2 = g2ToJSON(&c.TauG2)
+	j.AlphaG1 = g1ToJSON(&c.AlphaG1)
+	j.BetaG1 = g1ToJSON(&c.BetaG1)
+	j.BetaG2 = g2ToJSON(&c.BetaG2)
+	j.Key.Tau = jsonPublicKeyPart{g1ToJSON(&c.Key.TauG1S), g1ToJSON(&c.Key.TauG1SX), g2ToJSON(&c.Key.TauG2SPX)}
+	j.Key.Alpha = jsonPublicKeyPart{g1ToJSON(&c.Key.AlphaG1S), g1ToJSON(&c.Key.AlphaG1SX), g2ToJSON(&c.Key.AlphaG2SPX)}
+	j.Key.Beta = jsonPublicKeyPart{g1ToJSON(&c.Key.BetaG1S), g1ToJSON(&c.Key.BetaG1SX), g2ToJSON(&c.Key.BetaG2SPX)}
+	j.PartialHash = hashToJSON(c.PartialHash[:])
+	j.NextChallenge = hashToJSON(c.NextChallenge[:])
+	j.Type = c.Type
+	responseHash, err := c.ResponseHash()
+	if err != nil {
+		return j, err
+	}
+	j.ResponseHash = hashToJSON(responseHash[:])
+	j.Name = c.Name
+	j.NumIterationsExp = c.NumIterationsExp
+	if len(c.BeaconHash) > 0 {
+		j.BeaconHash = hashToJSON(c.BeaconHash)
+		if len(c.BeaconHash) != beaconHashSize(new(big.Int).SetBytes(reverseSlice(append([]byte{}, c.BeaconHash...)))) {
+			j.BeaconHashSize = len(c.BeaconHash)
+		}
+	}
+	return j, nil
+}
+
+func contributionFromJSON(j *jsonContribution) (PtauContribution, error) {
+	var c PtauContribution
+	var err error
+
+	for _, p := range []struct {
+		j *jsonG1
+		p *bn254.G1Affine
+	}{
+		{&j.TauG1, &c.TauG1}, {&j.AlphaG1, &c.AlphaG1}, {&j.BetaG1, &c.BetaG1},
+		{&j.Key.Tau.G1S, &c.Key.TauG1S}, {&j.Key.Tau.G1SX, &c.Key.TauG1SX},
+		{&j.Key.Alpha.G1S, &c.Key.AlphaG1S}, {&j.Key.Alpha.G1SX, &c.Key.AlphaG1SX},
+		{&j.Key.Beta.G1S, &c.Key.BetaG1S}, {&j.Key.Beta.G1SX, &c.Key.BetaG1SX},
+	} {
+		if *p.p, err = g1FromJSON(p.j); err != nil {
+			return c, err
+		}
+	}
+	for _, p := range []struct {
+		j *jsonG2
+		p *bn254.G2Affine
+	}{
+		{&j.TauG2, &c.TauG2}, {&j.BetaG2, &c.BetaG2},
+		{&j.Key.Tau.G2SPX, &c.Key.TauG2SPX}, {&j.Key.Alpha.G2SPX, &c.Key.AlphaG2SPX}, {&j.Key.Beta.G2SPX, &c.Key.BetaG2SPX},
+	} {
+		if *p.p, err = g2FromJSON(p.j); err != nil {
+			return c, err
+		}
+	}
+
+	for _, h := range []struct {
+		json  string
+		bytes []byte
+	}{{j.PartialHash, c.PartialHash[:]}, {j.NextChallenge, c.NextChallenge[:]}} {
+		if err := hashFromJSON(h.json, h.bytes); err != nil {
+			return c, err
+		}
+	}
+
+	c.Type = j.Type
+	c.Name = j.Name
+	c.NumIterationsExp = j.NumIterationsExp
+	if j.BeaconHash != "" {
+		var beaconHash big.Int
+		if _, ok := beaconHash.SetString(j.BeaconHash, 10); !ok || beaconHash.Sign() < 0 {
+			return c, fmt.Errorf("beaconHash %q is not a decimal", j.BeaconHash)
+		}
+		size := beaconHashSize(&beaconHash)
+		if j.BeaconHashSize < 0 || j.BeaconHashSize > 255 {
+			return c, fmt.Errorf("beaconHashSize %d is not the size of a hash", j.BeaconHashSize)
+		} else if j.BeaconHashSize != 0 {
+			size = j.BeaconHashSize
+		}
+		c.BeaconHash = make([]byte, size)
+		if err := hashFromJSON(j.BeaconHash, c.BeaconHash); err != nil {
+			return c, err
+		}
+	}
+
+	// the response hash is only dumped, but has to match the contribution
+	if j.ResponseHash != "" {
+		var responseHash [BELLMAN_HASH_SIZE]byte
+		if err := hashFromJSON(j.ResponseHash, responseHash[:]); err != nil {
+			return c, err
+		}
+		if expected, err := c.ResponseHash(); err != nil || expected != responseHash {
+			return c, fmt.Errorf("responseHash doesn't match the contribution")
+		}
+	}
+	return c, nil
+}
+
+// beaconHashSize is the length of a beacon hash of the given value, when the
+// dump doesn't record it.
+func beaconHashSize(beaconHash *big.Int) int {
+	return max(32, (beaconHash.BitLen()+7)/8)
+}
+
+// hashToJSON writes b as the decimal string of its little-endian integer.
+func hashToJSON(b []byte) string {
+	return new(big.Int).SetBytes(reverseSlice(append([]byte{}, b...))).String()
+}
+
+// hashFromJSON reads the decimal string of a little-endian integer into b.
+func hashFromJSON(decimal string, b []byte) error {
+	var n big.Int
+	if _, ok := n.SetString(decimal, 10); !ok || n.Sign() < 0 {
+		return fmt.Errorf("%q is not a decimal", decimal)
+	}
+	if n.BitLen() > 8*len(b) {
+		return fmt.Errorf("%s doesn't fit in a %d byte hash", decimal, len(b))
+	}
+	n.FillBytes(b)
+	reverseSlice(b)
+	return nil
+}
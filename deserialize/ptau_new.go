@@ -0,0 +1,89 @@
+package deserializer
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"golang.org/x/crypto/blake2b"
+)
+
+///////////////////////////////////////////////////////////////////
+///                        SYNTHETIC PTAU                       ///
+///////////////////////////////////////////////////////////////////
+
+// Taken from the iden3/snarkjs repo, powersoftau_new.js. A new ptau holds the
+// generators, here contributed to right away: the points are the generators
+// times the powers of the secrets, computed directly.
+
+// PTAU_MAX_POWER is the largest power snarkjs creates ptau files of.
+const PTAU_MAX_POWER = 28
+
+// newPtauChunkSize is the number of points computed at once.
+var newPtauChunkSize = 1 << 16
+
+// WriteNewPtau writes a ptau of the given power, like snarkjs powersoftau new,
+// followed by one contribution whose secrets are drawn from seed. The same
+// seed always gives the same file, so that conversions can be checked
+// against points computed from the returned secrets.
+func WriteNewPtau(ctx context.Context, power uint32, seed []byte, name string, outputPath string) (PtauSecrets, error) {
+	if power < 1 || power > PTAU_MAX_POWER {
+		return PtauSecrets{}, fmt.Errorf("power must be between 1 and %d, got %d", PTAU_MAX_POWER, power)
+	}
+
+	seedHash := blake2b.Sum512(seed)
+	challengeHash := initialBellmanChallengeHash(power)
+	secrets, key := newPtauKey(newSnarkjsRng(seedHash[:]), challengeHash)
+	contribution := PtauContribution{Key: key, Type: CONTRIBUTION_TYPE_CONTRIBUTION, Name: name}
+
+	outputFile, err := createAtomicFile(outputPath)
+	if err != nil {
+		return secrets, err
+	}
+	if err := writeContributedPtau(ctx, outputFile, power, power, nil, &secrets, challengeHash, &contribution, writeNewPtauSection); err != nil {
+		outputFile.Abort()
+		return secrets, err
+	}
+
+	log().Info("wrote new ptau", "output", outputPath, "power", power)
+	return secrets, outputFile.Commit()
+}
+
+// writeNewPtauSection multiplies the generator by factor*tau^i, the points
+// of a section before any contribution.
+func writeNewPtauSection(ctx context.Context, section contributionSection, tau *fr.Element, writer io.Writer, responseHasher io.Writer) error {
+	_, _, g1Gen, g2Gen := bn254.Generators()
+	scalar := *section.factor
+	scalars := make([]fr.Element, 0, newPtauChunkSize)
+
+	for start := 0; start < section.count; start += newPtauChunkSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		scalars = scalars[:min(newPtauChunkSize, section.count-start)]
+		for i := range scalars {
+			scalars[i] = scalar
+			scalar.Mul(&scalar, tau)
+		}
+
+		if section.g2 {
+			for _, p := range bn254.BatchScalarMultiplicationG2(&g2Gen, scalars) {
+				if _, err := writer.Write(montgomeryLE(p.X.A0, p.X.A1, p.Y.A0, p.Y.A1)); err != nil {
+					return err
+				}
+				responseHasher.Write(bellmanG2CompressedBytes(&p))
+			}
+			continue
+		}
+		for _, p := range bn254.BatchScalarMultiplicationG1(&g1Gen, scalars) {
+			if _, err := writer.Write(montgomeryLE(p.X, p.Y)); err != nil {
+				return err
+			}
+			responseHasher.Write(bellmanG1CompressedBytes(&p))
+		}
+	}
+	return nil
+}
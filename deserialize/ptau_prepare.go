@@ -0,0 +1,504 @@
+package deserializer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+///////////////////////////////////////////////////////////////////
+///                       PREPARE PHASE 2                       ///
+///////////////////////////////////////////////////////////////////
+
+// Taken from the iden3/snarkjs repo, powersoftau_preparephase2.js. A ptau
+// prepared for phase 2 holds 11 sections: the 7 of the ptau, then the points
+// of 4 of them in Lagrange form, for every domain up to the power of the ptau:
+/*
+lTauG1(12)
+    for p in 0..power+1
+        {2 ** p}[
+            L_0(tau)*G1, L_1(tau)*G1, ..., L_{2^p-1}(tau)*G1
+        ]
+lTauG2(13)
+    for p in 0..power
+        {2 ** p}[
+            L_0(tau)*G2, L_1(tau)*G2, ..., L_{2^p-1}(tau)*G2
+        ]
+lAlphaTauG1(14)
+    for p in 0..power
+        {2 ** p}[
+            alpha*L_0(tau)*G1, ..., alpha*L_{2^p-1}(tau)*G1
+        ]
+lBetaTauG1(15)
+    for p in 0..power
+        {2 ** p}[
+            beta*L_0(tau)*G1, ..., beta*L_{2^p-1}(tau)*G1
+        ]
+*/
+// The points of the domain of size 2^p start at point 2^p-1 of the section,
+// they are the inverse FFT of the first 2^p points of the matching section
+// (2, 3, 4, 5). The last domain of lTauG1 is twice as large as the ptau: its
+// inverse FFT is taken over the 2^(power+1)-1 points of tauG1 and a zero
+// point. For a ptau of power 28 that domain is larger than the largest of
+// bn254, snarkjs then takes it over the domain H of size m = 2^28 followed by
+// its coset 25*H, 25 being the square of the smallest non-residue. The points
+// of H, then of 25*H, are the inverse FFTs over H of:
+/*
+t0[i] = (25^m * c[i] - c[i+m]) / (25^m - 1)
+t1[i] = (c[i+m] - c[i]) / (25^i * (25^m - 1))
+*/
+// c being the points of tauG1 and the zero point.
+
+// PREPARE_MEMORY_LIMIT is the default number of bytes of points the inverse
+// FFTs of a prepared ptau hold in memory.
+const PREPARE_MEMORY_LIMIT = int64(4 << 30)
+
+// LAGRANGE_COSET_SHIFT shifts the coset of the largest domain.
+const LAGRANGE_COSET_SHIFT = 25
+
+// lagrangeMaxPower is the power of the largest domain, the Lagrange points of
+// the domain twice as large are taken over it and its coset. Tests lower it
+// to take that path on small ptaus.
+var lagrangeMaxPower = uint32(BN254_TWO_ADICITY)
+
+// lagrangeSection is a section of a prepared ptau, and the section whose
+// points it holds in Lagrange form.
+type lagrangeSection struct {
+	name          string
+	sectionId     uint32
+	ptauSectionId uint32
+	isG2          bool
+}
+
+var lagrangeSections = []lagrangeSection{
+	{"lTauG1", 12, 2, false},
+	{"lTauG2", 13, 3, true},
+	{"lAlphaTauG1", 14, 4, false},
+	{"lBetaTauG1", 15, 5, false},
+}
+
+// preparedForPhase2 reports whether the ptau holds the Lagrange sections.
+func (ptauFile *PtauFile) preparedForPhase2() bool {
+	for _, section := range lagrangeSections {
+		if len(ptauFile.Sections) <= int(section.sectionId) || len(ptauFile.Sections[section.sectionId]) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// PreparePhase2 writes the ptau followed by its Lagrange sections to
+// outputPath. The inverse FFTs hold at most memoryLimit bytes of points in
+// memory, the larger ones are split in two passes over a temporary file next
+// to the output.
+func (ptauFile *PtauFile) PreparePhase2(ctx context.Context, outputPath string, memoryLimit int64) error {
+	power := ptauFile.Header.Power
+	if power > lagrangeMaxPower {
+		return fmt.Errorf("a ptau of power %d can't be prepared, the domains of bn254 are of power at most %d", power, lagrangeMaxPower)
+	}
+	if len(ptauFile.Sections[7]) == 0 {
+		return fmt.Errorf("ptau has no contributions section")
+	}
+
+	ceremonyPower, err := ptauFile.ceremonyPower()
+	if err != nil {
+		return err
+	}
+
+	outputFile, err := createAtomicFile(outputPath)
+	if err != nil {
+		return err
+	}
+	writer := newPtauWriter(outputFile, 11, power, ceremonyPower)
+
+	for sectionId := uint32(2); sectionId <= 7; sectionId++ {
+		size := ptauPointsSize(sectionId, power)
+		if sectionId == 7 {
+			size = ptauFile.Sections[7][0].size
+		}
+		if err := copyPtauSection(ctx, ptauFile, writer, sectionId, size); err != nil {
+			outputFile.Abort()
+			return err
+		}
+	}
+
+	for _, section := range lagrangeSections {
+		if section.isG2 {
+			err = writeLagrangeSection(ctx, ptauFile, writer, outputFile, section, newLagrangePointsG2(), memoryLimit)
+		} else {
+			err = writeLagrangeSection(ctx, ptauFile, writer, outputFile, section, newLagrangePointsG1(), memoryLimit)
+		}
+		if err != nil {
+			outputFile.Abort()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		outputFile.Abort()
+		return err
+	}
+
+	log().Info("wrote ptau prepared for phase 2", "output", outputPath, "power", power)
+	return outputFile.Commit()
+}
+
+// lagrangePoints reads and writes the uncompressed points of a group, and
+// converts them to and from their Jacobian representation.
+type lagrangePoints[A any, J any] struct {
+	// size is the size of a point in a ptau, memory the one of a point being
+	// transformed
+	size, memory int
+	read         func(reader io.Reader, buffer []byte) (A, error)
+	bytes        func(p *A) []byte
+	fromAffine   func(p *J, a *A)
+	toAffine     func(points []J) []A
+}
+
+func newLagrangePointsG1() *lagrangePoints[bn254.G1Affine, bn254.G1Jac] {
+	return &lagrangePoints[bn254.G1Affine, bn254.G1Jac]{
+		size:       2 * BN254_FIELD_ELEMENT_SIZE,
+		memory:     2 * 3 * BN254_FIELD_ELEMENT_SIZE,
+		read:       readG1Affine,
+		bytes:      func(p *bn254.G1Affine) []byte { return montgomeryLE(p.X, p.Y) },
+		fromAffine: func(p *bn254.G1Jac, a *bn254.G1Affine) { p.FromAffine(a) },
+		toAffine:   bn254.BatchJacobianToAffineG1,
+	}
+}
+
+func newLagrangePointsG2() *lagrangePoints[bn254.G2Affine, bn254.G2Jac] {
+	return &lagrangePoints[bn254.G2Affine, bn254.G2Jac]{
+		size:       4 * BN254_FIELD_ELEMENT_SIZE,
+		memory:     2 * 6 * BN254_FIELD_ELEMENT_SIZE,
+		read:       readG2Affine,
+		bytes:      func(p *bn254.G2Affine) []byte { return montgomeryLE(p.X.A0, p.X.A1, p.Y.A0, p.Y.A1) },
+		fromAffine: func(p *bn254.G2Jac, a *bn254.G2Affine) { p.FromAffine(a) },
+		toAffine: func(points []bn254.G2Jac) []bn254.G2Affine {
+			affine := make([]bn254.G2Affine, len(points))
+			parallelize(len(points), func(start, end int) {
+				for i := start; i < end; i++ {
+					affine[i].FromJacobian(&points[i])
+				}
+			})
+			return affine
+		},
+	}
+}
+
+// pointsFile is a run of points of a file, the points past count read as
+// zero.
+type pointsFile[A any, J any] struct {
+	*lagrangePoints[A, J]
+	file   *os.File
+	offset int64
+	count  int
+}
+
+// readAt reads the points from the start-th one into points.
+func (f pointsFile[A, J]) readAt(points []J, start int) error {
+	n := max(0, min(len(points), f.count-start))
+	b := make([]byte, n*f.size)
+	if _, err := f.file.ReadAt(b, f.offset+int64(start)*int64(f.size)); err != nil {
+		return fmt.Errorf("point %d: %w", start, err)
+	}
+
+	errs := make([]error, n)
+	parallelize(n, func(first, end int) {
+		buffer := make([]byte, BN254_FIELD_ELEMENT_SIZE)
+		for i := first; i < end; i++ {
+			var a A
+			a, errs[i] = f.read(bytes.NewReader(b[i*f.size:]), buffer)
+			f.fromAffine(&points[i], &a)
+		}
+	})
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("point %d: %w", start+i, err)
+		}
+	}
+
+	var zero A
+	for i := n; i < len(points); i++ {
+		f.fromAffine(&points[i], &zero)
+	}
+	return nil
+}
+
+// writeAt writes points from the start-th one.
+func (f pointsFile[A, J]) writeAt(points []J, start int) error {
+	affine := f.toAffine(points)
+	b := make([]byte, len(points)*f.size)
+	parallelize(len(points), func(first, end int) {
+		for i := first; i < end; i++ {
+			copy(b[i*f.size:], f.bytes(&affine[i]))
+		}
+	})
+	_, err := f.file.WriteAt(b, f.offset+int64(start)*int64(f.size))
+	return err
+}
+
+// writeLagrangeSection appends section to the prepared ptau, a domain at a
+// time. Its points are written in place in outputFile, behind writer.
+func writeLagrangeSection[A any, J any, PJ jacobian[J]](ctx context.Context, ptauFile *PtauFile, writer *ptauWriter, outputFile *atomicFile, section lagrangeSection, points *lagrangePoints[A, J], memoryLimit int64) error {
+	power := ptauFile.Header.Power
+	size := ptauPointsSize(section.sectionId, power)
+	writer.beginSection(section.sectionId, size)
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	offset := int64(writer.Sections[section.sectionId][0].pos)
+
+	input := pointsFile[A, J]{
+		lagrangePoints: points,
+		file:           ptauFile.Reader,
+		offset:         int64(ptauFile.Sections[section.ptauSectionId][0].pos),
+		count:          int(ptauPointsSize(section.ptauSectionId, power)) / points.size,
+	}
+	maxPoints := int(max(1, memoryLimit/int64(points.memory)))
+
+	last := power
+	if section.sectionId == 12 {
+		last = power + 1
+	}
+	for p := uint32(0); p <= last; p++ {
+		n := 1 << p
+		log().Info("computing Lagrange points", "section", section.name, "power", p, "points", n)
+
+		output := pointsFile[A, J]{lagrangePoints: points, file: outputFile.File, offset: offset + int64(n-1)*int64(points.size), count: n}
+		if err := lagrangeEvaluations[A, J, PJ](ctx, input, output, p, maxPoints); err != nil {
+			return fmt.Errorf("%s power %d: %w", section.name, p, err)
+		}
+	}
+
+	_, err := outputFile.Seek(offset+int64(size), io.SeekStart)
+	return err
+}
+
+// lagrangeEvaluations writes the inverse FFT over the domain of size 2^power
+// of the first points of input to output, holding at most maxPoints points in
+// memory.
+//
+// When the points don't fit, the FFT is split in the four steps of Bailey's
+// algorithm: with n = n1*n2, the input seen as n1 rows of n2 points, the
+// columns are transformed and multiplied by twiddle factors, then the rows
+// are, each pass reading a block of columns or rows at a time. The columns
+// are stored in a temporary file between both passes.
+func lagrangeEvaluations[A any, J any, PJ jacobian[J]](ctx context.Context, input, output pointsFile[A, J], power uint32, maxPoints int) error {
+	if power > lagrangeMaxPower {
+		return lagrangeCosetEvaluations[A, J, PJ](ctx, input, output, power, maxPoints)
+	}
+
+	n := 1 << power
+	var omegaInv, nInv fr.Element
+	omega := rootOfUnity(power)
+	omegaInv.Inverse(&omega)
+	nInv.SetUint64(uint64(n)).Inverse(&nInv)
+
+	if n <= maxPoints {
+		points := make([]J, n)
+		if err := input.readAt(points, 0); err != nil {
+			return err
+		}
+		fftJacobian[J, PJ](points, omegaInv)
+		scaleJacobian[J, PJ](points, nInv)
+		return output.writeAt(points, 0)
+	}
+
+	n1 := 1 << (power / 2)
+	n2 := n / n1
+	if n2 > maxPoints {
+		return fmt.Errorf("the inverse FFT of %d points needs memory for at least %d points, got %d", n, n2, maxPoints)
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(output.file.Name()), "lagrange.*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		temp.Close()
+		os.Remove(temp.Name())
+	}()
+	columns := pointsFile[A, J]{lagrangePoints: input.lagrangePoints, file: temp, count: n}
+
+	// the column j2 holds the points n2*j1+j2, its transform is stored as
+	// the column of the rows of the temporary file
+	var omegaN2 fr.Element
+	omegaN2.Exp(omegaInv, big.NewInt(int64(n2)))
+	c := min(maxPoints/n1, n2)
+	points := make([]J, c*n1)
+	run := make([]J, c)
+	for start := 0; start < n2; start += c {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		m := min(c, n2-start)
+		for j1 := 0; j1 < n1; j1++ {
+			if err := input.readAt(run[:m], n2*j1+start); err != nil {
+				return err
+			}
+			for t := 0; t < m; t++ {
+				points[t*n1+j1] = run[t]
+			}
+		}
+
+		parallelize(m, func(first, end int) {
+			var w, twiddle fr.Element
+			var scalar big.Int
+			for t := first; t < end; t++ {
+				column := points[t*n1 : (t+1)*n1]
+				fftJacobian[J, PJ](column, omegaN2)
+
+				// column k1 is multiplied by omega^(j2*k1)
+				w.Exp(omegaInv, big.NewInt(int64(start+t)))
+				twiddle.SetOne()
+				for k1 := 1; k1 < n1; k1++ {
+					twiddle.Mul(&twiddle, &w)
+					twiddle.BigInt(&scalar)
+					PJ(&column[k1]).ScalarMultiplication(&column[k1], &scalar)
+				}
+			}
+		})
+
+		for k1 := 0; k1 < n1; k1++ {
+			for t := 0; t < m; t++ {
+				run[t] = points[t*n1+k1]
+			}
+			if err := columns.writeAt(run[:m], n2*k1+start); err != nil {
+				return err
+			}
+		}
+	}
+
+	// the row k1 gives the points k1+n1*k2
+	var omegaN1 fr.Element
+	omegaN1.Exp(omegaInv, big.NewInt(int64(n1)))
+	r := min(maxPoints/n2, n1)
+	points = make([]J, r*n2)
+	run = make([]J, r)
+	for start := 0; start < n1; start += r {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		m := min(r, n1-start)
+		if err := columns.readAt(points[:m*n2], n2*start); err != nil {
+			return err
+		}
+
+		parallelize(m, func(first, end int) {
+			for t := first; t < end; t++ {
+				fftJacobian[J, PJ](points[t*n2:(t+1)*n2], omegaN1)
+			}
+		})
+		scaleJacobian[J, PJ](points[:m*n2], nInv)
+
+		for k2 := 0; k2 < n2; k2++ {
+			for t := 0; t < m; t++ {
+				run[t] = points[t*n2+k2]
+			}
+			if err := output.writeAt(run[:m], start+n1*k2); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// lagrangeCosetEvaluations writes the Lagrange points of the domain of size
+// 2^power, twice the largest one, over the largest domain and its coset like
+// snarkjs. t0 and t1 are written in place of the points of both halves, whose
+// inverse FFTs then read all their input before writing any output.
+func lagrangeCosetEvaluations[A any, J any, PJ jacobian[J]](ctx context.Context, input, output pointsFile[A, J], power uint32, maxPoints int) error {
+	m := 1 << (power - 1)
+	var shift, shiftInv, shiftM, denomInv, shiftMOverDenom fr.Element
+	shift.SetUint64(LAGRANGE_COSET_SHIFT)
+	shiftInv.Inverse(&shift)
+	shiftM.Exp(shift, big.NewInt(int64(m)))
+	denomInv.SetOne()
+	denomInv.Sub(&shiftM, &denomInv).Inverse(&denomInv)
+	shiftMOverDenom.Mul(&shiftM, &denomInv)
+
+	halves := [2]pointsFile[A, J]{
+		{lagrangePoints: output.lagrangePoints, file: output.file, offset: output.offset, count: m},
+		{lagrangePoints: output.lagrangePoints, file: output.file, offset: output.offset + int64(m)*int64(output.size), count: m},
+	}
+	c := max(1, min(m, maxPoints/3))
+	low := make([]J, c)
+	high := make([]J, c)
+	diff := make([]J, c)
+	for start := 0; start < m; start += c {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n := min(c, m-start)
+		if err := input.readAt(low[:n], start); err != nil {
+			return err
+		}
+		if err := input.readAt(high[:n], m+start); err != nil {
+			return err
+		}
+
+		// t0, in place of the low points
+		parallelize(n, func(first, end int) {
+			var a, b big.Int
+			shiftMOverDenom.BigInt(&a)
+			denomInv.BigInt(&b)
+			var t J
+			for i := first; i < end; i++ {
+				PJ(&diff[i]).Set(&high[i])
+				PJ(&diff[i]).SubAssign(&low[i])
+				PJ(&low[i]).ScalarMultiplication(&low[i], &a)
+				PJ(&t).ScalarMultiplication(&high[i], &b)
+				PJ(&low[i]).SubAssign(&t)
+			}
+		})
+
+		// t1, in place of the high points
+		parallelize(n, func(first, end int) {
+			var w fr.Element
+			var scalar big.Int
+			w.Exp(shiftInv, big.NewInt(int64(start+first))).Mul(&w, &denomInv)
+			for i := first; i < end; i++ {
+				w.BigInt(&scalar)
+				PJ(&high[i]).ScalarMultiplication(&diff[i], &scalar)
+				w.Mul(&w, &shiftInv)
+			}
+		})
+
+		if err := halves[0].writeAt(low[:n], start); err != nil {
+			return err
+		}
+		if err := halves[1].writeAt(high[:n], start); err != nil {
+			return err
+		}
+	}
+
+	for _, half := range halves {
+		if err := lagrangeEvaluations[A, J, PJ](ctx, half, half, power-1, maxPoints); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rootOfUnity is the 2^power-th root of unity of the domains of snarkjs and
+// gnark. gnark only computes it along with the twiddles of a domain, which
+// don't fit in memory for the largest powers.
+func rootOfUnity(power uint32) fr.Element {
+	var omega fr.Element
+	// of order 2^BN254_TWO_ADICITY
+	omega.SetString("19103219067921713944291392827692070036145651957329286315305642004821462161904")
+	for i := power; i < BN254_TWO_ADICITY; i++ {
+		omega.Square(&omega)
+	}
+	return omega
+}
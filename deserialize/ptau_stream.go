@@ -0,0 +1,205 @@
+package deserializer
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	curve "github.com/consensys/gnark-crypto/ecc/bn254"
+)
+
+///////////////////////////////////////////////////////////////////
+///                         PTAU STREAM                         ///
+///////////////////////////////////////////////////////////////////
+
+// A .ptau file is a sequence of sections, each prefixed by its id and size:
+//
+//	4 bytes, "ptau"
+//	4 bytes, version
+//	4 bytes, number of sections
+//	{number of sections}[
+//	    4 bytes, section id
+//	    8 bytes, section size
+//	    {section size} bytes, section data
+//	]
+//
+// snarkjs writes the header (1) before any of the point sections, so once it
+// has been read the position of every point in the .ph1 output is known and
+// the conversion can be done in a single forward pass over the input.
+
+const ptauMagic = "ptau"
+
+// WritePhase1FromPtauReader converts a .ptau file read from any io.Reader
+// (stdin, an HTTP body, an object store stream...) into the .ph1 format,
+// without ever seeking the input. It stops when ctx is cancelled.
+func WritePhase1FromPtauReader(ctx context.Context, input io.Reader, outputPath string, opts ...ConvertOption) error {
+	outputFile, err := createAtomicFile(outputPath)
+	if err != nil {
+		return err
+	}
+
+	if err := writePhase1FromPtauReader(ctx, input, outputFile.File, newConvertOptions(opts)); err != nil {
+		outputFile.Abort()
+		return err
+	}
+
+	return outputFile.Commit()
+}
+
+func writePhase1FromPtauReader(ctx context.Context, input io.Reader, outputFile *os.File, options convertOptions) error {
+	reader := bufio.NewReaderSize(input, 1<<20)
+	tracker := newProgressTracker(options.progress)
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(reader, magic); err != nil {
+		return err
+	}
+	if string(magic) != ptauMagic {
+		return fmt.Errorf("invalid magic %q, expected %q", magic, ptauMagic)
+	}
+
+	// version
+	if _, err := readULE32(reader); err != nil {
+		return err
+	}
+
+	numSections, err := readULE32(reader)
+	if err != nil {
+		return err
+	}
+
+	var header *PtauHeader
+	var layout phase1Layout
+	var contributions uint16
+	seen := make(map[uint32]bool)
+
+	for i := uint32(0); i < numSections; i++ {
+		sectionId, err := readULE32(reader)
+		if err != nil {
+			return err
+		}
+		sectionSize, err := readULE64(reader)
+		if err != nil {
+			return err
+		}
+		section := &io.LimitedReader{R: reader, N: int64(sectionSize)}
+
+		// the sections read can't be checked against the ones that follow,
+		// so a ptau split or padded differently than snarkjs writes it is
+		// refused before any of its points are written
+		if sectionId >= 1 && sectionId <= 7 {
+			if seen[sectionId] {
+				return fmt.Errorf("ptau section %d appears more than once", sectionId)
+			}
+			seen[sectionId] = true
+		}
+		if sectionId >= 2 && sectionId <= 6 && header != nil {
+			if size := ptauPointsSize(sectionId, header.Power); sectionSize != size {
+				return fmt.Errorf("ptau section %d holds %d bytes, a ptau of power %d holds %d", sectionId, sectionSize, header.Power, size)
+			}
+		}
+
+		switch sectionId {
+		case 1:
+			ptauHeader, err := readPtauHeader(section)
+			if err != nil {
+				return err
+			}
+			if err := checkBN254(ptauHeader.Curve(), ptauHeader.N8, &ptauHeader.Prime); err != nil {
+				return err
+			}
+			if ptauHeader.Power < 1 || ptauHeader.Power > PTAU_MAX_POWER {
+				return fmt.Errorf("ptau power %d is not between 1 and %d", ptauHeader.Power, PTAU_MAX_POWER)
+			}
+			header = &ptauHeader
+			layout = newPhase1Layout(byte(header.Power))
+		case 2, 3, 4, 5, 6:
+			if header == nil {
+				return fmt.Errorf("section %d appears before the ptau header", sectionId)
+			}
+			if err := streamPtauSection(ctx, section, outputFile, layout, header.Power, sectionId, tracker); err != nil {
+				return fmt.Errorf("section %d: %w", sectionId, err)
+			}
+		case 7:
+			numContributions, err := readULE32(section)
+			if err != nil {
+				return fmt.Errorf("section 7: %w", err)
+			}
+			if contributions, err = phase1ContributionCount(numContributions); err != nil {
+				return err
+			}
+		}
+
+		// skip whatever is left of the section (contributions, unknown sections...)
+		skipped, err := io.Copy(io.Discard, section)
+		if err != nil {
+			return err
+		}
+		tracker.read(skipped)
+		if section.N != 0 {
+			return fmt.Errorf("ptau section %d ends %d bytes short", sectionId, section.N)
+		}
+	}
+
+	for sectionId := uint32(2); sectionId <= 7; sectionId++ {
+		if !seen[sectionId] {
+			return fmt.Errorf("ptau stream is missing section %d", sectionId)
+		}
+	}
+
+	var phase1Header Header
+	phase1Header.Power = byte(header.Power)
+	phase1Header.Contributions = contributions
+
+	return phase1Header.writeTo(io.NewOffsetWriter(outputFile, 0))
+}
+
+// streamPtauSection copies the points of one ptau section to their place in
+// the .ph1 output.
+func streamPtauSection(ctx context.Context, reader io.Reader, outputFile *os.File, layout phase1Layout, power uint32, sectionId uint32, tracker *progressTracker) error {
+	var index int
+	for index = range phase1Sections {
+		if phase1Sections[index].ptauSectionId == sectionId {
+			break
+		}
+	}
+	section := phase1Sections[index]
+	numPoints := section.numPoints(1 << power)
+
+	writer := bufio.NewWriterSize(io.NewOffsetWriter(outputFile, layout.offsets()[index]), 1<<20)
+	enc := curve.NewEncoder(writer)
+	buffer := make([]byte, BN254_FIELD_ELEMENT_SIZE)
+
+	for i := 0; i < numPoints; i++ {
+		if i%pointsPerUpdate == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			tracker.report(section.name, i, numPoints)
+		}
+
+		if section.isG2 {
+			point, err := readG2Affine(reader, buffer)
+			if err != nil {
+				return fmt.Errorf("point %d: %w", i, err)
+			}
+			if err := enc.Encode(&point); err != nil {
+				return err
+			}
+		} else {
+			point, err := readG1Affine(reader, buffer)
+			if err != nil {
+				return fmt.Errorf("point %d: %w", i, err)
+			}
+			if err := enc.Encode(&point); err != nil {
+				return err
+			}
+		}
+		tracker.read(section.ptauPointSize())
+	}
+	tracker.report(section.name, numPoints, numPoints)
+
+	return writer.Flush()
+}
@@ -0,0 +1,101 @@
+package deserializer
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+///////////////////////////////////////////////////////////////////
+///                          TRUNCATE                           ///
+///////////////////////////////////////////////////////////////////
+
+// Taken from the iden3/snarkjs repo, powersoftau_truncate.js. A ptau of a
+// smaller power holds the prefixes of the point sections of the original, and
+// its contributions unchanged:
+/*
+header        power = the smaller power, ceremonyPower unchanged
+tauG1         (2^power*2-1) points
+tauG2         2^power points
+alphaTauG1    2^power points
+betaTauG1     2^power points
+betaG2        1 point
+contributions copied
+lTauG1        the domains up to power+1, (2^power*4-1) points
+lTauG2        the domains up to power, (2^power*2-1) points
+lAlphaTauG1   the domains up to power, (2^power*2-1) points
+lBetaTauG1    the domains up to power, (2^power*2-1) points
+*/
+// The Lagrange sections are only there when the ptau was prepared for phase 2,
+// see ptau_prepare.go. The last domain of lTauG1 then comes from the points of
+// the original tauG1 instead of a zero point, like snarkjs truncates it.
+// snarkjs only checks the last challenge hash of a ptau whose power is its
+// ceremony power, so the contributions still verify.
+
+// truncateCopySize is the number of bytes copied between two checks of the
+// context.
+const truncateCopySize = 1 << 24
+
+// Truncate writes the ptau reduced to the given power, smaller than the one of
+// ptauFile, to outputPath.
+func (ptauFile *PtauFile) Truncate(ctx context.Context, power uint32, outputPath string) error {
+	if power < 1 || power >= ptauFile.Header.Power {
+		return fmt.Errorf("can only truncate a ptau of power %d to a power between 1 and %d, got %d", ptauFile.Header.Power, ptauFile.Header.Power-1, power)
+	}
+	if len(ptauFile.Sections[7]) == 0 {
+		return fmt.Errorf("ptau has no contributions section")
+	}
+
+	ceremonyPower, err := ptauFile.ceremonyPower()
+	if err != nil {
+		return err
+	}
+
+	outputFile, err := createAtomicFile(outputPath)
+	if err != nil {
+		return err
+	}
+	sectionIds := []uint32{2, 3, 4, 5, 6, 7}
+	numSections := uint32(7)
+	if ptauFile.preparedForPhase2() {
+		sectionIds = append(sectionIds, 12, 13, 14, 15)
+		numSections = 11
+	}
+	writer := newPtauWriter(outputFile, numSections, power, ceremonyPower)
+
+	for _, sectionId := range sectionIds {
+		size := ptauPointsSize(sectionId, power)
+		if sectionId == 7 {
+			size = ptauFile.Sections[7][0].size
+		}
+		if err := copyPtauSection(ctx, ptauFile, writer, sectionId, size); err != nil {
+			outputFile.Abort()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		outputFile.Abort()
+		return err
+	}
+
+	log().Info("wrote truncated ptau", "output", outputPath, "power", power, "ceremonyPower", ceremonyPower)
+	return outputFile.Commit()
+}
+
+// copyPtauSection copies the first size bytes of a section of ptauFile to a
+// section of writer with the same id.
+func copyPtauSection(ctx context.Context, ptauFile *PtauFile, writer *ptauWriter, sectionId uint32, size uint64) error {
+	log().Debug("copying section", "section", sectionId, "bytes", size)
+
+	writer.beginSection(sectionId, size)
+	seekToUniqueSection(ptauFile.Reader, ptauFile.Sections, sectionId)
+	for copied := uint64(0); copied < size; copied += truncateCopySize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := io.CopyN(writer, ptauFile.Reader, int64(min(truncateCopySize, size-copied))); err != nil {
+			return fmt.Errorf("copying section %d: %w", sectionId, err)
+		}
+	}
+	return nil
+}
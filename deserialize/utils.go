@@ -6,12 +6,13 @@ import (
 	"math/big"
 
 	fp "github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
 )
 
 func readULE32(reader io.Reader) (uint32, error) {
 	var buffer = make([]byte, 4)
 
-	_, err := reader.Read(buffer)
+	_, err := io.ReadFull(reader, buffer)
 
 	if err != nil {
 		return 0, err
@@ -23,7 +24,7 @@ func readULE32(reader io.Reader) (uint32, error) {
 func readULE64(reader io.Reader) (uint64, error) {
 	var buffer = make([]byte, 8)
 
-	_, err := reader.Read(buffer)
+	_, err := io.ReadFull(reader, buffer)
 
 	if err != nil {
 		return 0, err
@@ -35,7 +36,7 @@ func readULE64(reader io.Reader) (uint64, error) {
 func readBigInt(reader io.Reader, n8 uint32) (big.Int, error) {
 	var buffer = make([]byte, n8)
 
-	_, err := reader.Read(buffer)
+	_, err := io.ReadFull(reader, buffer)
 	reverseSlice(buffer)
 
 	if err != nil {
@@ -69,3 +70,38 @@ func bytesToElement(b []byte) fp.Element {
 
 	return z
 }
+
+// readElement reads a base field element laid out the way snarkjs stores
+// coordinates in .ptau files: 32 little-endian bytes already in Montgomery
+// form, so the limbs can be copied into the fp.Element as they are.
+func readElement(reader io.Reader, buffer []byte) (fp.Element, error) {
+	var z fp.Element
+
+	if _, err := io.ReadFull(reader, buffer[:BN254_FIELD_ELEMENT_SIZE]); err != nil {
+		return z, err
+	}
+
+	z[0] = binary.LittleEndian.Uint64(buffer[0:8])
+	z[1] = binary.LittleEndian.Uint64(buffer[8:16])
+	z[2] = binary.LittleEndian.Uint64(buffer[16:24])
+	z[3] = binary.LittleEndian.Uint64(buffer[24:32])
+
+	return z, nil
+}
+
+// readFrElement reads a scalar field element, which snarkjs stores like the
+// coordinates of the points: 32 little-endian bytes in Montgomery form.
+func readFrElement(reader io.Reader, buffer []byte) (fr.Element, error) {
+	var z fr.Element
+
+	if _, err := io.ReadFull(reader, buffer[:BN254_FIELD_ELEMENT_SIZE]); err != nil {
+		return z, err
+	}
+
+	z[0] = binary.LittleEndian.Uint64(buffer[0:8])
+	z[1] = binary.LittleEndian.Uint64(buffer[8:16])
+	z[2] = binary.LittleEndian.Uint64(buffer[16:24])
+	z[3] = binary.LittleEndian.Uint64(buffer[24:32])
+
+	return z, nil
+}
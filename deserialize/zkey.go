@@ -1,12 +1,13 @@
 package deserializer
 
 import (
-	"errors"
 	"fmt"
 	"io"
-	"math"
 	"math/big"
+	"math/bits"
 	"os"
+
+	"github.com/consensys/gnark-crypto/ecc"
 )
 
 ///////////////////////////////////////////////////////////////////
@@ -40,7 +41,19 @@ import (
 //      delta2
 
 const GROTH_16_PROTOCOL_ID = uint32(1)
+const PLONK_PROTOCOL_ID = uint32(2)
+const FFLONK_PROTOCOL_ID = uint32(10)
+
+// BN254_TWO_ADICITY is the largest power of two dividing r-1, the scalar
+// field of bn254 has no larger power of two domain
+const BN254_TWO_ADICITY = 28
 
+// MAX_FIELD_ELEMENT_SIZE bounds the n8 of the headers, no supported curve has
+// larger field elements
+const MAX_FIELD_ELEMENT_SIZE = 64
+
+// Deprecated: zkeys of other protocols than Groth16 are no longer rejected,
+// ReadZkey returns UnsupportedProtocol for protocols it doesn't know.
 type NotGroth16 struct {
 	Err error
 }
@@ -49,26 +62,111 @@ func (r *NotGroth16) Error() string {
 	return fmt.Sprintf("Groth16 is the only supported protocol at this time (PLONK and FFLONK are not): %v", r.Err)
 }
 
+// UnsupportedProtocol is returned for zkeys of another protocol than Groth16,
+// PLONK and FFLONK.
+type UnsupportedProtocol struct {
+	ProtocolID uint32
+}
+
+func (r *UnsupportedProtocol) Error() string {
+	return fmt.Sprintf("unknown zkey protocol %d, only Groth16 (1), PLONK (2) and FFLONK (10) are supported", r.ProtocolID)
+}
+
+// InvalidZkeyHeader is returned when the header of a zkey can't be right,
+// usually because the file is corrupted.
+type InvalidZkeyHeader struct {
+	Err error
+}
+
+func (r *InvalidZkeyHeader) Error() string {
+	return fmt.Sprintf("invalid zkey header: %v", r.Err)
+}
+
+func (r *InvalidZkeyHeader) Unwrap() error {
+	return r.Err
+}
+
 // Incomplete (only extracts necessary fields for conversion to .ph1 format)
 type Zkey struct {
-	ZkeyHeader     ZkeyHeader
+	ZkeyHeader ZkeyHeader
+	// the fields all protocols share, from n8q to domainSize
 	protocolHeader HeaderGroth
 }
 
 type ZkeyHeader struct {
 	ProtocolID     uint32
 	protocolHeader HeaderGroth
+	plonkHeader    HeaderPlonk
+	fflonkHeader   HeaderFflonk
 }
 
+// HeaderGroth is the Groth16 header (section 2) of a zkey.
 type HeaderGroth struct {
-	n8q        uint32
-	q          big.Int
-	n8r        uint32
-	r          big.Int
-	nVars      uint32
-	nPublic    uint32
-	domainSize uint32
-	power      uint32
+	// N8q is the size in bytes of an element of the base field
+	N8q uint32
+	// Q is the modulus of the base field
+	Q big.Int
+	// N8r is the size in bytes of an element of the scalar field
+	N8r uint32
+	// R is the modulus of the scalar field
+	R big.Int
+	// NVars is the number of variables of the circuit, including the constant one
+	NVars uint32
+	// NPublic is the number of public inputs and outputs of the circuit
+	NPublic uint32
+	// DomainSize is the size of the evaluation domain, 2^Power
+	DomainSize uint32
+	Power      uint32
+}
+
+// Groth16Header returns the Groth16 header of the zkey, ok is false when the
+// zkey is for another protocol.
+func (zkey Zkey) Groth16Header() (header HeaderGroth, ok bool) {
+	return zkey.protocolHeader, zkey.ZkeyHeader.ProtocolID == GROTH_16_PROTOCOL_ID
+}
+
+// PlonkHeader returns the PLONK header of the zkey, ok is false when the zkey
+// is for another protocol.
+func (zkey Zkey) PlonkHeader() (header HeaderPlonk, ok bool) {
+	return zkey.ZkeyHeader.plonkHeader, zkey.ZkeyHeader.ProtocolID == PLONK_PROTOCOL_ID
+}
+
+// FflonkHeader returns the FFLONK header of the zkey, ok is false when the
+// zkey is for another protocol.
+func (zkey Zkey) FflonkHeader() (header HeaderFflonk, ok bool) {
+	return zkey.ZkeyHeader.fflonkHeader, zkey.ZkeyHeader.ProtocolID == FFLONK_PROTOCOL_ID
+}
+
+// Curve returns the curve the zkey is defined over, ecc.UNKNOWN if its base
+// and scalar fields match none of the curves known to gnark-crypto.
+func (zkey Zkey) Curve() ecc.ID {
+	return zkey.protocolHeader.Curve()
+}
+
+// Curve returns the curve identified from the q and r primes of the header.
+func (header HeaderGroth) Curve() ecc.ID {
+	return curveFromFields(header.N8q, &header.Q, header.N8r, &header.R)
+}
+
+// DomainSize returns the size of the evaluation domain of the circuit.
+func (zkey Zkey) DomainSize() uint32 {
+	return zkey.protocolHeader.DomainSize
+}
+
+// Power returns the log2 of the domain size, i.e. the power of the smallest
+// ptau that can be used with the circuit.
+func (zkey Zkey) Power() uint32 {
+	return zkey.protocolHeader.Power
+}
+
+// NPublic returns the number of public inputs and outputs of the circuit.
+func (zkey Zkey) NPublic() uint32 {
+	return zkey.protocolHeader.NPublic
+}
+
+// NVars returns the number of variables of the circuit.
+func (zkey Zkey) NVars() uint32 {
+	return zkey.protocolHeader.NVars
 }
 
 type SectionSegment struct {
@@ -88,14 +186,14 @@ func ReadZkey(zkeyPath string) (Zkey, error) {
 	// zkey
 	var zkeyStr = make([]byte, 4)
 	_, err = reader.Read(zkeyStr)
-	fmt.Printf("zkeyStr: %s \n", string(zkeyStr))
+	log().Debug("read magic", "magic", string(zkeyStr))
 
 	// version
 	_, err = readULE32(reader)
 
 	// number of sections
 	numSections, err := readULE32(reader)
-	fmt.Printf("num sections: %v \n", numSections)
+	log().Debug("read number of sections", "sections", numSections)
 
 	// in practice, all sections have only one segment, but who knows...
 	// 1-based indexing, so we need to allocate one more than the number of sections
@@ -103,8 +201,7 @@ func ReadZkey(zkeyPath string) (Zkey, error) {
 	for i := uint32(0); i < numSections; i++ {
 		ht, _ := readULE32(reader)
 		hl, _ := readULE64(reader)
-		fmt.Printf("ht: %v \n", ht)
-		fmt.Printf("hl: %v \n", hl)
+		log().Debug("read section", "id", ht, "size", hl)
 		if sections[ht] == nil {
 			sections[ht] = make([]SectionSegment, 0)
 		}
@@ -113,7 +210,7 @@ func ReadZkey(zkeyPath string) (Zkey, error) {
 		reader.Seek(int64(hl), io.SeekCurrent)
 	}
 
-	fmt.Printf("sections: %v \n", sections)
+	log().Debug("read section table", "sections", sections)
 
 	// section size
 	_, err = readBigInt(reader, 8)
@@ -125,6 +222,10 @@ func ReadZkey(zkeyPath string) (Zkey, error) {
 		return Zkey{}, err
 	}
 
+	if err := checkBN254(header.protocolHeader.Curve(), header.protocolHeader.N8q, &header.protocolHeader.Q); err != nil {
+		return Zkey{}, err
+	}
+
 	zkey := Zkey{ZkeyHeader: header, protocolHeader: header.protocolHeader}
 
 	return zkey, nil
@@ -149,8 +250,8 @@ func readHeader(reader io.ReadSeeker, sections [][]SectionSegment) (ZkeyHeader,
 		return header, err
 	}
 
-	// if groth16
-	if protocolID == GROTH_16_PROTOCOL_ID {
+	switch protocolID {
+	case GROTH_16_PROTOCOL_ID:
 		seekToUniqueSection(reader, sections, 2)
 		headerGroth, err := readHeaderGroth16(reader)
 
@@ -160,24 +261,48 @@ func readHeader(reader io.ReadSeeker, sections [][]SectionSegment) (ZkeyHeader,
 
 		header = ZkeyHeader{ProtocolID: protocolID, protocolHeader: headerGroth}
 
-	} else {
-		return header, &NotGroth16{Err: errors.New("ProtocolID is not Groth16")}
+	case PLONK_PROTOCOL_ID:
+		seekToUniqueSection(reader, sections, 2)
+		headerPlonk, err := readHeaderPlonk(reader)
+
+		if err != nil {
+			return header, err
+		}
+
+		header = ZkeyHeader{ProtocolID: protocolID, protocolHeader: headerPlonk.HeaderGroth, plonkHeader: headerPlonk}
+
+	case FFLONK_PROTOCOL_ID:
+		seekToUniqueSection(reader, sections, 2)
+		headerFflonk, err := readHeaderFflonk(reader)
+
+		if err != nil {
+			return header, err
+		}
+
+		header = ZkeyHeader{ProtocolID: protocolID, protocolHeader: headerFflonk.HeaderGroth, fflonkHeader: headerFflonk}
+
+	default:
+		return header, &UnsupportedProtocol{ProtocolID: protocolID}
 	}
 
 	return header, nil
 }
 
-func readHeaderGroth16(reader io.ReadSeeker) (HeaderGroth, error) {
+func readHeaderGroth16(reader io.Reader) (HeaderGroth, error) {
 	var header = HeaderGroth{}
 
 	n8q, err := readULE32(reader)
 
-	fmt.Printf("n8q is: %v \n", n8q)
+	log().Debug("read groth16 header", "n8q", n8q)
 
 	if err != nil {
 		return header, err
 	}
 
+	if n8q == 0 || n8q > MAX_FIELD_ELEMENT_SIZE {
+		return header, &InvalidZkeyHeader{Err: fmt.Errorf("n8q %d is not the size of a field element", n8q)}
+	}
+
 	q, err := readBigInt(reader, n8q)
 
 	if err != nil {
@@ -190,6 +315,10 @@ func readHeaderGroth16(reader io.ReadSeeker) (HeaderGroth, error) {
 		return header, err
 	}
 
+	if n8r == 0 || n8r > MAX_FIELD_ELEMENT_SIZE {
+		return header, &InvalidZkeyHeader{Err: fmt.Errorf("n8r %d is not the size of a field element", n8r)}
+	}
+
 	r, err := readBigInt(reader, n8r)
 
 	if err != nil {
@@ -214,11 +343,19 @@ func readHeaderGroth16(reader io.ReadSeeker) (HeaderGroth, error) {
 		return header, err
 	}
 
-	power := math.Log2(float64(domainSize))
+	// the domain is a multiplicative subgroup of the scalar field, its size
+	// has to be a power of two no larger than 2^two-adicity
+	if domainSize == 0 || domainSize&(domainSize-1) != 0 {
+		return header, &InvalidZkeyHeader{Err: fmt.Errorf("domainSize %d is not a power of two", domainSize)}
+	}
+
+	power := uint32(bits.TrailingZeros32(domainSize))
 
-	power_int := uint32(math.Ceil(power))
+	if power > BN254_TWO_ADICITY {
+		return header, &InvalidZkeyHeader{Err: fmt.Errorf("domainSize 2^%d is larger than the largest domain of the curve, 2^%d", power, BN254_TWO_ADICITY)}
+	}
 
-	header = HeaderGroth{n8q: n8q, q: q, n8r: n8r, r: r, nVars: nVars, nPublic: nPublic, domainSize: domainSize, power: power_int}
+	header = HeaderGroth{N8q: n8q, Q: q, N8r: n8r, R: r, NVars: nVars, NPublic: nPublic, DomainSize: domainSize, Power: power}
 
 	return header, nil
 }
@@ -0,0 +1,389 @@
+package deserializer
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+///////////////////////////////////////////////////////////////////
+///                        PLONK / FFLONK                       ///
+///////////////////////////////////////////////////////////////////
+
+// Taken from the iden3/snarkjs repo, plonk_setup.js and zkey_utils.js (readHeaderPlonk)
+// https://github.com/iden3/snarkjs/blob/master/src/plonk_setup.js
+// Format
+// ======
+// Header(1)
+// 4 bytes, Prover Type 2 PLONK
+// HeaderPlonk(2)
+// 4 bytes, n8q
+// n8q bytes, q
+// 4 bytes, n8r
+// n8r bytes, r
+// 4 bytes, NVars
+// 4 bytes, NPub
+// 4 bytes, DomainSize (power of 2)
+// 4 bytes, NAdditions
+// 4 bytes, NConstraints
+// n8r bytes, k1
+// n8r bytes, k2
+//      Qm, Ql, Qr, Qo, Qc (G1)
+//      S1, S2, S3 (G1)
+//      X_2 (G2)
+// Additions(3)
+//      {NAdditions}[4 bytes signalId1, 4 bytes signalId2, n8r bytes factor1, n8r bytes factor2]
+// AMap(4), BMap(5), CMap(6)
+//      {NConstraints}[4 bytes signalId]
+// QM(7), QL(8), QR(9), QO(10), QC(11)
+//      {DomainSize} coefficients, then {4*DomainSize} evaluations on the extended domain
+// Sigma(12)
+//      S1, S2, S3, each laid out like the Q polynomials
+// Lagrange(13)
+//      {NPub} Lagrange polynomials, laid out like the Q polynomials
+// PTau(14)
+//      {DomainSize+6}[tau^i*G1]
+//
+// FFLONK zkeys (protocol 10) start with the same header up to k2, followed by
+// w3, w4, w8, wr (n8r bytes each), X_2 (G2) and C0 (G1).
+
+const (
+	plonkAdditionsSection = 3
+	plonkAMapSection      = 4
+	plonkBMapSection      = 5
+	plonkCMapSection      = 6
+	plonkQmSection        = 7
+	plonkQlSection        = 8
+	plonkQrSection        = 9
+	plonkQoSection        = 10
+	plonkQcSection        = 11
+	plonkSigmaSection     = 12
+	plonkLagrangeSection  = 13
+	plonkPTauSection      = 14
+)
+
+// HeaderPlonk is the PLONK header (section 2) of a zkey.
+type HeaderPlonk struct {
+	// HeaderGroth holds the fields PLONK shares with Groth16, from N8q to DomainSize
+	HeaderGroth
+	NAdditions   uint32
+	NConstraints uint32
+	// K1 and K2 shift the domain to label the wires of B and C in the permutation
+	K1 fr.Element
+	K2 fr.Element
+	// Commitments to the selector and permutation polynomials
+	Qm bn254.G1Affine
+	Ql bn254.G1Affine
+	Qr bn254.G1Affine
+	Qo bn254.G1Affine
+	Qc bn254.G1Affine
+	S1 bn254.G1Affine
+	S2 bn254.G1Affine
+	S3 bn254.G1Affine
+	// X2 is tau*G2
+	X2 bn254.G2Affine
+}
+
+// HeaderFflonk is the FFLONK header (section 2) of a zkey.
+type HeaderFflonk struct {
+	// HeaderGroth holds the fields FFLONK shares with Groth16, from N8q to DomainSize
+	HeaderGroth
+	NAdditions   uint32
+	NConstraints uint32
+	K1           fr.Element
+	K2           fr.Element
+	// Roots of unity of order 3, 4, 8 and of the opening domain
+	W3 fr.Element
+	W4 fr.Element
+	W8 fr.Element
+	Wr fr.Element
+	X2 bn254.G2Affine
+	// C0 commits to the preprocessed polynomials
+	C0 bn254.G1Affine
+}
+
+// PlonkAddition defines an internal signal of the circuit as
+// Factor1*SignalID1 + Factor2*SignalID2.
+type PlonkAddition struct {
+	SignalID1 uint32
+	SignalID2 uint32
+	Factor1   fr.Element
+	Factor2   fr.Element
+}
+
+// PlonkPolynomial is a polynomial of degree less than the domain size.
+type PlonkPolynomial struct {
+	Coefficients []fr.Element
+	// Evaluations on the domain, taken from the evaluations on the extended
+	// domain of size 4*DomainSize that the zkey stores
+	Evaluations []fr.Element
+}
+
+// PlonkZkey is a PLONK zkey with all of its sections.
+type PlonkZkey struct {
+	Header    HeaderPlonk
+	Additions []PlonkAddition
+	// signals wired to the a, b and c inputs of each constraint
+	AMap     []uint32
+	BMap     []uint32
+	CMap     []uint32
+	Qm       PlonkPolynomial
+	Ql       PlonkPolynomial
+	Qr       PlonkPolynomial
+	Qo       PlonkPolynomial
+	Qc       PlonkPolynomial
+	Sigma    [3]PlonkPolynomial
+	Lagrange []PlonkPolynomial
+	// PTau holds tau^i*G1 for i up to DomainSize+6
+	PTau []bn254.G1Affine
+}
+
+// ReadPlonkZkey reads all the sections of a PLONK zkey.
+func ReadPlonkZkey(zkeyPath string) (*PlonkZkey, error) {
+	file, err := os.Open(zkeyPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(file, magic); err != nil {
+		return nil, err
+	}
+	if string(magic) != "zkey" {
+		return nil, fmt.Errorf("%s is not a zkey", zkeyPath)
+	}
+
+	_, sections, err := readSectionTable(file, stat.Size())
+	if err != nil {
+		return nil, err
+	}
+
+	section, err := openSection(file, sections, 1)
+	if err != nil {
+		return nil, err
+	}
+	protocolID, err := readULE32(section)
+	if err != nil {
+		return nil, err
+	}
+	if protocolID != PLONK_PROTOCOL_ID {
+		return nil, fmt.Errorf("%s is not a PLONK zkey (protocol %d)", zkeyPath, protocolID)
+	}
+
+	var zkey PlonkZkey
+
+	if section, err = openSection(file, sections, 2); err != nil {
+		return nil, err
+	}
+	if zkey.Header, err = readHeaderPlonk(section); err != nil {
+		return nil, err
+	}
+	n := int(zkey.Header.DomainSize)
+	buffer := make([]byte, BN254_FIELD_ELEMENT_SIZE)
+
+	if section, err = openSection(file, sections, plonkAdditionsSection); err != nil {
+		return nil, err
+	}
+	zkey.Additions = make([]PlonkAddition, zkey.Header.NAdditions)
+	for i := range zkey.Additions {
+		if zkey.Additions[i], err = readPlonkAddition(section, buffer); err != nil {
+			return nil, fmt.Errorf("addition %d: %w", i, err)
+		}
+	}
+
+	for i, m := range []*[]uint32{&zkey.AMap, &zkey.BMap, &zkey.CMap} {
+		if section, err = openSection(file, sections, uint32(plonkAMapSection+i)); err != nil {
+			return nil, err
+		}
+		*m = make([]uint32, zkey.Header.NConstraints)
+		for j := range *m {
+			if (*m)[j], err = readULE32(section); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for i, p := range []*PlonkPolynomial{&zkey.Qm, &zkey.Ql, &zkey.Qr, &zkey.Qo, &zkey.Qc} {
+		if section, err = openSection(file, sections, uint32(plonkQmSection+i)); err != nil {
+			return nil, err
+		}
+		if *p, err = readPlonkPolynomial(section, n, buffer); err != nil {
+			return nil, fmt.Errorf("section %d: %w", plonkQmSection+i, err)
+		}
+	}
+
+	if section, err = openSection(file, sections, plonkSigmaSection); err != nil {
+		return nil, err
+	}
+	for i := range zkey.Sigma {
+		if zkey.Sigma[i], err = readPlonkPolynomial(section, n, buffer); err != nil {
+			return nil, fmt.Errorf("sigma %d: %w", i+1, err)
+		}
+	}
+
+	if section, err = openSection(file, sections, plonkLagrangeSection); err != nil {
+		return nil, err
+	}
+	zkey.Lagrange = make([]PlonkPolynomial, zkey.Header.NPublic)
+	for i := range zkey.Lagrange {
+		if zkey.Lagrange[i], err = readPlonkPolynomial(section, n, buffer); err != nil {
+			return nil, fmt.Errorf("lagrange polynomial %d: %w", i, err)
+		}
+	}
+
+	for _, s := range sections {
+		if s.ID != plonkPTauSection {
+			continue
+		}
+		if s.Size%(2*BN254_FIELD_ELEMENT_SIZE) != 0 {
+			return nil, fmt.Errorf("PTau section of size %d doesn't hold a whole number of G1 points", s.Size)
+		}
+		zkey.PTau = make([]bn254.G1Affine, s.Size/(2*BN254_FIELD_ELEMENT_SIZE))
+	}
+	if section, err = openSection(file, sections, plonkPTauSection); err != nil {
+		return nil, err
+	}
+	for i := range zkey.PTau {
+		if zkey.PTau[i], err = readG1Affine(section, buffer); err != nil {
+			return nil, fmt.Errorf("PTau point %d: %w", i, err)
+		}
+	}
+
+	return &zkey, nil
+}
+
+func readHeaderPlonk(reader io.Reader) (HeaderPlonk, error) {
+	var header HeaderPlonk
+	var err error
+
+	if header.HeaderGroth, err = readHeaderGroth16(reader); err != nil {
+		return header, err
+	}
+
+	// the rest of the header is made of bn254 points and scalars
+	if err := checkBN254(header.Curve(), header.N8q, &header.Q); err != nil {
+		return header, err
+	}
+
+	if header.NAdditions, err = readULE32(reader); err != nil {
+		return header, err
+	}
+	if header.NConstraints, err = readULE32(reader); err != nil {
+		return header, err
+	}
+	if header.NConstraints > header.DomainSize {
+		return header, &InvalidZkeyHeader{Err: fmt.Errorf("%d constraints don't fit in a domain of size %d", header.NConstraints, header.DomainSize)}
+	}
+
+	buffer := make([]byte, BN254_FIELD_ELEMENT_SIZE)
+	for _, k := range []*fr.Element{&header.K1, &header.K2} {
+		if *k, err = readFrElement(reader, buffer); err != nil {
+			return header, err
+		}
+	}
+	for _, p := range []*bn254.G1Affine{&header.Qm, &header.Ql, &header.Qr, &header.Qo, &header.Qc, &header.S1, &header.S2, &header.S3} {
+		if *p, err = readG1Affine(reader, buffer); err != nil {
+			return header, err
+		}
+	}
+	if header.X2, err = readG2Affine(reader, buffer); err != nil {
+		return header, err
+	}
+
+	log().Debug("read plonk header", "nAdditions", header.NAdditions, "nConstraints", header.NConstraints)
+
+	return header, nil
+}
+
+func readHeaderFflonk(reader io.Reader) (HeaderFflonk, error) {
+	var header HeaderFflonk
+	var err error
+
+	if header.HeaderGroth, err = readHeaderGroth16(reader); err != nil {
+		return header, err
+	}
+
+	if err := checkBN254(header.Curve(), header.N8q, &header.Q); err != nil {
+		return header, err
+	}
+
+	if header.NAdditions, err = readULE32(reader); err != nil {
+		return header, err
+	}
+	if header.NConstraints, err = readULE32(reader); err != nil {
+		return header, err
+	}
+
+	buffer := make([]byte, BN254_FIELD_ELEMENT_SIZE)
+	for _, k := range []*fr.Element{&header.K1, &header.K2, &header.W3, &header.W4, &header.W8, &header.Wr} {
+		if *k, err = readFrElement(reader, buffer); err != nil {
+			return header, err
+		}
+	}
+	if header.X2, err = readG2Affine(reader, buffer); err != nil {
+		return header, err
+	}
+	if header.C0, err = readG1Affine(reader, buffer); err != nil {
+		return header, err
+	}
+
+	log().Debug("read fflonk header", "nAdditions", header.NAdditions, "nConstraints", header.NConstraints)
+
+	return header, nil
+}
+
+func readPlonkAddition(reader io.Reader, buffer []byte) (PlonkAddition, error) {
+	var addition PlonkAddition
+	var err error
+
+	if addition.SignalID1, err = readULE32(reader); err != nil {
+		return addition, err
+	}
+	if addition.SignalID2, err = readULE32(reader); err != nil {
+		return addition, err
+	}
+	if addition.Factor1, err = readFrElement(reader, buffer); err != nil {
+		return addition, err
+	}
+	if addition.Factor2, err = readFrElement(reader, buffer); err != nil {
+		return addition, err
+	}
+
+	return addition, nil
+}
+
+// readPlonkPolynomial reads the n coefficients and the 4n extended
+// evaluations of a polynomial, keeping one evaluation out of four: the
+// extended domain is generated by a 4n-th root of unity w, so w^(4i) walks
+// the domain.
+func readPlonkPolynomial(reader io.Reader, n int, buffer []byte) (PlonkPolynomial, error) {
+	p := PlonkPolynomial{Coefficients: make([]fr.Element, n), Evaluations: make([]fr.Element, n)}
+	var err error
+
+	for i := range p.Coefficients {
+		if p.Coefficients[i], err = readFrElement(reader, buffer); err != nil {
+			return p, err
+		}
+	}
+
+	for i := 0; i < 4*n; i++ {
+		e, err := readFrElement(reader, buffer)
+		if err != nil {
+			return p, err
+		}
+		if i%4 == 0 {
+			p.Evaluations[i/4] = e
+		}
+	}
+
+	return p, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v2"
+	deserializer "github.com/worldcoin/ptau-deserializer/deserialize"
+)
+
+var diffCommand = &cli.Command{
+	Name:      "diff",
+	Usage:     "Compare the headers, contributions and points of two .ptau or .ph1 files",
+	ArgsUsage: "FILE1 FILE2",
+	Action: func(cCtx *cli.Context) error {
+		if cCtx.NArg() != 2 {
+			return fmt.Errorf("diff expects exactly two FILEs")
+		}
+
+		diff, err := deserializer.Diff(cCtx.Context, cCtx.Args().Get(0), cCtx.Args().Get(1), cCtx.Int("max-indices"))
+		if err != nil {
+			return err
+		}
+
+		if cCtx.Bool("json") {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			err = enc.Encode(diff)
+		} else {
+			err = printDiff(os.Stdout, diff)
+		}
+		if err != nil {
+			return err
+		}
+
+		// like diff(1), exit with 1 when the files differ
+		if !diff.Equal() {
+			return cli.Exit("", 1)
+		}
+		return nil
+	},
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:  "max-indices",
+			Usage: "Report up to `N` differing indices per section",
+			Value: deserializer.DIFF_MAX_INDICES,
+		},
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "Print the comparison as JSON",
+		},
+	},
+}
+
+func printDiff(w io.Writer, diff *deserializer.FileDiff) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "types:\t%s\t%s\n", diff.Types[0], diff.Types[1])
+	fmt.Fprintf(tw, "power:\t%d\t%d\n", diff.Powers[0], diff.Powers[1])
+	fmt.Fprintf(tw, "contributions:\t%d\t%d\n", diff.Contributions[0], diff.Contributions[1])
+	if len(diff.ContributionMismatches) > 0 {
+		fmt.Fprintf(tw, "differing contributions:\t%v\n", diff.ContributionMismatches)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SECTION\tPOINTS\tCOMPARED\tMISMATCHES\tFIRST INDICES")
+	for _, section := range diff.Sections {
+		points := fmt.Sprint(section.Counts[0])
+		if section.Counts[0] != section.Counts[1] {
+			points = fmt.Sprintf("%d/%d", section.Counts[0], section.Counts[1])
+		}
+		indices := "-"
+		if len(section.FirstMismatches) > 0 {
+			indices = fmt.Sprint(section.FirstMismatches)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\n", section.Name, points, section.Compared, section.Mismatches, indices)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if diff.Equal() {
+		fmt.Fprintln(w, "\nthe files are equal")
+	}
+	return nil
+}
@@ -1,6 +1,6 @@
 module github.com/worldcoin/ptau-deserializer
 
-go 1.20
+go 1.21
 
 require github.com/consensys/gnark v0.8.0
 
@@ -8,6 +8,7 @@ require (
 	github.com/consensys/gnark-crypto v0.9.1
 	github.com/stretchr/testify v1.8.4
 	github.com/urfave/cli/v2 v2.25.7
+	golang.org/x/crypto v0.17.0
 )
 
 require (
@@ -27,7 +28,7 @@ require (
 	github.com/russross/blackfriday/v2 v2.1.0 // indirect
 	github.com/x448/float16 v0.8.4 // indirect
 	github.com/xrash/smetrics v0.0.0-20201216005158-039620a65673 // indirect
-	golang.org/x/sys v0.9.0 // indirect
+	golang.org/x/sys v0.15.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 	rsc.io/tmplfunc v0.0.3 // indirect
 )
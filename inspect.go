@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v2"
+	deserializer "github.com/worldcoin/ptau-deserializer/deserialize"
+)
+
+var inspectCommand = &cli.Command{
+	Name:      "inspect",
+	Aliases:   []string{"i"},
+	Usage:     "Print the metadata of a .ptau, .zkey, .r1cs, .wtns or .ph1 file",
+	ArgsUsage: "FILE",
+	Action: func(cCtx *cli.Context) error {
+		if cCtx.NArg() != 1 {
+			return fmt.Errorf("inspect expects exactly one FILE")
+		}
+
+		info, err := deserializer.Inspect(cCtx.Args().First())
+		if err != nil {
+			return err
+		}
+
+		if cCtx.Bool("json") {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(info)
+		}
+
+		return printFileInfo(os.Stdout, info)
+	},
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "Print the metadata as JSON",
+		},
+	},
+}
+
+func printFileInfo(w io.Writer, info *deserializer.FileInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "type:\t%s\n", info.Type)
+	fmt.Fprintf(tw, "size:\t%d\n", info.Size)
+	if info.Type != "ph1" {
+		fmt.Fprintf(tw, "version:\t%d\n", info.Version)
+	}
+
+	switch {
+	case info.Ptau != nil:
+		fmt.Fprintf(tw, "curve:\t%s\n", info.Ptau.Curve)
+		fmt.Fprintf(tw, "n8:\t%d\n", info.Ptau.N8)
+		fmt.Fprintf(tw, "prime:\t%s\n", info.Ptau.Prime)
+		fmt.Fprintf(tw, "power:\t%d\n", info.Ptau.Power)
+		fmt.Fprintf(tw, "ceremony power:\t%d\n", info.Ptau.CeremonyPower)
+	case info.Zkey != nil:
+		fmt.Fprintf(tw, "protocol:\t%s (%d)\n", info.Zkey.Protocol, info.Zkey.ProtocolID)
+		if info.Zkey.Curve != "" {
+			fmt.Fprintf(tw, "curve:\t%s\n", info.Zkey.Curve)
+			fmt.Fprintf(tw, "n8q:\t%d\n", info.Zkey.N8q)
+			fmt.Fprintf(tw, "q:\t%s\n", info.Zkey.Q)
+			fmt.Fprintf(tw, "n8r:\t%d\n", info.Zkey.N8r)
+			fmt.Fprintf(tw, "r:\t%s\n", info.Zkey.R)
+			fmt.Fprintf(tw, "nVars:\t%d\n", info.Zkey.NVars)
+			fmt.Fprintf(tw, "nPublic:\t%d\n", info.Zkey.NPublic)
+			fmt.Fprintf(tw, "domainSize:\t%d\n", info.Zkey.DomainSize)
+			fmt.Fprintf(tw, "power:\t%d\n", info.Zkey.Power)
+		}
+		if info.Zkey.ProtocolID != deserializer.GROTH_16_PROTOCOL_ID && info.Zkey.Curve != "" {
+			fmt.Fprintf(tw, "nAdditions:\t%d\n", info.Zkey.NAdditions)
+			fmt.Fprintf(tw, "nConstraints:\t%d\n", info.Zkey.NConstraints)
+		}
+	case info.R1CS != nil:
+		fmt.Fprintf(tw, "n8:\t%d\n", info.R1CS.N8)
+		fmt.Fprintf(tw, "prime:\t%s\n", info.R1CS.Prime)
+		fmt.Fprintf(tw, "nWires:\t%d\n", info.R1CS.NWires)
+		fmt.Fprintf(tw, "nPubOut:\t%d\n", info.R1CS.NPubOut)
+		fmt.Fprintf(tw, "nPubIn:\t%d\n", info.R1CS.NPubIn)
+		fmt.Fprintf(tw, "nPrvIn:\t%d\n", info.R1CS.NPrvIn)
+		fmt.Fprintf(tw, "nLabels:\t%d\n", info.R1CS.NLabels)
+		fmt.Fprintf(tw, "nConstraints:\t%d\n", info.R1CS.NConstraints)
+	case info.Wtns != nil:
+		fmt.Fprintf(tw, "n8:\t%d\n", info.Wtns.N8)
+		fmt.Fprintf(tw, "prime:\t%s\n", info.Wtns.Prime)
+		fmt.Fprintf(tw, "nWitness:\t%d\n", info.Wtns.NWitness)
+	case info.Phase1 != nil:
+		fmt.Fprintf(tw, "power:\t%d\n", info.Phase1.Power)
+		fmt.Fprintf(tw, "contributions:\t%d\n", info.Phase1.Contributions)
+		fmt.Fprintf(tw, "trailing bytes:\t%d\n", info.Phase1.TrailingBytes)
+	}
+
+	if len(info.Sections) > 0 {
+		fmt.Fprintf(tw, "\nsection\toffset\tsize\n")
+		for _, section := range info.Sections {
+			fmt.Fprintf(tw, "%d\t%d\t%d\n", section.ID, section.Offset, section.Size)
+		}
+	}
+
+	if info.Ptau != nil {
+		fmt.Fprintf(tw, "\ncontribution\ttype\tname\tnext challenge\n")
+		for _, c := range info.Ptau.Contributions {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", c.Index, c.Type, c.Name, c.NextChallenge)
+		}
+	}
+
+	return tw.Flush()
+}
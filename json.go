@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v2"
+	deserializer "github.com/worldcoin/ptau-deserializer/deserialize"
+)
+
+var exportJSONCommand = &cli.Command{
+	Name:  "export-json",
+	Usage: "Dump a .ptau as JSON, in the layout of snarkjs powersoftau export json",
+	Action: func(cCtx *cli.Context) error {
+		if cCtx.String("input") == "-" {
+			return fmt.Errorf("export-json needs a seekable --input, not stdin")
+		}
+
+		file, err := deserializer.InitPtau(cCtx.String("input"))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		return deserializer.WriteJSONFromPtauFile(cCtx.Context, file, cCtx.String("output"))
+	},
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "input",
+			Aliases:  []string{"i"},
+			Usage:    "Load the `FILE`.ptau to dump",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "output",
+			Aliases:  []string{"o"},
+			Usage:    "Write the dump to `FILE`.json",
+			Required: true,
+		},
+	},
+}
+
+var importJSONCommand = &cli.Command{
+	Name:  "import-json",
+	Usage: "Write a .ptau back from its JSON dump",
+	Action: func(cCtx *cli.Context) error {
+		input := os.Stdin
+		if path := cCtx.String("input"); path != "-" {
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			input = file
+		}
+
+		return deserializer.WritePtauFromJSON(cCtx.Context, input, cCtx.String("output"))
+	},
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "input",
+			Aliases:  []string{"i"},
+			Usage:    "Load the `FILE`.json dump (- reads it from stdin)",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "output",
+			Aliases:  []string{"o"},
+			Usage:    "Write the ptau to `FILE`.ptau",
+			Required: true,
+		},
+	},
+}
@@ -1,9 +1,18 @@
 package main
 
 import (
+	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
 
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
 	"github.com/urfave/cli/v2"
 	deserializer "github.com/worldcoin/ptau-deserializer/deserialize"
 )
@@ -15,22 +24,122 @@ func main() {
 		Action: func(*cli.Context) error {
 			return nil
 		},
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:    "verbose",
+				Aliases: []string{"v"},
+				Usage:   "Log debug diagnostics",
+			},
+			&cli.BoolFlag{
+				Name:    "quiet",
+				Aliases: []string{"q"},
+				Usage:   "Only log errors and hide the progress bar",
+			},
+			&cli.StringFlag{
+				Name:  "log-format",
+				Usage: "Format of the logs written to stderr, `text` or json",
+				Value: "text",
+			},
+		},
+		Before: setupLogger,
 		Commands: []*cli.Command{
+			inspectCommand,
+			convertPlonkCommand,
+			newCommand,
+			contributeCommand,
+			beaconCommand,
+			exportJSONCommand,
+			importJSONCommand,
+			diffCommand,
+			checkDerivationCommand,
+			verifyCommand,
+			truncateCommand,
+			preparePhase2Command,
 			{
 				Name:    "convert",
 				Aliases: []string{"c"},
-				Usage:   "Deserialize .ptau file into .ph1 format and write to `OUTPUT`",
+				Usage:   "Deserialize .ptau file into .ph1 format, or a KZG SRS for PLONK, and write to `OUTPUT`",
 				Action: func(cCtx *cli.Context) error {
 					ptauFilePath := cCtx.String("input")
 					outputFilePath := cCtx.String("output")
 
+					switch inputFormat := cCtx.String("input-format"); inputFormat {
+					case "ptau":
+					case "bellman":
+						if cCtx.String("format") != "ph1" {
+							return fmt.Errorf("bellman challenges and responses can only be converted to ph1")
+						}
+						if cCtx.IsSet("powers") {
+							return fmt.Errorf("--powers can't be used with --input-format bellman, a bellman file converts to the .ph1 of its own power")
+						}
+					case "ignition":
+						switch cCtx.String("format") {
+						case "ph1":
+							return &deserializer.NoGroth16Sections{Ceremony: "Aztec Ignition"}
+						case "halo2", "halo2-raw", "bellman":
+							return fmt.Errorf("Aztec Ignition transcripts can only be converted to kzg")
+						}
+					default:
+						return fmt.Errorf("unknown input format %q, expected ptau, bellman or ignition", inputFormat)
+					}
+
+					switch format := cCtx.String("format"); format {
+					case "ph1":
+					case "kzg":
+						return convertToKZGSRS(cCtx)
+					case "kzg-lagrange":
+						// a Lagrange SRS in the kzg.SRS layout would be loaded as a canonical one
+						return fmt.Errorf("--format kzg-lagrange isn't supported: gnark-crypto v0.9.1 has no file format for a Lagrange SRS, and gnark would read one in the kzg.SRS layout as a canonical SRS, then build wrong PLONK keys")
+					case "halo2", "halo2-raw":
+						return convertToHalo2Params(cCtx, format)
+					case "bellman":
+						return convertToBellman(cCtx)
+					default:
+						return fmt.Errorf("unknown format %q, expected ph1, kzg, halo2, halo2-raw or bellman", format)
+					}
+
+					var progress deserializer.ConvertOption
+					if cCtx.Bool("quiet") {
+						progress = deserializer.WithProgress(nil)
+					} else {
+						progress = deserializer.WithProgress(newProgressBar(os.Stderr))
+					}
+
+					if cCtx.String("input-format") == "bellman" {
+						return convertBellmanToPhase1(cCtx, progress)
+					}
+
+					if cCtx.IsSet("powers") {
+						return convertToPhase1s(cCtx, progress)
+					}
+
+					// stream the ptau from stdin, it never has to be staged on disk
+					if ptauFilePath == "-" {
+						if cCtx.Bool("resume") {
+							return fmt.Errorf("--resume needs a seekable --input, not stdin")
+						}
+						err := deserializer.WritePhase1FromPtauReader(cCtx.Context, os.Stdin, outputFilePath, progress)
+						if err != nil {
+							return err
+						}
+						return nil
+					}
+
 					file, err := deserializer.InitPtau(ptauFilePath)
 					if err != nil {
-						panic(err)
+						return err
+					}
+					defer file.Close()
+					if cCtx.Bool("resume") {
+						err = deserializer.ResumePhase1FromPtauFile(cCtx.Context, file, outputFilePath, progress)
+					} else {
+						err = deserializer.WritePhase1FromPtauFile(cCtx.Context, file, outputFilePath, progress)
+					}
+					if errors.Is(err, context.Canceled) {
+						return errors.New("conversion interrupted, continue it with --resume")
 					}
-					err = deserializer.WritePhase1FromPtauFile(file, outputFilePath)
 					if err != nil {
-						panic(err)
+						return err
 					}
 
 					//ptau, err := deserializer.ReadPtau(ptauFilePath)
@@ -58,7 +167,7 @@ func main() {
 					&cli.StringFlag{
 						Name:     "input",
 						Aliases:  []string{"i"},
-						Usage:    "Load `FILE`.ptau to convert to .ph1",
+						Usage:    "Load `FILE`.ptau to convert to .ph1 (- reads the ptau from stdin), or a bellman challenge or response with --input-format bellman",
 						Required: true,
 					},
 					&cli.StringFlag{
@@ -67,12 +176,281 @@ func main() {
 						Usage:    "File output for the phase 1 conversion (`FILE`.ph1)",
 						Required: true,
 					},
+					&cli.BoolFlag{
+						Name:  "resume",
+						Usage: "Continue an interrupted conversion from the checkpoint stored next to the output",
+					},
+					&cli.StringFlag{
+						Name:  "input-format",
+						Usage: "Input `FORMAT`: ptau, bellman for the challenge and response files of Perpetual Powers of Tau, or ignition for the directory of Aztec Ignition transcripts",
+						Value: "ptau",
+					},
+					&cli.StringFlag{
+						Name:  "previous",
+						Usage: "Check that the bellman input starts with the hash of `FILE`, the challenge or response it follows",
+					},
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "Output `FORMAT`: ph1 for Groth16, kzg for gnark's PLONK KZG SRS, halo2 and halo2-raw for halo2 ParamsKZG in the Processed and RawBytes serde formats, bellman for a Perpetual Powers of Tau response",
+						Value: "ph1",
+					},
+					&cli.StringFlag{
+						Name:  "powers",
+						Usage: "Write one .ph1 per power of `LIST`, such as 10-24 or 10,12,16, in a single pass over the ptau. The output must hold a {power} placeholder",
+					},
+					&cli.IntFlag{
+						Name:  "size",
+						Usage: "Number of G1 points of the KZG SRS, defaults to all of them",
+					},
+					&cli.UintFlag{
+						Name:  "k",
+						Usage: "log2 of the number of points of the halo2 params, defaults to the power of the ptau",
+					},
 				},
 			},
 		},
 	}
 
-	if err := app.Run(os.Args); err != nil {
-		fmt.Printf("%s", err)
+	// stop the conversions cleanly on ^C, so that they can be resumed
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := app.RunContext(ctx, os.Args); err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+}
+
+// convertBellmanToPhase1 writes the .ph1 of the bellman challenge or response
+// given to convert, once its leading hash has been checked.
+func convertBellmanToPhase1(cCtx *cli.Context, progress deserializer.ConvertOption) error {
+	if cCtx.String("input") == "-" {
+		return fmt.Errorf("--input-format bellman needs a seekable --input, not stdin")
+	}
+
+	file, err := deserializer.InitBellman(cCtx.String("input"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if previousPath := cCtx.String("previous"); previousPath != "" {
+		previous, err := deserializer.InitBellman(previousPath)
+		if err != nil {
+			return err
+		}
+		defer previous.Close()
+		if err := file.CheckPreviousHash(previous); err != nil {
+			return err
+		}
+	} else if !file.IsInitialChallenge() {
+		slog.Warn("the leading hash of the input is not checked, pass the file it follows with --previous")
+	}
+
+	if cCtx.Bool("resume") {
+		err = deserializer.ResumePhase1FromBellmanFile(cCtx.Context, file, cCtx.String("output"), progress)
+	} else {
+		err = deserializer.WritePhase1FromBellmanFile(cCtx.Context, file, cCtx.String("output"), progress)
+	}
+	if errors.Is(err, context.Canceled) {
+		return errors.New("conversion interrupted, continue it with --resume")
+	}
+	return err
+}
+
+// convertToPhase1s writes the .ph1 of every power given to convert, reading
+// the ptau once.
+func convertToPhase1s(cCtx *cli.Context, progress deserializer.ConvertOption) error {
+	if cCtx.String("input") == "-" || cCtx.Bool("resume") {
+		return fmt.Errorf("--powers needs a seekable --input and can't be resumed")
+	}
+	output := cCtx.String("output")
+	if !strings.Contains(output, "{power}") {
+		return fmt.Errorf("--output must hold a {power} placeholder when converting to several powers")
+	}
+
+	powers, err := parsePowers(cCtx.String("powers"))
+	if err != nil {
+		return err
+	}
+	outputPaths := make(map[uint32]string, len(powers))
+	for _, power := range powers {
+		outputPaths[power] = strings.ReplaceAll(output, "{power}", fmt.Sprintf("%02d", power))
+	}
+
+	file, err := deserializer.InitPtau(cCtx.String("input"))
+	if err != nil {
+		return err
 	}
+	defer file.Close()
+
+	return deserializer.WritePhase1sFromPtauFile(cCtx.Context, file, outputPaths, progress)
+}
+
+// parsePowers parses a comma-separated list of powers and ranges of powers,
+// such as 10-24 or 8,10,12-14.
+func parsePowers(list string) ([]uint32, error) {
+	var powers []uint32
+	for _, item := range strings.Split(list, ",") {
+		first, last, isRange := strings.Cut(strings.TrimSpace(item), "-")
+		from, err := strconv.ParseUint(first, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid power %q in %q", first, list)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.ParseUint(last, 10, 8); err != nil || to < from {
+				return nil, fmt.Errorf("invalid range of powers %q in %q", item, list)
+			}
+		}
+		for power := from; power <= to; power++ {
+			powers = append(powers, uint32(power))
+		}
+	}
+	return powers, nil
+}
+
+// convertToKZGSRS writes the KZG SRS of the ptau or the Ignition transcripts
+// given to convert.
+func convertToKZGSRS(cCtx *cli.Context) error {
+	if cCtx.String("input") == "-" || cCtx.Bool("resume") {
+		return fmt.Errorf("--format kzg needs a seekable --input and can't be resumed")
+	}
+
+	var srs *kzg.SRS
+	var err error
+	if cCtx.String("input-format") == "ignition" {
+		srs, err = ignitionToKZGSRS(cCtx)
+	} else {
+		srs, err = ptauToKZGSRS(cCtx)
+	}
+	if err != nil {
+		return err
+	}
+
+	return deserializer.WriteKZGSRS(srs, cCtx.String("output"))
+}
+
+func ptauToKZGSRS(cCtx *cli.Context) (*kzg.SRS, error) {
+	file, err := deserializer.InitPtau(cCtx.String("input"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	size := cCtx.Int("size")
+	if size == 0 {
+		size = 2*file.DomainSize() - 1
+	}
+	return deserializer.PtauToKZGSRS(file, size)
+}
+
+// ignitionToKZGSRS reads the SRS of the Aztec Ignition transcripts in the
+// directory given to convert, once their checksums have been verified.
+func ignitionToKZGSRS(cCtx *cli.Context) (*kzg.SRS, error) {
+	transcripts, err := deserializer.InitIgnition(cCtx.String("input"))
+	if err != nil {
+		return nil, err
+	}
+	if err := transcripts.VerifyChecksums(); err != nil {
+		return nil, err
+	}
+
+	size := cCtx.Int("size")
+	if size == 0 {
+		size = transcripts.NumG1Powers()
+	}
+	return deserializer.IgnitionToKZGSRS(transcripts, size)
+}
+
+// convertToHalo2Params writes the halo2 params of the ptau given to convert.
+func convertToHalo2Params(cCtx *cli.Context, format string) error {
+	if cCtx.String("input") == "-" || cCtx.Bool("resume") {
+		return fmt.Errorf("--format %s needs a seekable --input and can't be resumed", format)
+	}
+
+	file, err := deserializer.InitPtau(cCtx.String("input"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	k := uint32(cCtx.Uint("k"))
+	if k == 0 {
+		k = file.Header.Power
+	}
+	serde := deserializer.HALO2_SERDE_PROCESSED
+	if format == "halo2-raw" {
+		serde = deserializer.HALO2_SERDE_RAW_BYTES
+	}
+
+	return deserializer.WriteHalo2Params(file, k, serde, cCtx.String("output"))
+}
+
+// convertToBellman writes the ptau given to convert as a bellman response.
+func convertToBellman(cCtx *cli.Context) error {
+	if cCtx.String("input") == "-" || cCtx.Bool("resume") {
+		return fmt.Errorf("--format bellman needs a seekable --input and can't be resumed")
+	}
+
+	file, err := deserializer.InitPtau(cCtx.String("input"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	hash, err := deserializer.WriteBellmanFromPtauFile(cCtx.Context, file, cCtx.String("output"))
+	if err != nil {
+		return err
+	}
+	slog.Info("wrote bellman file", "output", cCtx.String("output"), "hash", hex.EncodeToString(hash[:]))
+	return nil
+}
+
+// setupLogger sends the logs of the command and of the deserializer package
+// to stderr, at the level and in the format picked by the global flags.
+func setupLogger(cCtx *cli.Context) error {
+	level := slog.LevelInfo
+	if cCtx.Bool("verbose") {
+		level = slog.LevelDebug
+	}
+	if cCtx.Bool("quiet") {
+		level = slog.LevelError
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch cCtx.String("log-format") {
+	case "text":
+		handler = slog.NewTextHandler(os.Stderr, options)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, options)
+	default:
+		return fmt.Errorf("unknown log format %q, expected text or json", cCtx.String("log-format"))
+	}
+
+	logger := slog.New(handler)
+	slog.SetDefault(logger)
+	deserializer.SetLogger(logger)
+	return nil
+}
+
+// newProgressBar draws the progress of a conversion on w, when w is a terminal.
+func newProgressBar(w *os.File) deserializer.Progress {
+	if info, err := w.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return nil
+	}
+
+	return deserializer.ProgressFunc(func(update deserializer.ProgressUpdate) {
+		const width = 40
+		filled := width
+		if update.Total > 0 {
+			filled = width * update.Done / update.Total
+		}
+		bar := strings.Repeat("=", filled) + strings.Repeat(" ", width-filled)
+		fmt.Fprintf(w, "\r%-10s [%s] %d/%d (%.1f MB/s)", update.Section, bar, update.Done, update.Total, update.BytesPerSecond/1e6)
+		if update.Done == update.Total {
+			io.WriteString(w, "\n")
+		}
+	})
 }
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/urfave/cli/v2"
+	deserializer "github.com/worldcoin/ptau-deserializer/deserialize"
+)
+
+var newCommand = &cli.Command{
+	Name:  "new",
+	Usage: "Write a synthetic .ptau with one contribution whose secrets are derived from --seed, for testing",
+	Action: func(cCtx *cli.Context) error {
+		power := cCtx.Uint("power")
+		if power > deserializer.PTAU_MAX_POWER {
+			return fmt.Errorf("--power must be at most %d", deserializer.PTAU_MAX_POWER)
+		}
+
+		secrets, err := deserializer.WriteNewPtau(cCtx.Context, uint32(power), []byte(cCtx.String("seed")), cCtx.String("name"), cCtx.String("output"))
+		if err != nil {
+			return err
+		}
+		slog.Info("secrets of the contribution", "tau", secrets.Tau.String(), "alpha", secrets.Alpha.String(), "beta", secrets.Beta.String())
+		return nil
+	},
+	Flags: []cli.Flag{
+		&cli.UintFlag{
+			Name:     "power",
+			Aliases:  []string{"p"},
+			Usage:    "Write 2^`POWER` powers of tau",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "seed",
+			Usage:    "Derive the secrets of the contribution from `SEED`, the same seed always gives the same file",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "name",
+			Usage: "`NAME` recorded with the contribution",
+		},
+		&cli.StringFlag{
+			Name:     "output",
+			Aliases:  []string{"o"},
+			Usage:    "Write the ptau to `FILE`.ptau",
+			Required: true,
+		},
+	},
+}
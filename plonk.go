@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/urfave/cli/v2"
+	deserializer "github.com/worldcoin/ptau-deserializer/deserialize"
+)
+
+var convertPlonkCommand = &cli.Command{
+	Name:  "convert-plonk",
+	Usage: "Convert a snarkjs PLONK .zkey into gnark's bn254 PLONK proving and verifying keys, which only work with a gnark circuit whose wiring is identical to the zkey's",
+	Description: "The additions and the A, B and C wire maps of the zkey are not converted: gnark solves the witness with its own constraint system, " +
+		"so proofs are only valid when that circuit has exactly the constraints and wiring the zkey was set up with.",
+	Action: func(cCtx *cli.Context) error {
+		zkey, err := deserializer.ReadPlonkZkey(cCtx.String("input"))
+		if err != nil {
+			return err
+		}
+
+		pk, vk, srs, err := zkey.GnarkKeys()
+		if err != nil {
+			return err
+		}
+
+		outputs := []struct {
+			path string
+			key  io.WriterTo
+		}{{cCtx.String("pk"), pk}, {cCtx.String("vk"), vk}, {cCtx.String("srs"), srs}}
+		for _, output := range outputs {
+			if output.path == "" {
+				continue
+			}
+			if err := writeKey(output.path, output.key); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	},
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "input",
+			Aliases:  []string{"i"},
+			Usage:    "Load the PLONK `FILE`.zkey",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "pk",
+			Usage:    "Write the gnark proving key to `FILE`",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "vk",
+			Usage:    "Write the gnark verifying key to `FILE`",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "srs",
+			Usage: "Write the KZG SRS the keys commit with to `FILE`, it is not serialized with the keys",
+		},
+	},
+}
+
+func writeKey(path string, key io.WriterTo) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := key.WriteTo(file); err != nil {
+		file.Close()
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return file.Close()
+}
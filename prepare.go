@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+	deserializer "github.com/worldcoin/ptau-deserializer/deserialize"
+)
+
+var preparePhase2Command = &cli.Command{
+	Name:  "prepare-phase2",
+	Usage: "Append the Lagrange sections phase 2 tools expect to a .ptau, like snarkjs powersoftau prepare phase2",
+	Action: func(cCtx *cli.Context) error {
+		if cCtx.String("input") == "-" {
+			return fmt.Errorf("prepare-phase2 needs a seekable --input, not stdin")
+		}
+		memory := cCtx.Int64("memory")
+		if memory < 1 {
+			return fmt.Errorf("--memory must be at least 1 MiB")
+		}
+
+		file, err := deserializer.InitPtau(cCtx.String("input"))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		return file.PreparePhase2(cCtx.Context, cCtx.String("output"), memory<<20)
+	},
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "input",
+			Aliases:  []string{"i"},
+			Usage:    "Load the `FILE`.ptau to prepare",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "output",
+			Aliases:  []string{"o"},
+			Usage:    "Write the prepared ptau to `FILE`",
+			Required: true,
+		},
+		&cli.Int64Flag{
+			Name:  "memory",
+			Usage: "Hold at most `MiB` of points in memory, larger FFTs go through a temporary file next to the output",
+			Value: deserializer.PREPARE_MEMORY_LIMIT >> 20,
+		},
+	},
+}
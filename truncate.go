@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	deserializer "github.com/worldcoin/ptau-deserializer/deserialize"
+)
+
+var truncateCommand = &cli.Command{
+	Name:  "truncate",
+	Usage: "Write .ptau files of smaller powers, like snarkjs powersoftau truncate",
+	Action: func(cCtx *cli.Context) error {
+		if cCtx.String("input") == "-" {
+			return fmt.Errorf("truncate needs a seekable --input, not stdin")
+		}
+		output := cCtx.String("output")
+		if !strings.Contains(output, "{power}") {
+			return fmt.Errorf("--output must hold a {power} placeholder")
+		}
+
+		file, err := deserializer.InitPtau(cCtx.String("input"))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		powers := make([]uint32, 0, file.Header.Power)
+		if cCtx.IsSet("powers") {
+			if powers, err = parsePowers(cCtx.String("powers")); err != nil {
+				return err
+			}
+		} else {
+			for power := uint32(1); power < file.Header.Power; power++ {
+				powers = append(powers, power)
+			}
+		}
+
+		for _, power := range powers {
+			outputPath := strings.ReplaceAll(output, "{power}", fmt.Sprintf("%02d", power))
+			if err := file.Truncate(cCtx.Context, power, outputPath); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "input",
+			Aliases:  []string{"i"},
+			Usage:    "Load the `FILE`.ptau to truncate",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "output",
+			Aliases:  []string{"o"},
+			Usage:    "Write the ptau of every power to `FILE`, which must hold a {power} placeholder",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "powers",
+			Usage: "Only write the powers of `LIST`, such as 10-24 or 10,12,16, instead of every power below the one of the input",
+		},
+	},
+}
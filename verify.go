@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+	deserializer "github.com/worldcoin/ptau-deserializer/deserialize"
+)
+
+var verifyCommand = &cli.Command{
+	Name:      "verify",
+	Usage:     "Check with pairings that the points of a .ph1 are the powers of a single tau",
+	ArgsUsage: "FILE",
+	Action: func(cCtx *cli.Context) error {
+		if cCtx.NArg() != 1 {
+			return fmt.Errorf("verify expects exactly one FILE")
+		}
+
+		phase1File, err := deserializer.InitPhase1(cCtx.Args().First())
+		if err != nil {
+			return err
+		}
+		defer phase1File.Close()
+
+		return phase1File.Verify(cCtx.Context)
+	},
+}